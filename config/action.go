@@ -2,44 +2,131 @@
 package config
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 
 	"github.com/parihaaraka/ripple/data"
+	"github.com/parihaaraka/ripple/keystore"
 	"github.com/parihaaraka/ripple/websockets"
 )
 
 type Action struct {
 	Seed         data.Seed
+	Key          string `json:",omitempty"` // Name or address in a keystore instead of Seed
 	Fee          data.Value
 	KeyType      data.KeyType
 	AccountSets  []data.AccountSet
 	TrustSets    []data.TrustSet
 	OfferCreates []data.OfferCreate
 	Payments     []data.Payment
+	secret       *data.Secret
 }
 
-type actionFunc func(seed data.Seed, fee data.Value, keyType data.KeyType, tx data.Transaction, txType data.TransactionType) error
+// Wrapper to stop recursive unmarshalling
+type actionJSON Action
+
+// The Seed of an action may be given in any form data.NewSecret accepts.
+// An "sEd" seed sets the KeyType to Ed25519. The transactions may use
+// X-addresses, which are expanded as data.ExpandXAddresses does.
+func (a *Action) UnmarshalJSON(b []byte) error {
+	var extract struct {
+		*actionJSON
+		Seed         string
+		AccountSets  []json.RawMessage
+		TrustSets    []json.RawMessage
+		OfferCreates []json.RawMessage
+		Payments     []json.RawMessage
+	}
+	extract.actionJSON = (*actionJSON)(a)
+	if err := json.Unmarshal(b, &extract); err != nil {
+		return err
+	}
+	for _, txs := range []struct {
+		raw []json.RawMessage
+		v   interface{}
+	}{
+		{extract.AccountSets, &a.AccountSets},
+		{extract.TrustSets, &a.TrustSets},
+		{extract.OfferCreates, &a.OfferCreates},
+		{extract.Payments, &a.Payments},
+	} {
+		if err := unmarshalTransactions(txs.raw, txs.v); err != nil {
+			return err
+		}
+	}
+	if extract.Seed == "" {
+		return nil
+	}
+	secret, err := data.NewSecret(extract.Seed)
+	if err != nil {
+		return err
+	}
+	switch {
+	case secret.Seed == nil:
+		a.secret = secret
+	case secret.KeyType == data.Ed25519:
+		a.Seed, a.KeyType = *secret.Seed, data.Ed25519
+	default:
+		a.Seed = *secret.Seed
+	}
+	return nil
+}
+
+func unmarshalTransactions(raw []json.RawMessage, v interface{}) error {
+	if raw == nil {
+		return nil
+	}
+	for i := range raw {
+		var err error
+		if raw[i], err = data.ExpandXAddresses(raw[i]); err != nil {
+			return err
+		}
+	}
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// Secret returns what the keys of the action are derived from.
+func (a *Action) Secret() (*data.Secret, error) {
+	switch {
+	case a.secret != nil:
+		return a.secret, nil
+	case a.Key != "":
+		return nil, fmt.Errorf("Key %s has not been unlocked", a.Key)
+	default:
+		return data.NewSecretFromSeed(a.Seed, a.KeyType), nil
+	}
+}
+
+type actionFunc func(secret *data.Secret, fee data.Value, tx data.Transaction, txType data.TransactionType) error
 
 func (a *Action) each(f actionFunc) error {
+	secret, err := a.Secret()
+	if err != nil {
+		return err
+	}
 	for i := range a.AccountSets {
-		if err := f(a.Seed, a.Fee, a.KeyType, &a.AccountSets[i], data.ACCOUNT_SET); err != nil {
+		if err := f(secret, a.Fee, &a.AccountSets[i], data.ACCOUNT_SET); err != nil {
 			return err
 		}
 	}
 	for i := range a.TrustSets {
-		if err := f(a.Seed, a.Fee, a.KeyType, &a.TrustSets[i], data.TRUST_SET); err != nil {
+		if err := f(secret, a.Fee, &a.TrustSets[i], data.TRUST_SET); err != nil {
 			return err
 		}
 	}
 	for i := range a.OfferCreates {
-		if err := f(a.Seed, a.Fee, a.KeyType, &a.OfferCreates[i], data.OFFER_CREATE); err != nil {
+		if err := f(secret, a.Fee, &a.OfferCreates[i], data.OFFER_CREATE); err != nil {
 			return err
 		}
 	}
 	for i := range a.Payments {
-		if err := f(a.Seed, a.Fee, a.KeyType, &a.Payments[i], data.PAYMENT); err != nil {
+		if err := f(secret, a.Fee, &a.Payments[i], data.PAYMENT); err != nil {
 			return err
 		}
 	}
@@ -56,6 +143,28 @@ func Parse(r io.Reader) (ActionSlice, error) {
 	return actions, nil
 }
 
+// Unlock decrypts the secrets of the actions which refer to keys in ks,
+// asking passphrase for the passphrase of each key.
+func (s ActionSlice) Unlock(ks *keystore.Keystore, passphrase func(*keystore.Entry) ([]byte, error)) error {
+	for i := range s {
+		if s[i].Key == "" || s[i].secret != nil {
+			continue
+		}
+		e, err := ks.Find(s[i].Key)
+		if err != nil {
+			return err
+		}
+		p, err := passphrase(e)
+		if err != nil {
+			return err
+		}
+		if s[i].secret, err = ks.Secret(s[i].Key, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s ActionSlice) each(f actionFunc) error {
 	for i := range s {
 		if err := s[i].each(f); err != nil {
@@ -66,26 +175,58 @@ func (s ActionSlice) each(f actionFunc) error {
 }
 
 func (s ActionSlice) Prepare() error {
-	var prepare = func(seed data.Seed, fee data.Value, keyType data.KeyType, tx data.Transaction, txType data.TransactionType) error {
-		var (
-			sequence uint32
-			key      = seed.Key(keyType)
-			base     = tx.GetBase()
-		)
+	var prepare = func(secret *data.Secret, fee data.Value, tx data.Transaction, txType data.TransactionType) error {
+		base := tx.GetBase()
 		base.TransactionType = txType
-		base.Fee = fee
-		base.Account = seed.AccountId(keyType, &sequence)
-		return data.Sign(tx, key, &sequence)
+		if !fee.IsZero() {
+			base.Fee = fee
+		}
+		base.Account = secret.AccountId()
+		return data.Sign(tx, secret.Key(), secret.Sequence())
 	}
 	return s.each(prepare)
 }
 
+// Autofill sets the Sequence, Fee and LastLedgerSequence of the transactions
+// from host where they are not set, so should be called before Prepare.
+// Transactions for the same account are given consecutive sequences.
+func (s ActionSlice) Autofill(host string) error {
+	remote, err := websockets.NewClient(host)
+	if err != nil {
+		return err
+	}
+	defer remote.Close()
+	next := make(map[data.Account]uint32)
+	var autofill = func(secret *data.Secret, fee data.Value, tx data.Transaction, txType data.TransactionType) error {
+		base := tx.GetBase()
+		base.TransactionType = txType
+		if !fee.IsZero() {
+			base.Fee = fee
+		}
+		base.Account = secret.AccountId()
+		if base.Sequence == 0 {
+			base.Sequence = next[base.Account]
+		}
+		if err := websockets.Autofill(remote, tx, nil); err != nil {
+			return err
+		}
+		if base.Sequence != 0 {
+			next[base.Account] = base.Sequence + 1
+		}
+		return nil
+	}
+	return s.each(autofill)
+}
+
+// Submit sends the transactions to host, which may be either
+// a websockets or a JSON-RPC endpoint.
 func (s ActionSlice) Submit(host string) error {
-	remote, err := websockets.NewRemote(host)
+	remote, err := websockets.NewClient(host)
 	if err != nil {
 		return err
 	}
-	var submit = func(seed data.Seed, fee data.Value, keyType data.KeyType, tx data.Transaction, txType data.TransactionType) error {
+	defer remote.Close()
+	var submit = func(secret *data.Secret, fee data.Value, tx data.Transaction, txType data.TransactionType) error {
 		result, err := remote.Submit(tx)
 		if err != nil {
 			return err
@@ -98,12 +239,38 @@ func (s ActionSlice) Submit(host string) error {
 	return s.each(submit)
 }
 
+// SubmitAndWait signs and submits the transactions to host in turn, waiting
+// for each to be validated. Sequence, Fee and LastLedgerSequence are filled
+// in from the network where they are not set. An error is returned for the
+// first transaction which fails, whether or not it was validated.
+func (s ActionSlice) SubmitAndWait(ctx context.Context, host string) error {
+	remote, err := websockets.NewClient(host)
+	if err != nil {
+		return err
+	}
+	defer remote.Close()
+	var submit = func(secret *data.Secret, fee data.Value, tx data.Transaction, txType data.TransactionType) error {
+		base := tx.GetBase()
+		base.TransactionType = txType
+		base.Fee = fee
+		base.Account = secret.AccountId()
+		txm, err := websockets.SubmitAndWait(ctx, remote, tx, secret.Key(), secret.Sequence())
+		if err != nil {
+			return fmt.Errorf("%s\n%s", err, js(tx))
+		}
+		if result := txm.MetaData.TransactionResult; !result.Success() {
+			return fmt.Errorf("%s: %s\n%s", result, result.Human(), js(tx))
+		}
+		return nil
+	}
+	return s.each(submit)
+}
+
 func (s ActionSlice) Count() int {
 	var count int
-	s.each(func(seed data.Seed, fee data.Value, keyType data.KeyType, tx data.Transaction, txType data.TransactionType) error {
-		count++
-		return nil
-	})
+	for _, a := range s {
+		count += len(a.AccountSets) + len(a.TrustSets) + len(a.OfferCreates) + len(a.Payments)
+	}
 	return count
 }
 
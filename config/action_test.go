@@ -1,8 +1,14 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/parihaaraka/ripple/data"
+	"github.com/parihaaraka/ripple/keystore"
 )
 
 func TestParse(t *testing.T) {
@@ -20,3 +26,102 @@ func TestParse(t *testing.T) {
 	}
 	// t.Log(actions)
 }
+
+func TestSecrets(t *testing.T) {
+	f, err := os.Open("testdata/secrets.json")
+	if err != nil {
+		t.Fatalf("open file: %v", err)
+	}
+	defer f.Close()
+	actions, err := Parse(f)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if err := actions.Prepare(); err != nil {
+		t.Fatalf("prepare: %v", err)
+	}
+	for i, expected := range []struct {
+		account string
+		keyType data.KeyType
+	}{
+		{"rHb9CJAWyB4rj91VRWn96DkukG4bwdtyTh", data.ECDSA},
+		{"rGWrZyQqhTp9Xu7G5Pkayo7bXjH4k4QYpf", data.Ed25519},
+		{"rHsMGQEkVNJmpGWs8XUBoTBiAAbwxZN5v3", data.ECDSA},
+	} {
+		tx := &actions[i].AccountSets[0]
+		if account := tx.Account.String(); account != expected.account {
+			t.Errorf("action %d: account %s expected %s", i, account, expected.account)
+		}
+		secret, err := actions[i].Secret()
+		if err != nil {
+			t.Fatalf("action %d: %v", i, err)
+		}
+		if keyType := secret.KeyType; keyType != expected.keyType {
+			t.Errorf("action %d: key type %s expected %s", i, keyType, expected.keyType)
+		}
+		if ok, err := data.CheckSignature(tx); !ok || err != nil {
+			t.Errorf("action %d: bad signature: %v", i, err)
+		}
+	}
+}
+
+func TestUnlock(t *testing.T) {
+	ks, err := keystore.Open(filepath.Join(t.TempDir(), "keystore.json"))
+	if err != nil {
+		t.Fatalf("open keystore: %v", err)
+	}
+	ks.ScryptN = 1 << 10
+	if _, err := ks.Add("root", "snoPBrXtMeMyMHUVTgbuqAfg1SUTb", []byte("passphrase")); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	actions, err := Parse(strings.NewReader(`[{"key": "root", "fee": "10", "accountsets": [{"sequence": 1}]}]`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if err := actions.Prepare(); err == nil || err.Error() != "Key root has not been unlocked" {
+		t.Fatalf("prepare locked: %v", err)
+	}
+	var asked []string
+	err = actions.Unlock(ks, func(e *keystore.Entry) ([]byte, error) {
+		asked = append(asked, e.Name)
+		return []byte("passphrase"), nil
+	})
+	if err != nil {
+		t.Fatalf("unlock: %v", err)
+	}
+	if len(asked) != 1 || asked[0] != "root" {
+		t.Errorf("asked for passphrases of %v", asked)
+	}
+	if err := actions.Prepare(); err != nil {
+		t.Fatalf("prepare: %v", err)
+	}
+	if account := actions[0].AccountSets[0].Account.String(); account != "rHb9CJAWyB4rj91VRWn96DkukG4bwdtyTh" {
+		t.Errorf("account %s", account)
+	}
+}
+
+func TestXAddresses(t *testing.T) {
+	const action = `[{"seed": "snoPBrXtMeMyMHUVTgbuqAfg1SUTb", "fee": "10", "payments": [` +
+		`{"sequence": 1, "destination": "XVLhHMPHU98es4dbozjVtdWzVrDjtV8xvjGQTYPiAx6gwDC", "amount": "100"%s}]}]`
+	actions, err := Parse(strings.NewReader(fmt.Sprintf(action, "")))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if err := actions.Prepare(); err != nil {
+		t.Fatalf("prepare: %v", err)
+	}
+	payment := &actions[0].Payments[0]
+	if destination := payment.Destination.String(); destination != "rGWrZyQqhTp9Xu7G5Pkayo7bXjH4k4QYpf" {
+		t.Errorf("destination %s", destination)
+	}
+	if payment.DestinationTag == nil || *payment.DestinationTag != 1 {
+		t.Errorf("destination tag %v", payment.DestinationTag)
+	}
+	if ok, err := data.CheckSignature(payment); !ok || err != nil {
+		t.Errorf("bad signature: %v", err)
+	}
+	_, err = Parse(strings.NewReader(fmt.Sprintf(action, `, "destinationtag": 2`)))
+	if err == nil || !strings.Contains(err.Error(), "conflicts with DestinationTag 2") {
+		t.Errorf("conflicting tag: %v", err)
+	}
+}
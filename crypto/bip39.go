@@ -0,0 +1,136 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	_ "embed"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+//go:embed bip39_english.txt
+var bip39English string
+
+var bip39Words = func() map[string]int {
+	words := make(map[string]int, 2048)
+	for i, word := range strings.Fields(bip39English) {
+		words[word] = i
+	}
+	return words
+}()
+
+// The BIP44 path of the first account key of a mnemonic in most wallets
+const XRPLDerivationPath = "m/44'/144'/0'/0/0"
+
+// MnemonicSeed checks the words and checksum of an English BIP39 mnemonic
+// and returns the 64 byte seed it stretches to with passphrase, which is
+// used as is rather than NFKD normalized.
+func MnemonicSeed(mnemonic, passphrase string) ([]byte, error) {
+	words := strings.Fields(mnemonic)
+	if len(words) < 12 || len(words) > 24 || len(words)%3 != 0 {
+		return nil, fmt.Errorf("Bad mnemonic length: %d words", len(words))
+	}
+	bits := new(big.Int)
+	for _, word := range words {
+		index, ok := bip39Words[word]
+		if !ok {
+			return nil, fmt.Errorf("Unknown mnemonic word: %s", word)
+		}
+		bits.Lsh(bits, 11).Or(bits, big.NewInt(int64(index)))
+	}
+	checksumBits := uint(len(words) * 11 / 33)
+	checksum := new(big.Int).And(bits, big.NewInt(1<<checksumBits-1)).Uint64()
+	entropy := make([]byte, len(words)*11*4/33)
+	bits.Rsh(bits, checksumBits).FillBytes(entropy)
+	hash := sha256.Sum256(entropy)
+	if uint64(hash[0]>>(8-checksumBits)) != checksum {
+		return nil, fmt.Errorf("Bad mnemonic checksum")
+	}
+	normalized := strings.Join(words, " ")
+	return pbkdf2.Key([]byte(normalized), []byte("mnemonic"+passphrase), 2048, 64, sha512.New), nil
+}
+
+// NewMnemonicKey returns the secp256k1 key of a BIP39 mnemonic at the
+// XRPL derivation path. It is the account's key itself rather than the
+// root of a family, so is used with nil sequences.
+func NewMnemonicKey(mnemonic, passphrase string) (Key, error) {
+	seed, err := MnemonicSeed(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return DeriveKey(seed, XRPLDerivationPath)
+}
+
+const hardened = 1 << 31
+
+// DeriveKey returns the BIP32 private key at path, such as
+// "m/44'/144'/0'/0/0", from a seed.
+func DeriveKey(seed []byte, path string) (Key, error) {
+	parts := strings.Split(path, "/")
+	if parts[0] != "m" {
+		return nil, fmt.Errorf("Bad derivation path: %s", path)
+	}
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	i := mac.Sum(nil)
+	key, chain := new(big.Int).SetBytes(i[:32]), i[32:]
+	if key.Sign() == 0 || key.Cmp(order) >= 0 {
+		return nil, fmt.Errorf("Bad master key for seed")
+	}
+	for _, part := range parts[1:] {
+		var offset uint64
+		if trimmed := strings.TrimRight(part, "'h"); trimmed != part {
+			part, offset = trimmed, hardened
+		}
+		n, err := strconv.ParseUint(part, 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("Bad derivation path: %s", path)
+		}
+		index := make([]byte, 4)
+		binary.BigEndian.PutUint32(index, uint32(n+offset))
+		mac := hmac.New(sha512.New, chain)
+		if offset == hardened {
+			mac.Write([]byte{0})
+			mac.Write(key.FillBytes(make([]byte, 32)))
+		} else {
+			private, _ := btcec.PrivKeyFromBytes(key.FillBytes(make([]byte, 32)))
+			mac.Write(private.PubKey().SerializeCompressed())
+		}
+		mac.Write(index)
+		i := mac.Sum(nil)
+		tweak := new(big.Int).SetBytes(i[:32])
+		if tweak.Cmp(order) >= 0 {
+			return nil, fmt.Errorf("Bad key at %s of %s", part, path)
+		}
+		key.Add(key, tweak).Mod(key, order)
+		if key.Sign() == 0 {
+			return nil, fmt.Errorf("Bad key at %s of %s", part, path)
+		}
+		chain = i[32:]
+	}
+	private, err := NewECDSAPrivateKey(key.FillBytes(make([]byte, 32)))
+	if err != nil {
+		return nil, err
+	}
+	return private, nil
+}
+
+// NewECDSAPrivateKey wraps a secp256k1 private key. A non-nil sequence
+// derives a key of the family it is the root of, as for seeds.
+func NewECDSAPrivateKey(b []byte) (*ecdsaKey, error) {
+	if len(b) != btcec.PrivKeyBytesLen {
+		return nil, fmt.Errorf("Bad private key length: %d", len(b))
+	}
+	key, _ := btcec.PrivKeyFromBytes(b)
+	if n := new(big.Int).SetBytes(b); n.Sign() == 0 || n.Cmp(order) >= 0 {
+		return nil, fmt.Errorf("Bad private key")
+	}
+	return &ecdsaKey{key}, nil
+}
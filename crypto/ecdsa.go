@@ -59,9 +59,12 @@ func (k *ecdsaKey) Id(sequence *uint32) []byte {
 	return Sha256RipeMD160(k.Public(sequence))
 }
 
+// Private returns the 32 byte scalar, keeping any leading zeros, which
+// Sign relies on to tell it from an Ed25519 key.
 func (k *ecdsaKey) Private(sequence *uint32) []byte {
 	if sequence == nil {
-		return k.ToECDSA().D.Bytes()
+		b := k.Key.Bytes()
+		return b[:]
 	}
 	b := k.generateKey(*sequence).Key.Bytes()
 	return b[:]
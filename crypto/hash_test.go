@@ -49,3 +49,36 @@ func accountCheck(v interface{}) Hash {
 func (s *HashSuite) TestHashes(c *C) {
 	accountTests.Test(c)
 }
+
+func (s *HashSuite) TestXAddress(c *C) {
+	account := accountCheck("rGWrZyQqhTp9Xu7G5Pkayo7bXjH4k4QYpf").Payload()
+	one, max := uint32(1), uint32(4294967295)
+	for _, test := range []struct {
+		tag      *uint32
+		test     bool
+		expected string
+	}{
+		{nil, false, "XVLhHMPHU98es4dbozjVtdWzVrDjtV5fdx1mHp98tDMoQXb"},
+		{&one, false, "XVLhHMPHU98es4dbozjVtdWzVrDjtV8xvjGQTYPiAx6gwDC"},
+		{&max, false, "XVLhHMPHU98es4dbozjVtdWzVrDjtV18pX8yuPT7y4xaEHi"},
+		{nil, true, "TVE26TYGhfLC7tQDno7G8dGtxSkYQn49b3qD26PK7FcGSKE"},
+		{&one, true, "TVE26TYGhfLC7tQDno7G8dGtxSkYQnSz1uDimDdPYXzSpyw"},
+	} {
+		s, err := EncodeXAddress(account, test.tag, test.test)
+		c.Assert(err, IsNil)
+		c.Check(s, Equals, test.expected)
+		c.Check(IsXAddress(s), Equals, true)
+		decoded, tag, isTest, err := DecodeXAddress(s)
+		c.Assert(err, IsNil)
+		c.Check(decoded, DeepEquals, account)
+		c.Check(tag, DeepEquals, test.tag)
+		c.Check(isTest, Equals, test.test)
+	}
+	c.Check(IsXAddress("rGWrZyQqhTp9Xu7G5Pkayo7bXjH4k4QYpf"), Equals, false)
+	_, _, _, err := DecodeXAddress("rGWrZyQqhTp9Xu7G5Pkayo7bXjH4k4QYpf")
+	c.Check(err, ErrorMatches, "Bad X-address length: .*")
+	_, _, _, err = DecodeXAddress("XVLhHMPHU98es4dbozjVtdWzVrDjtV5fdx1mHp98tDMoQXc")
+	c.Check(err, ErrorMatches, "Bad Base58 checksum: .*")
+	_, err = EncodeXAddress(account[1:], nil, false)
+	c.Check(err, ErrorMatches, "Bad account id length: 19")
+}
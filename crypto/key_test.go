@@ -57,6 +57,16 @@ func (s *KeySuite) TestWikiVectors(c *C) {
 	c.Check(checkHash(AccountPrivateKey(key, &sequenceZero)), Equals, "pwMPbuE25rnajigDPBEh9Pwv8bMV2ebN9gVPTWTh4c3DtB14iGL")
 }
 
+// The root key of this seed has a leading zero byte, which must be kept
+func (s *KeySuite) TestLeadingZeroKey(c *C) {
+	key, err := NewECDSAKey(h2b("00000000000000000000000000000199"))
+	c.Assert(err, IsNil)
+	private := key.Private(nil)
+	c.Check(b2h(private), Equals, "00116430494D20C31B592D64A61ED7B12994CCCB35C1B8F000CCE749EABC130F")
+	hash := Sha512Half([]byte("message"))
+	c.Check(checkSignature(c, private, key.Public(nil), hash, nil), Equals, true)
+}
+
 // Examples from https://github.com/ripple/rippled/blob/develop/src/ripple_data/protocol/RippleAddress.cpp
 func (s *KeySuite) TestRippledVectors(c *C) {
 	seed, err := GenerateFamilySeed("masterpassphrase")
@@ -107,3 +117,82 @@ func (s *KeySuite) TestEd25119(c *C) {
 	c.Check(checkSignature(c, key.Private(nil), other.Public(nil), hash, msg), Equals, false)
 	c.Check(checkSignature(c, other.Private(nil), key.Public(nil), hash, msg), Equals, false)
 }
+
+func (s *KeySuite) TestSeeds(c *C) {
+	seed := h2b("DEDCE9CE67B451D852FD4E846FCDE31C")
+	for _, ed25519 := range []bool{false, true} {
+		encoded, err := EncodeSeed(seed, ed25519)
+		c.Assert(err, IsNil)
+		c.Check(encoded[:3] == "sEd", Equals, ed25519)
+		decoded, isEd25519, err := DecodeSeed(encoded)
+		c.Assert(err, IsNil)
+		c.Check(decoded, DeepEquals, seed)
+		c.Check(isEd25519, Equals, ed25519)
+	}
+	_, _, err := DecodeSeed("rHb9CJAWyB4rj91VRWn96DkukG4bwdtyTh")
+	c.Check(err, ErrorMatches, "Bad version for: .*")
+
+	// The example secret of xrpl-secret-numbers
+	numbers := "554872 394230 209376 323698 140250 387423 652803 258676"
+	c.Check(IsSecretNumbers(numbers), Equals, true)
+	seed, err = DecodeSecretNumbers(numbers)
+	c.Assert(err, IsNil)
+	c.Check(b2h(seed), Equals, "D8BF99FF51C97E7136C99756FF00650B")
+	c.Check(checkHash(NewFamilySeed(seed)), Equals, "sn5ScMr4n1Kqc9DUJqVsGcSv7yb2U")
+	key, err := NewECDSAKey(seed)
+	c.Assert(err, IsNil)
+	var sequenceZero uint32
+	c.Check(checkHash(AccountId(key, &sequenceZero)), Equals, "rHcEZm4eRiSaMoZaa3sw2HrCn1oGDCEJPA")
+	encoded, err := EncodeSecretNumbers(seed)
+	c.Assert(err, IsNil)
+	c.Check(encoded, Equals, numbers)
+	_, err = DecodeSecretNumbers("554872 394230 209376 323698 140250 387423 652803 258677")
+	c.Check(err, ErrorMatches, "Bad check digit in secret number group 8: 258677")
+	_, err = DecodeSecretNumbers("554872 394230")
+	c.Check(err, ErrorMatches, "Secret numbers must have 8 groups: .*")
+	c.Check(IsSecretNumbers("554872 394230 209376 323698 140250 387423 652803 25867"), Equals, false)
+}
+
+func (s *KeySuite) TestMnemonic(c *C) {
+	// BIP39 and BIP32 test vectors
+	seed, err := MnemonicSeed("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", "TREZOR")
+	c.Assert(err, IsNil)
+	c.Check(b2h(seed), Equals, "C55257C360C07C72029AEBC1B53C05ED0362ADA38EAD3E3E9EFA3708E53495531F09A6987599D18264C1E1C92F2CF141630C7A3C4AB7C81B2F001698E7463B04")
+	seed, err = MnemonicSeed("legal winner thank year wave sausage worth useful legal winner thank year wave sausage worth useful legal winner thank year wave sausage worth title", "TREZOR")
+	c.Assert(err, IsNil)
+	c.Check(b2h(seed), Equals, "BC09FCA1804F7E69DA93C2F2028EB238C227F2E9DDA30CD63699232578480A4021B146AD717FBB7E451CE9EB835F43620BF5C514DB0F8ADD49F5D121449D3E87")
+	for _, test := range []struct {
+		path, private string
+	}{
+		{"m", "E8F32E723DECF4051AEFAC8E2C93C9C5B214313817CDB01A1494B917C8436B35"},
+		{"m/0'", "EDB2E14F9EE77D26DD93B4ECEDE8D16ED408CE149B6CD80B0715A2D911A0AFEA"},
+		{"m/0h/1", "3C6CB8D0F6A264C91EA8B5030FADAA8E538B020F0A387421A12DE9319DC93368"},
+	} {
+		key, err := DeriveKey(h2b("000102030405060708090A0B0C0D0E0F"), test.path)
+		c.Assert(err, IsNil)
+		c.Check(b2h(key.Private(nil)), Equals, test.private)
+	}
+	_, err = DeriveKey(seed, "44'/144'")
+	c.Check(err, ErrorMatches, "Bad derivation path: .*")
+
+	mnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	key, err := NewMnemonicKey(mnemonic, "")
+	c.Assert(err, IsNil)
+	seed, err = MnemonicSeed(mnemonic, "")
+	c.Assert(err, IsNil)
+	derived, err := DeriveKey(seed, XRPLDerivationPath)
+	c.Assert(err, IsNil)
+	c.Check(key.Private(nil), DeepEquals, derived.Private(nil))
+	// As xrpl.js derives the wallet of this mnemonic
+	c.Check(b2h(key.Public(nil)), Equals, "031D68BC1A142E6766B2BDFB006CCFE135EF2E0E2E94ABB5CF5C9AB6104776FBAE")
+	c.Check(b2h(key.Private(nil)), Equals, "90802A50AA84EFB6CDB225F17C27616EA94048C179142FECF03F4712A07EA7A4")
+	c.Check(checkHash(AccountId(key, nil)), Equals, "rHsMGQEkVNJmpGWs8XUBoTBiAAbwxZN5v3")
+	c.Check(checkSignature(c, key.Private(nil), key.Public(nil), Sha512Half([]byte("hello")), nil), Equals, true)
+
+	_, err = MnemonicSeed("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon", "")
+	c.Check(err, ErrorMatches, "Bad mnemonic checksum")
+	_, err = MnemonicSeed("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abut", "")
+	c.Check(err, ErrorMatches, "Unknown mnemonic word: abut")
+	_, err = MnemonicSeed("abandon about", "")
+	c.Check(err, ErrorMatches, "Bad mnemonic length: 2 words")
+}
@@ -0,0 +1,114 @@
+package crypto
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Ed25519 seeds are family seeds with a three byte prefix, which encodes
+// as "sEd".
+var ed25519SeedPrefix = []byte{0x01, 0xE1, 0x4B}
+
+const seedSize = 16
+
+// EncodeSeed returns the base58 form of a 16 byte seed, starting with
+// "sEd" when it is for an Ed25519 key.
+func EncodeSeed(seed []byte, ed25519 bool) (string, error) {
+	if len(seed) != seedSize {
+		return "", fmt.Errorf("Bad seed length: %d", len(seed))
+	}
+	if !ed25519 {
+		hash, err := NewFamilySeed(seed)
+		if err != nil {
+			return "", err
+		}
+		return hash.String(), nil
+	}
+	b := append(append([]byte(nil), ed25519SeedPrefix...), seed...)
+	return Base58Encode(b, ALPHABET), nil
+}
+
+// DecodeSeed returns the 16 byte seed of either form of encoded seed, and
+// whether the form was that of an Ed25519 key.
+func DecodeSeed(s string) ([]byte, bool, error) {
+	if strings.HasPrefix(s, "sEd") {
+		decoded, err := Base58Decode(s, ALPHABET)
+		if err != nil {
+			return nil, false, err
+		}
+		b := decoded[:len(decoded)-4]
+		if len(b) == len(ed25519SeedPrefix)+seedSize && bytes.HasPrefix(b, ed25519SeedPrefix) {
+			return b[len(ed25519SeedPrefix):], true, nil
+		}
+	}
+	hash, err := NewRippleHashCheck(s, RIPPLE_FAMILY_SEED)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(hash.Payload()) != seedSize {
+		return nil, false, fmt.Errorf("Bad seed length: %d", len(hash.Payload()))
+	}
+	return hash.Payload(), false, nil
+}
+
+// Secret numbers are a 16 byte seed written as 8 groups of 6 digits. Each
+// group is 5 digits of a big endian uint16 of the seed and a check digit.
+const secretNumberGroups = 8
+
+func secretNumberCheck(position int, value uint64) uint64 {
+	return value * uint64(position*2+1) % 9
+}
+
+// IsSecretNumbers reports whether s has the form of secret numbers,
+// without checking the check digits.
+func IsSecretNumbers(s string) bool {
+	groups := strings.Fields(s)
+	if len(groups) != secretNumberGroups {
+		return false
+	}
+	for _, group := range groups {
+		if _, err := strconv.ParseUint(group, 10, 32); err != nil || len(group) != 6 {
+			return false
+		}
+	}
+	return true
+}
+
+// DecodeSecretNumbers returns the 16 byte seed written as secret numbers.
+func DecodeSecretNumbers(s string) ([]byte, error) {
+	groups := strings.Fields(s)
+	if len(groups) != secretNumberGroups {
+		return nil, fmt.Errorf("Secret numbers must have %d groups: %s", secretNumberGroups, s)
+	}
+	seed := make([]byte, 0, seedSize)
+	for i, group := range groups {
+		n, err := strconv.ParseUint(group, 10, 32)
+		if err != nil || len(group) != 6 {
+			return nil, fmt.Errorf("Bad secret number group %d: %s", i+1, group)
+		}
+		value, check := n/10, n%10
+		if value > 0xFFFF {
+			return nil, fmt.Errorf("Bad secret number group %d: %s", i+1, group)
+		}
+		if secretNumberCheck(i, value) != check {
+			return nil, fmt.Errorf("Bad check digit in secret number group %d: %s", i+1, group)
+		}
+		seed = append(seed, byte(value>>8), byte(value))
+	}
+	return seed, nil
+}
+
+// EncodeSecretNumbers writes a 16 byte seed as secret numbers.
+func EncodeSecretNumbers(seed []byte) (string, error) {
+	if len(seed) != seedSize {
+		return "", fmt.Errorf("Bad seed length: %d", len(seed))
+	}
+	groups := make([]string, secretNumberGroups)
+	for i := range groups {
+		value := uint64(seed[i*2])<<8 | uint64(seed[i*2+1])
+		groups[i] = fmt.Sprintf("%05d%d", value, secretNumberCheck(i, value))
+	}
+	return strings.Join(groups, " "), nil
+}
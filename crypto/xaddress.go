@@ -0,0 +1,84 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// X-addresses pack an account id and an optional destination tag into a
+// single base58 string, with a prefix telling mainnet and testnet apart.
+// The payload is the prefix, the 20 byte account id, a flag byte set when
+// there is a tag and the tag as 8 little endian bytes of which the top 4
+// are reserved.
+var (
+	xAddressMainnet = []byte{0x05, 0x44}
+	xAddressTestnet = []byte{0x04, 0x93}
+)
+
+const xAddressSize = 2 + 20 + 1 + 8
+
+// IsXAddress reports whether s looks like an X-address rather than a
+// classic address, without checking that it decodes.
+func IsXAddress(s string) bool {
+	return len(s) == 47 && (s[0] == 'X' || s[0] == 'T')
+}
+
+// EncodeXAddress returns the X-address of an account id with an optional
+// tag, for testnet when test is set.
+func EncodeXAddress(account []byte, tag *uint32, test bool) (string, error) {
+	if len(account) != 20 {
+		return "", fmt.Errorf("Bad account id length: %d", len(account))
+	}
+	b := make([]byte, 0, xAddressSize)
+	if test {
+		b = append(b, xAddressTestnet...)
+	} else {
+		b = append(b, xAddressMainnet...)
+	}
+	b = append(b, account...)
+	var flag byte
+	var value [8]byte
+	if tag != nil {
+		flag = 1
+		binary.LittleEndian.PutUint32(value[:], *tag)
+	}
+	b = append(append(b, flag), value[:]...)
+	return Base58Encode(b, ALPHABET), nil
+}
+
+// DecodeXAddress returns the account id, tag and network of an X-address.
+func DecodeXAddress(s string) ([]byte, *uint32, bool, error) {
+	decoded, err := Base58Decode(s, ALPHABET)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	b := decoded[:len(decoded)-4]
+	if len(b) != xAddressSize {
+		return nil, nil, false, fmt.Errorf("Bad X-address length: %s", s)
+	}
+	var test bool
+	switch {
+	case bytes.Equal(b[:2], xAddressMainnet):
+	case bytes.Equal(b[:2], xAddressTestnet):
+		test = true
+	default:
+		return nil, nil, false, fmt.Errorf("Bad X-address prefix: %s", s)
+	}
+	account, flag, value := b[2:22], b[22], b[23:]
+	if binary.LittleEndian.Uint32(value[4:]) != 0 {
+		return nil, nil, false, fmt.Errorf("Unsupported 64 bit tag in X-address: %s", s)
+	}
+	switch flag {
+	case 0:
+		if binary.LittleEndian.Uint32(value) != 0 {
+			return nil, nil, false, fmt.Errorf("Tag without flag in X-address: %s", s)
+		}
+		return account, nil, test, nil
+	case 1:
+		tag := binary.LittleEndian.Uint32(value)
+		return account, &tag, test, nil
+	default:
+		return nil, nil, false, fmt.Errorf("Bad X-address flag %d: %s", flag, s)
+	}
+}
@@ -13,16 +13,16 @@ type Amount struct {
 	*Value
 	Currency Currency
 	Issuer   Account
+	// Set for Multi-Purpose Tokens, whose values are whole numbers like drops
+	MPTIssuanceID *Hash192
 }
 
 type ExchangeRate uint64
 
-func newAmount(value *Value, currency Currency, issuer Account) *Amount {
-	return &Amount{
-		Value:    value,
-		Currency: currency,
-		Issuer:   issuer,
-	}
+// withValue returns an amount of the same asset as a
+func (a Amount) withValue(value *Value) *Amount {
+	a.Value = value
+	return &a
 }
 
 // Requires v to be in computer parsable form
@@ -36,6 +36,9 @@ func NewAmount(v interface{}) (*Amount, error) {
 		var err error
 		amount := new(Amount)
 		parts := strings.Split(strings.TrimSpace(n), "/")
+		if len(parts) == 2 && len(parts[1]) == 2*len(Hash192{}) {
+			return newMPTAmount(parts[0], parts[1])
+		}
 		native := false
 		switch {
 		case len(parts) == 1:
@@ -81,7 +84,27 @@ func (a *Amount) UnmarshalBinary(b []byte) error {
 func (a Amount) Equals(b Amount) bool {
 	return a.Value.Equals(*b.Value) &&
 		a.Currency == b.Currency &&
-		a.Issuer == b.Issuer
+		a.Issuer == b.Issuer &&
+		a.SameMPT(b)
+}
+
+// IsNative returns true for XRP amounts.
+func (a Amount) IsNative() bool {
+	return a.Value.IsNative() && !a.IsMPT()
+}
+
+// IsMPT returns true for Multi-Purpose Token amounts.
+func (a Amount) IsMPT() bool {
+	return a.MPTIssuanceID != nil
+}
+
+// SameMPT returns true if both amounts are of the same Multi-Purpose
+// Token, or neither is.
+func (a Amount) SameMPT(b Amount) bool {
+	if a.IsMPT() != b.IsMPT() {
+		return false
+	}
+	return !a.IsMPT() || *a.MPTIssuanceID == *b.MPTIssuanceID
 }
 
 // Returns true if the values are equal, but ignores the currency and issuer
@@ -90,12 +113,12 @@ func (a Amount) SameValue(b *Amount) bool {
 }
 
 func (a Amount) Clone() *Amount {
-	return newAmount(a.Value.Clone(), a.Currency, a.Issuer)
+	return a.withValue(a.Value.Clone())
 }
 
 // Returns a new Amount with the same currency and issuer, but a zero value
 func (a Amount) ZeroClone() *Amount {
-	return newAmount(a.Value.ZeroClone(), a.Currency, a.Issuer)
+	return a.withValue(a.Value.ZeroClone())
 }
 
 func (a Amount) IsPositive() bool {
@@ -119,7 +142,7 @@ func (a Amount) Add(b *Amount) (*Amount, error) {
 	if err != nil {
 		return nil, err
 	}
-	return newAmount(sum, a.Currency, a.Issuer), nil
+	return a.withValue(sum), nil
 }
 
 func (a Amount) Subtract(b *Amount) (*Amount, error) {
@@ -131,7 +154,7 @@ func (a Amount) multiply(b *Amount) (*Amount, error) {
 	if err != nil {
 		return nil, err
 	}
-	return newAmount(product, a.Currency, a.Issuer), nil
+	return a.withValue(product), nil
 }
 
 func (num Amount) divide(den *Amount) (*Amount, error) {
@@ -139,7 +162,7 @@ func (num Amount) divide(den *Amount) (*Amount, error) {
 	if err != nil {
 		return nil, err
 	}
-	return newAmount(quotient, num.Currency, num.Issuer), nil
+	return num.withValue(quotient), nil
 }
 
 func (a Amount) ApplyInterest() (*Amount, error) {
@@ -191,6 +214,16 @@ func (a Amount) Ratio(b Amount) *Value {
 }
 
 func (a Amount) Bytes() []byte {
+	if a.IsMPT() {
+		// Flags, then the whole number of units, then the issuance
+		b := make([]byte, 9, 9+len(a.MPTIssuanceID))
+		b[0] = 0x20
+		if !a.negative {
+			b[0] |= 0x40
+		}
+		binary.BigEndian.PutUint64(b[1:], a.num)
+		return append(b, a.MPTIssuanceID[:]...)
+	}
 	if a.IsNative() {
 		return a.Value.Bytes()
 	}
@@ -205,6 +238,8 @@ func (a Amount) String() string {
 		return err.Error()
 	}
 	switch {
+	case a.IsMPT():
+		return a.Machine()
 	case a.IsNative():
 		return factored.Value.String() + "/XRP"
 	case a.Issuer.IsZero():
@@ -217,6 +252,8 @@ func (a Amount) String() string {
 // Amount in computer parsable form
 func (a Amount) Machine() string {
 	switch {
+	case a.IsMPT():
+		return mptString(a.Value) + "/" + a.MPTIssuanceID.String()
 	case a.IsNative():
 		return a.Value.String() + "/XRP"
 	case a.Issuer.IsZero():
@@ -1,6 +1,7 @@
 package data
 
 import (
+	"bytes"
 	"fmt"
 	"sort"
 )
@@ -28,13 +29,17 @@ type Transfer struct {
 }
 
 type Balance struct {
-	CounterParty Account
-	Balance      Value
-	Change       Value
-	Currency     Currency
+	CounterParty  Account
+	Balance       Value
+	Change        Value
+	Currency      Currency
+	MPTIssuanceID *Hash192 // Set for Multi-Purpose Tokens, with whole number values
 }
 
 func (b Balance) String() string {
+	if b.MPTIssuanceID != nil {
+		return fmt.Sprintf("CounterParty: %-34s  MPT: %s Balance: %20s Change: %20s", b.CounterParty, b.MPTIssuanceID, mptString(&b.Balance), mptString(&b.Change))
+	}
 	return fmt.Sprintf("CounterParty: %-34s  Currency: %s Balance: %20s Change: %20s", b.CounterParty, b.Currency, b.Balance, b.Change)
 }
 
@@ -44,6 +49,10 @@ func (s BalanceSlice) Len() int      { return len(s) }
 func (s BalanceSlice) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
 func (s BalanceSlice) Less(i, j int) bool {
 	switch {
+	case (s[i].MPTIssuanceID == nil) != (s[j].MPTIssuanceID == nil):
+		return s[i].MPTIssuanceID == nil
+	case s[i].MPTIssuanceID != nil && *s[i].MPTIssuanceID != *s[j].MPTIssuanceID:
+		return bytes.Compare(s[i].MPTIssuanceID[:], s[j].MPTIssuanceID[:]) < 0
 	case !s[i].Currency.Equals(s[j].Currency):
 		return s[i].Currency.Less(s[j].Currency)
 	case s[i].Change.Abs().Equals(*s[j].Change.Abs()):
@@ -54,7 +63,11 @@ func (s BalanceSlice) Less(i, j int) bool {
 }
 
 func (s *BalanceSlice) Add(counterparty *Account, balance, change *Value, currency *Currency) {
-	*s = append(*s, Balance{*counterparty, *balance, *change, *currency})
+	*s = append(*s, Balance{*counterparty, *balance, *change, *currency, nil})
+}
+
+func (s *BalanceSlice) AddMPT(counterparty *Account, balance, change *Value, id *Hash192) {
+	*s = append(*s, Balance{*counterparty, *balance, *change, zeroCurrency, id})
 }
 
 type BalanceMap map[Account]*BalanceSlice
@@ -67,6 +80,22 @@ func (m *BalanceMap) Add(account *Account, counterparty *Account, balance, chang
 	(*m)[*account].Add(counterparty, balance, change, currency)
 }
 
+func (m *BalanceMap) AddMPT(account *Account, counterparty *Account, balance, change *Value, id *Hash192) {
+	_, ok := (*m)[*account]
+	if !ok {
+		(*m)[*account] = &BalanceSlice{}
+	}
+	(*m)[*account].AddMPT(counterparty, balance, change, id)
+}
+
+// mptAmount returns the units held in an MPToken, which are omitted when zero.
+func mptAmount(token *MPToken) *Value {
+	if token == nil || token.MPTAmount == nil {
+		return zeroNative.Clone()
+	}
+	return newValue(true, false, uint64(*token.MPTAmount), 0)
+}
+
 func (txm *TransactionWithMetaData) Balances() (BalanceMap, error) {
 	if txm.GetTransactionType() != OFFER_CREATE && txm.GetTransactionType() != PAYMENT {
 		return nil, nil
@@ -93,6 +122,25 @@ func (txm *TransactionWithMetaData) Balances() (BalanceMap, error) {
 			case ACCOUNT_ROOT:
 				return nil, fmt.Errorf("Deleted AccountRoot!")
 			}
+		case node.ModifiedNode != nil && node.ModifiedNode.LedgerEntryType == MPTOKEN:
+			// Changed MPT balance, held against the issuer. MPTAmount is
+			// omitted when zero, so it is missing from the PreviousFields,
+			// if there are any, of a holder's first receipt.
+			var (
+				previous, _ = node.ModifiedNode.PreviousFields.(*MPToken)
+				current     = node.ModifiedNode.FinalFields.(*MPToken)
+			)
+			balance := mptAmount(current)
+			change, err := balance.Subtract(*mptAmount(previous))
+			if err != nil {
+				return nil, err
+			}
+			if change.IsZero() {
+				continue
+			}
+			issuer := current.MPTokenIssuanceID.MPTIssuer()
+			balanceMap.AddMPT(current.Account, &issuer, balance, change, current.MPTokenIssuanceID)
+			balanceMap.AddMPT(&issuer, current.Account, balance.Negate(), change.Negate(), current.MPTokenIssuanceID)
 		case node.ModifiedNode != nil:
 			if node.ModifiedNode.PreviousFields == nil {
 				// No change
@@ -1,8 +1,13 @@
 package data
 
 import (
+	"bytes"
 	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"strings"
 
+	"github.com/parihaaraka/ripple/crypto"
 	internal "github.com/parihaaraka/ripple/testing"
 	. "gopkg.in/check.v1"
 )
@@ -47,6 +52,40 @@ func (s *CodecSuite) TestValidations(c *C) {
 	}
 }
 
+func (s *CodecSuite) TestSignValidation(c *C) {
+	for _, ed25519 := range []bool{true, false} {
+		var key crypto.Key
+		var err error
+		if ed25519 {
+			key, err = crypto.NewEd25519Key([]byte("validator"))
+		} else {
+			key, err = crypto.NewECDSAKey([]byte("validator"))
+		}
+		c.Assert(err, IsNil)
+		cookie := uint64(42)
+		v := &Validation{
+			Flags:          VF_FULL_VALIDATION,
+			LedgerHash:     Hash256{1},
+			LedgerSequence: 100,
+			SigningTime:    *NewRippleTime(1000),
+			Cookie:         &cookie,
+		}
+		c.Assert(Sign(v, key, nil), IsNil)
+		c.Check(v.Full(), Equals, true)
+		_, raw, err := Raw(v)
+		c.Assert(err, IsNil)
+		decoded, err := ReadValidation(bytes.NewReader(raw))
+		c.Assert(err, IsNil)
+		c.Check(*decoded.Cookie, Equals, cookie)
+		ok, err := CheckSignature(decoded)
+		c.Check(err, IsNil)
+		c.Check(ok, Equals, true)
+		decoded.LedgerSequence++
+		ok, _ = CheckSignature(decoded)
+		c.Check(ok, Equals, false)
+	}
+}
+
 func (s *CodecSuite) TestParseNodes(c *C) {
 	for _, test := range internal.Nodes {
 		nodeId, err := NewHash256(test.NodeId())
@@ -64,6 +103,22 @@ func (s *CodecSuite) TestParseNodes(c *C) {
 	}
 }
 
+func (s *CodecSuite) TestLedgerHash(c *C) {
+	b, err := ioutil.ReadFile("testdata/ledger_6000000.json")
+	c.Assert(err, IsNil)
+	var ledger Ledger
+	c.Assert(json.Unmarshal(b, &ledger), IsNil)
+	hash, err := ledger.CalculateHash()
+	c.Assert(err, IsNil)
+	c.Check(hash.String(), Equals, "E6DB7365949BF9814D76BCC730B01818EB9136A89DB224F3F9F5AAE4569D758E")
+	c.Check(ledger.VerifyHash(), IsNil)
+
+	ledger.CloseFlags ^= 1
+	c.Check(ledger.VerifyHash(), ErrorMatches, "Ledger 38129 hash: .* expected: E6DB7365.*")
+	ledger.ParentCloseTime = nil
+	c.Check(ledger.VerifyHash(), ErrorMatches, "Ledger 38129 has no close times")
+}
+
 func (s *CodecSuite) TestBadNodes(c *C) {
 	for _, test := range internal.BadNodes {
 		nodeid, err := NewHash256(test.NodeId())
@@ -93,3 +148,214 @@ func (s *CodecSuite) TestParseMetaData(c *C) {
 		}
 	}
 }
+
+// checkTransactions round trips the transactions in the file through
+// their binary encoding and back to the same JSON. Where a transaction
+// has a tx_blob and hash, its encoding and signature are checked too.
+func checkTransactions(c *C, path string, n int) {
+	b, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	var txs []json.RawMessage
+	c.Assert(json.Unmarshal(b, &txs), IsNil)
+	c.Assert(txs, HasLen, n)
+	for _, raw := range txs {
+		var typ struct {
+			TransactionType string
+			TxBlob          string `json:"tx_blob"`
+			Hash            string `json:"hash"`
+		}
+		c.Assert(json.Unmarshal(raw, &typ), IsNil)
+		tx := GetTxFactoryByType(typ.TransactionType)()
+		c.Assert(json.Unmarshal(raw, tx), IsNil)
+		msg := Commentf(typ.TransactionType)
+		hash, encoded, err := Raw(tx)
+		c.Assert(err, IsNil, msg)
+		if typ.TxBlob != "" {
+			c.Check(string(b2h(encoded)), Equals, typ.TxBlob, msg)
+			c.Check(hash.String(), Equals, typ.Hash, msg)
+			ok, err := CheckSignature(tx)
+			c.Check(ok, Equals, true, msg)
+			c.Check(err, IsNil, msg)
+			var fields map[string]json.RawMessage
+			c.Assert(json.Unmarshal(raw, &fields), IsNil)
+			delete(fields, "tx_blob")
+			delete(fields, "hash")
+			raw, err = json.Marshal(fields)
+			c.Assert(err, IsNil)
+		}
+		decoded, err := ReadTransaction(bytes.NewReader(encoded))
+		c.Assert(err, IsNil, msg)
+		c.Check(decoded.GetTransactionType().String(), Equals, typ.TransactionType)
+		_, reencoded, err := Raw(decoded)
+		c.Assert(err, IsNil, msg)
+		c.Check(string(b2h(reencoded)), Equals, string(b2h(encoded)), msg)
+
+		out, err := json.Marshal(decoded)
+		c.Assert(err, IsNil, msg)
+		var fields map[string]interface{}
+		c.Assert(json.Unmarshal(out, &fields), IsNil)
+		delete(fields, "hash")
+		out, err = json.Marshal(fields)
+		c.Assert(err, IsNil, msg)
+		compare(c, typ.TransactionType, raw, out)
+	}
+}
+
+func (s *CodecSuite) TestXChainTransactions(c *C) {
+	checkTransactions(c, "testdata/xchain_transactions.json", 8)
+}
+
+func (s *CodecSuite) TestMPTTransactions(c *C) {
+	checkTransactions(c, "testdata/mpt_transactions.json", 6)
+}
+
+func (s *CodecSuite) TestMPTAmount(c *C) {
+	issuer, err := NewAccountFromAddress("rJMNfiJTwXHcMdB4SpxMgL3mvV4xUVHDnd")
+	c.Assert(err, IsNil)
+	id := NewMPTIssuanceID(1, *issuer)
+	c.Check(id.String(), Equals, "00000001BE4D6872C9A8F4D11188D8E1A42F45BCFD149D07")
+	c.Check(id.MPTSequence(), Equals, uint32(1))
+	c.Check(id.MPTIssuer(), Equals, *issuer)
+
+	a, err := NewAmount("-1000/" + id.String())
+	c.Assert(err, IsNil)
+	c.Check(a.IsMPT(), Equals, true)
+	c.Check(a.IsNative(), Equals, false)
+	c.Check(a.String(), Equals, "-1000/"+id.String())
+	c.Check(string(b2h(a.Bytes())), Equals, "2000000000000003E8"+id.String())
+	b := checkBinaryMarshal(a)
+	c.Check(b.Equals(*a), Equals, true, Commentf("%s %s", a, b))
+
+	out, err := json.Marshal(a)
+	c.Assert(err, IsNil)
+	c.Check(string(out), Equals, `{"value":"-1000","mpt_issuance_id":"`+id.String()+`"}`)
+	var decoded Amount
+	c.Assert(json.Unmarshal(out, &decoded), IsNil)
+	c.Check(decoded.Equals(*a), Equals, true)
+
+	_, err = NewAmount("1.5/" + id.String())
+	c.Check(err, NotNil)
+	_, err = NewAmount("1/00000001")
+	c.Check(err, NotNil)
+
+	one := uint32(1)
+	issuance, err := GetMPTokenIssuanceIndex(id)
+	c.Assert(err, IsNil)
+	token, err := GetMPTokenIndex(id, *issuer)
+	c.Assert(err, IsNil)
+	c.Check(*token, Not(Equals), *issuance)
+	index, err := LedgerIndex(&MPTokenIssuance{Issuer: issuer, Sequence: &one})
+	c.Assert(err, IsNil)
+	c.Check(*index, Equals, *issuance)
+	index, err = LedgerIndex(&MPToken{Account: issuer, MPTokenIssuanceID: &id})
+	c.Assert(err, IsNil)
+	c.Check(*index, Equals, *token)
+}
+
+func (s *CodecSuite) TestXChainBridge(c *C) {
+	locking, err := NewAccountFromAddress("rJMNfiJTwXHcMdB4SpxMgL3mvV4xUVHDnd")
+	c.Assert(err, IsNil)
+	issuing, err := NewAccountFromAddress("rHb9CJAWyB4rj91VRWn96DkukG4bwdtyTh")
+	c.Assert(err, IsNil)
+	reward, err := NewAmount("100")
+	c.Assert(err, IsNil)
+	tx := &XChainCreateClaimID{
+		TxBase:           TxBase{TransactionType: XCHAIN_CREATE_CLAIM_ID, Account: *issuing, Fee: *reward.Value},
+		XChainBridge:     XChainBridge{LockingChainDoor: *locking, IssuingChainDoor: *issuing},
+		SignatureReward:  *reward,
+		OtherChainSource: *locking,
+	}
+	_, encoded, err := Raw(tx)
+	c.Assert(err, IsNil)
+	// Each door is a variable length account, each XRP issue a bare currency
+	xrp := strings.Repeat("00", 20)
+	bridge := "011914" + string(b2h(locking.Bytes())) + xrp + "14" + string(b2h(issuing.Bytes())) + xrp
+	c.Check(strings.HasSuffix(string(b2h(encoded)), bridge), Equals, true)
+}
+
+func (s *CodecSuite) TestXChainOwnedClaimID(c *C) {
+	b := []byte(`{
+		"Account": "rH5RpDxc1cXJhgDF4cMKkcZQ9HLYTsTZa9",
+		"Flags": 0,
+		"LedgerEntryType": "XChainOwnedClaimID",
+		"OtherChainSource": "rBEERdzDHqDyYmfw2jqzyMNBZu31yzfg6q",
+		"OwnerNode": "0000000000000000",
+		"SignatureReward": "100",
+		"XChainBridge": {
+			"IssuingChainDoor": "rHb9CJAWyB4rj91VRWn96DkukG4bwdtyTh",
+			"IssuingChainIssue": {"currency": "XRP"},
+			"LockingChainDoor": "rJMNfiJTwXHcMdB4SpxMgL3mvV4xUVHDnd",
+			"LockingChainIssue": {"currency": "XRP"}
+		},
+		"XChainClaimAttestations": [{
+			"XChainClaimProofSig": {
+				"Amount": "10000",
+				"AttestationRewardAccount": "rH5RpDxc1cXJhgDF4cMKkcZQ9HLYTsTZa9",
+				"AttestationSignerAccount": "rH5RpDxc1cXJhgDF4cMKkcZQ9HLYTsTZa9",
+				"Destination": "rvYAfWj5gh67oV6fW32ZzP3Aw4Eubs59B",
+				"PublicKey": "03ADB44CA8E56F78A0096825E5667C450ABD5C24C34E027BC1AAF7E5BD114CB5B5",
+				"WasLockingChainSend": 1
+			}
+		}],
+		"XChainClaimID": "000000000000013F"
+	}`)
+	var le XChainOwnedClaimID
+	c.Assert(json.Unmarshal(b, &le), IsNil)
+	var buf bytes.Buffer
+	c.Assert(encode(&buf, &le, false), IsNil)
+	r := bytes.NewReader(buf.Bytes())
+	leType, err := expectType(r, "LedgerEntryType")
+	c.Assert(err, IsNil)
+	c.Check(LedgerEntryType(leType), Equals, XCHAIN_OWNED_CLAIM_ID)
+	decoded := LedgerEntryFactory[leType]()
+	v := reflect.ValueOf(decoded)
+	c.Assert(readObject(r, &v), IsNil)
+	out, err := json.Marshal(decoded)
+	c.Assert(err, IsNil)
+	compare(c, "XChainOwnedClaimID", b, out)
+	c.Check(decoded.Affects(*le.Account), Equals, true)
+}
+
+func (s *CodecSuite) TestMPTBalances(c *C) {
+	b := []byte(`{
+		"Account": "rJMNfiJTwXHcMdB4SpxMgL3mvV4xUVHDnd",
+		"Amount": {"mpt_issuance_id": "00000001BE4D6872C9A8F4D11188D8E1A42F45BCFD149D07", "value": "250"},
+		"Destination": "rHb9CJAWyB4rj91VRWn96DkukG4bwdtyTh",
+		"Fee": "12",
+		"Sequence": 3,
+		"TransactionType": "Payment",
+		"meta": {
+			"AffectedNodes": [{
+				"ModifiedNode": {
+					"FinalFields": {
+						"Account": "rHb9CJAWyB4rj91VRWn96DkukG4bwdtyTh",
+						"Flags": 0,
+						"MPTAmount": "300",
+						"MPTokenIssuanceID": "00000001BE4D6872C9A8F4D11188D8E1A42F45BCFD149D07",
+						"OwnerNode": "0000000000000000"
+					},
+					"LedgerEntryType": "MPToken",
+					"LedgerIndex": "0F1D9B4B4E2B6A8E9AB3B0B5E2E8C7BB0E0F7C3D1C2A6D1B3F1E2D4C5B6A7980",
+					"PreviousFields": {"MPTAmount": "50"}
+				}
+			}],
+			"TransactionIndex": 0,
+			"TransactionResult": "tesSUCCESS"
+		}
+	}`)
+	var txm TransactionWithMetaData
+	c.Assert(json.Unmarshal(b, &txm), IsNil)
+	balances, err := txm.Balances()
+	c.Assert(err, IsNil)
+	c.Assert(balances, HasLen, 2)
+	issuer, holder := txm.GetBase().Account, txm.Transaction.(*Payment).Destination
+	held := (*balances[holder])[0]
+	c.Check(held.CounterParty, Equals, issuer)
+	c.Check(mptString(&held.Balance), Equals, "300")
+	c.Check(mptString(&held.Change), Equals, "250")
+	issued := (*balances[issuer])[0]
+	c.Check(issued.CounterParty, Equals, holder)
+	c.Check(issued.MPTIssuanceID.MPTIssuer(), Equals, issuer)
+	c.Check(mptString(&issued.Balance), Equals, "-300")
+	c.Check(mptString(&issued.Change), Equals, "-250")
+}
@@ -330,6 +330,24 @@ func readObject(r Reader, v *reflect.Value) error {
 				err := readObject(r, &ve)
 				v.FieldByName("VoteEntry").Set(ve.Elem())
 				return err
+			case "PriceData":
+				var priceData PriceDataItem
+				pd := reflect.ValueOf(&priceData)
+				err := readObject(r, &pd)
+				v.FieldByName("PriceData").Set(pd.Elem())
+				return err
+			case "XChainClaimProofSig":
+				var proof XChainClaimProofSigItem
+				p := reflect.ValueOf(&proof)
+				err := readObject(r, &p)
+				v.FieldByName("XChainClaimProofSig").Set(p.Elem())
+				return err
+			case "XChainCreateAccountProofSig":
+				var proof XChainCreateAccountProofSigItem
+				p := reflect.ValueOf(&proof)
+				err := readObject(r, &p)
+				v.FieldByName("XChainCreateAccountProofSig").Set(p.Elem())
+				return err
 			case "Memo":
 				var memo Memo
 				m := reflect.ValueOf(&memo)
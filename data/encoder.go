@@ -63,6 +63,9 @@ func raw(value interface{}, prefix HashPrefix, suffix []byte, ignoreSigningField
 func writeRaw(w io.Writer, value interface{}, ignoreSigningFields bool) error {
 	switch v := value.(type) {
 	case *Ledger:
+		if v.ParentCloseTime == nil || v.CloseTime == nil {
+			return fmt.Errorf("Ledger %d has no close times", v.LedgerSequence)
+		}
 		values := []interface{}{
 			v.LedgerSequence,
 			v.TotalXRP,
@@ -82,14 +85,20 @@ func writeRaw(w io.Writer, value interface{}, ignoreSigningFields bool) error {
 		return nil
 	case *InnerNode:
 		return write(w, v.Children)
-	case *Validation:
+	case *Validation, *Manifest:
 		return encode(w, value, ignoreSigningFields)
 	case *Proposal:
 		if ignoreSigningFields {
 			return writeValues(w, v.SigningValues())
-		} else {
-			return write(w, v)
 		}
+		return writeValues(w, []interface{}{
+			v.LedgerHash,
+			v.PreviousLedger,
+			v.Sequence,
+			v.CloseTime.Uint32(),
+			v.PublicKey,
+			[]byte(v.Signature),
+		})
 	case *TransactionWithMetaData:
 		txid, tx, err := Raw(v.Transaction)
 		if err != nil {
@@ -178,16 +187,23 @@ func getFields(v *reflect.Value, depth int) fieldSlice {
 		if fieldName == "LedgerEntryType" && depth > 1 && typ.Name() == "leBase" {
 			continue
 		}
+		// The index of an entry is its key, rather than one of its fields
+		if fieldName == "LedgerIndex" && typ.Name() == "leBase" {
+			continue
+		}
 		encoding := reverseEncodings[fieldName]
 		f := v.Field(i)
 		// fmt.Println(fieldName, encoding, f, f.Kind())
 		if f.Kind() == reflect.Interface {
 			f = f.Elem()
 		}
+		// A set pointer to an empty value, such as the Data of a DIDSet
+		// which removes it, is still encoded
+		set := f.Kind() == reflect.Ptr && !f.IsNil()
 		if f.Kind() == reflect.Ptr {
 			f = f.Elem()
 		}
-		if !f.IsValid() || (f.Kind() == reflect.Slice && f.Len() == 0) {
+		if !f.IsValid() || (f.Kind() == reflect.Slice && f.Len() == 0 && !set) {
 			continue
 		}
 		switch encoding.typ {
@@ -195,7 +211,7 @@ func getFields(v *reflect.Value, depth int) fieldSlice {
 			fields.Append(encoding, f.Addr().Interface(), nil)
 		case ST_HASH96, ST_HASH128, ST_HASH160, ST_HASH192, ST_HASH256, ST_HASH384, ST_HASH512, ST_AMOUNT, ST_VL, ST_ACCOUNT, ST_PATHSET, ST_VECTOR256:
 			fields.Append(encoding, f.Addr().Interface(), nil)
-		case ST_ISSUE:
+		case ST_ISSUE, ST_XCHAIN_BRIDGE, ST_CURRENCY:
 			fields.Append(encoding, f.Addr().Interface(), nil)
 		case ST_ARRAY:
 			var children fieldSlice
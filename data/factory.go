@@ -25,6 +25,8 @@ const (
 	NFTOKEN_PAGE                         LedgerEntryType = 0x50 // 'P'
 	OFFER                                LedgerEntryType = 0x6f // 'o'
 	ORACLE                               LedgerEntryType = 0x80
+	MPTOKEN_ISSUANCE                     LedgerEntryType = 0x7e
+	MPTOKEN                              LedgerEntryType = 0x7f
 	PAY_CHANNEL                          LedgerEntryType = 0x78 // 'x'
 	RIPPLE_STATE                         LedgerEntryType = 0x72 // 'r'
 	SIGNER_LIST                          LedgerEntryType = 0x53 // 'S'
@@ -68,22 +70,23 @@ const (
 	AMM_VOTE                              TransactionType = 38
 	AMM_BID                               TransactionType = 39
 	AMM_DELETE                            TransactionType = 40
-	DID_SET                               TransactionType = 41
-	DID_DELETE                            TransactionType = 42
-	ORACLE_SET                            TransactionType = 43
-	ORACLE_DELETE                         TransactionType = 44
-	XCHAIN_ACCOUNT_CREATE_COMMIT          TransactionType = 45
+	XCHAIN_CREATE_CLAIM_ID                TransactionType = 41
+	XCHAIN_COMMIT                         TransactionType = 42
+	XCHAIN_CLAIM                          TransactionType = 43
+	XCHAIN_ACCOUNT_CREATE_COMMIT          TransactionType = 44
+	XCHAIN_ADD_CLAIM_ATTESTATION          TransactionType = 45
 	XCHAIN_ADD_ACCOUNT_CREATE_ATTESTATION TransactionType = 46
-	XCHAIN_ADD_CLAIM_ATTESTATION          TransactionType = 47
-	XCHAIN_CLAIM                          TransactionType = 48
-	XCHAIN_COMMIT                         TransactionType = 49
-	XCHAIN_CREATE_BRIDGE                  TransactionType = 50
-	XCHAIN_CREATE_CLAIM_ID                TransactionType = 51
-	XCHAIN_MODIFY_BRIDGE                  TransactionType = 52
+	XCHAIN_MODIFY_BRIDGE                  TransactionType = 47
+	XCHAIN_CREATE_BRIDGE                  TransactionType = 48
+	DID_SET                               TransactionType = 49
+	DID_DELETE                            TransactionType = 50
+	ORACLE_SET                            TransactionType = 51
+	ORACLE_DELETE                         TransactionType = 52
 	CREDENTIAL_CREATE                     TransactionType = 53
 	MPTOKEN_ISSUANCE_CREATE               TransactionType = 54
-	MPTOKEN_ISSUANCE                      TransactionType = 55
-	MPTOKEN_ISSUANCE_DESTROY              TransactionType = 56
+	MPTOKEN_ISSUANCE_DESTROY              TransactionType = 55
+	MPTOKEN_ISSUANCE_SET                  TransactionType = 56
+	MPTOKEN_AUTHORIZE                     TransactionType = 57
 	AMENDMENT                             TransactionType = 100
 	SET_FEE                               TransactionType = 101
 	UNL_MODIFY                            TransactionType = 102
@@ -119,6 +122,10 @@ var LedgerEntryFactory = [...]func() LedgerEntry{
 		return &XChainOwnedCreateAccountClaimID{leBase: leBase{LedgerEntryType: XCHAIN_OWNED_CREATE_ACCOUNT_CLAIM_ID}}
 	},
 	CREDENTIAL: func() LedgerEntry { return &Credential{leBase: leBase{LedgerEntryType: CREDENTIAL}} },
+	MPTOKEN_ISSUANCE: func() LedgerEntry {
+		return &MPTokenIssuance{leBase: leBase{LedgerEntryType: MPTOKEN_ISSUANCE}}
+	},
+	MPTOKEN: func() LedgerEntry { return &MPToken{leBase: leBase{LedgerEntryType: MPTOKEN}} },
 }
 
 var TxFactory = [...]func() Transaction{
@@ -185,12 +192,15 @@ var TxFactory = [...]func() Transaction{
 	MPTOKEN_ISSUANCE_CREATE: func() Transaction {
 		return &MPTokenIssuanceCreate{TxBase: TxBase{TransactionType: MPTOKEN_ISSUANCE_CREATE}}
 	},
-	MPTOKEN_ISSUANCE: func() Transaction {
-		return &MPTokenIssuance{TxBase: TxBase{TransactionType: MPTOKEN_ISSUANCE}}
-	},
 	MPTOKEN_ISSUANCE_DESTROY: func() Transaction {
 		return &MPTokenIssuanceDestroy{TxBase: TxBase{TransactionType: MPTOKEN_ISSUANCE_DESTROY}}
 	},
+	MPTOKEN_ISSUANCE_SET: func() Transaction {
+		return &MPTokenIssuanceSet{TxBase: TxBase{TransactionType: MPTOKEN_ISSUANCE_SET}}
+	},
+	MPTOKEN_AUTHORIZE: func() Transaction {
+		return &MPTokenAuthorize{TxBase: TxBase{TransactionType: MPTOKEN_AUTHORIZE}}
+	},
 }
 
 var ledgerEntryNames = [...]string{
@@ -217,6 +227,8 @@ var ledgerEntryNames = [...]string{
 	XCHAIN_OWNED_CLAIM_ID:                "XChainOwnedClaimID",
 	XCHAIN_OWNED_CREATE_ACCOUNT_CLAIM_ID: "XChainOwnedCreateAccountClaimID",
 	CREDENTIAL:                           "Credential",
+	MPTOKEN_ISSUANCE:                     "MPTokenIssuance",
+	MPTOKEN:                              "MPToken",
 }
 
 var ledgerEntryTypes = map[string]LedgerEntryType{
@@ -243,6 +255,8 @@ var ledgerEntryTypes = map[string]LedgerEntryType{
 	"XChainOwnedClaimID":              XCHAIN_OWNED_CLAIM_ID,
 	"XChainOwnedCreateAccountClaimID": XCHAIN_OWNED_CREATE_ACCOUNT_CLAIM_ID,
 	"Credential":                      CREDENTIAL,
+	"MPTokenIssuance":                 MPTOKEN_ISSUANCE,
+	"MPToken":                         MPTOKEN,
 }
 
 var txNames = [...]string{
@@ -294,6 +308,10 @@ var txNames = [...]string{
 	XCHAIN_CREATE_CLAIM_ID:                "XChainCreateClaimID",
 	XCHAIN_MODIFY_BRIDGE:                  "XChainModifyBridge",
 	CREDENTIAL_CREATE:                     "CredentialCreate",
+	MPTOKEN_ISSUANCE_CREATE:               "MPTokenIssuanceCreate",
+	MPTOKEN_ISSUANCE_DESTROY:              "MPTokenIssuanceDestroy",
+	MPTOKEN_ISSUANCE_SET:                  "MPTokenIssuanceSet",
+	MPTOKEN_AUTHORIZE:                     "MPTokenAuthorize",
 }
 
 var txTypes = map[string]TransactionType{
@@ -346,8 +364,9 @@ var txTypes = map[string]TransactionType{
 	"XChainModifyBridge":                XCHAIN_MODIFY_BRIDGE,
 	"CredentialCreate":                  CREDENTIAL_CREATE,
 	"MPTokenIssuanceCreate":             MPTOKEN_ISSUANCE_CREATE,
-	"MPTokenIssuance":                   MPTOKEN_ISSUANCE,
 	"MPTokenIssuanceDestroy":            MPTOKEN_ISSUANCE_DESTROY,
+	"MPTokenIssuanceSet":                MPTOKEN_ISSUANCE_SET,
+	"MPTokenAuthorize":                  MPTOKEN_AUTHORIZE,
 }
 
 var HashableTypes []string
@@ -23,6 +23,7 @@ const (
 	HP_TRANSACTION_MULTISIGN HashPrefix = 0x534D5400 // 'SMT' inner transaction to multi-sign
 	HP_VALIDATION            HashPrefix = 0x56414C00 // 'VAL' validation for signing
 	HP_PROPOSAL              HashPrefix = 0x50525000 // 'PRP' proposal for signing
+	HP_MANIFEST              HashPrefix = 0x4D414E00 // 'MAN' manifest for signing
 
 	// Node Types
 	NT_UNKNOWN          NodeType = 0
@@ -37,23 +38,34 @@ const (
 	NF_WIRE   NodeFormat = 3
 
 	// Ledger index NameSpaces
-	NS_ACCOUNT         LedgerNamespace = 'a'
-	NS_DIRECTORY_NODE  LedgerNamespace = 'd'
-	NS_RIPPLE_STATE    LedgerNamespace = 'r'
-	NS_OFFER           LedgerNamespace = 'o' // Entry for an offer
-	NS_OWNER_DIRECTORY LedgerNamespace = 'O' // Directory of things owned by an account
-	NS_BOOK_DIRECTORY  LedgerNamespace = 'B' // Directory of order books
-	NS_SKIP_LIST       LedgerNamespace = 's'
-	NS_AMENDMENT       LedgerNamespace = 'f'
-	NS_FEE             LedgerNamespace = 'e'
-	NS_SUSPAY          LedgerNamespace = 'u'
-	NS_TICKET          LedgerNamespace = 'T'
-	NS_SIGNER_LIST     LedgerNamespace = 'S'
-	NS_XRPU_CHANNEL    LedgerNamespace = 'x'
-	NS_CHECK           LedgerNamespace = 'C'
-	NS_DEPOSIT_PREAUTH LedgerNamespace = 'p'
-	NS_NEGATIVE_UNL    LedgerNamespace = 'N'
-	NS_AMM             LedgerNamespace = 'A'
+	NS_ACCOUNT          LedgerNamespace = 'a'
+	NS_DIRECTORY_NODE   LedgerNamespace = 'd'
+	NS_RIPPLE_STATE     LedgerNamespace = 'r'
+	NS_OFFER            LedgerNamespace = 'o' // Entry for an offer
+	NS_OWNER_DIRECTORY  LedgerNamespace = 'O' // Directory of things owned by an account
+	NS_BOOK_DIRECTORY   LedgerNamespace = 'B' // Directory of order books
+	NS_SKIP_LIST        LedgerNamespace = 's'
+	NS_AMENDMENT        LedgerNamespace = 'f'
+	NS_FEE              LedgerNamespace = 'e'
+	NS_SUSPAY           LedgerNamespace = 'u'
+	NS_TICKET           LedgerNamespace = 'T'
+	NS_SIGNER_LIST      LedgerNamespace = 'S'
+	NS_XRPU_CHANNEL     LedgerNamespace = 'x'
+	NS_CHECK            LedgerNamespace = 'C'
+	NS_DEPOSIT_PREAUTH  LedgerNamespace = 'p'
+	NS_NEGATIVE_UNL     LedgerNamespace = 'N'
+	NS_AMM              LedgerNamespace = 'A'
+	NS_MPTOKEN_ISSUANCE LedgerNamespace = '~'
+	NS_MPTOKEN          LedgerNamespace = 't'
+	NS_NFTOKEN_OFFER    LedgerNamespace = 'q'
+	NS_NFTOKEN_BUYS     LedgerNamespace = 'h' // Directory of buy offers for an NFToken
+	NS_NFTOKEN_SELLS    LedgerNamespace = 'i' // Directory of sell offers for an NFToken
+	NS_BRIDGE           LedgerNamespace = 'H'
+	NS_XCHAIN_CLAIM_ID  LedgerNamespace = 'Q'
+	NS_XCHAIN_CREATE    LedgerNamespace = 'K' // Entry for an account creation claim
+	NS_DID              LedgerNamespace = 'I'
+	NS_ORACLE           LedgerNamespace = 'R'
+	NS_CREDENTIAL       LedgerNamespace = 'D'
 )
 
 var nodeTypes = [...]string{
@@ -95,6 +107,7 @@ const (
 	ST_HASH512       uint8 = 23
 	ST_ISSUE         uint8 = 24
 	ST_XCHAIN_BRIDGE uint8 = 25
+	ST_CURRENCY      uint8 = 26
 )
 
 // See rippled's SField.cpp for the strings and corresponding encoding values.
@@ -104,6 +117,7 @@ var encodings = map[enc]string{
 	{ST_UINT8, 2}: "Method",
 	{ST_UINT8, 3}: "TransactionResult",
 	{ST_UINT8, 4}: "Scale",
+	{ST_UINT8, 5}: "AssetScale",
 	// 8-bit unsigned integers (uncommon)
 	{ST_UINT8, 16}: "TickSize",
 	{ST_UINT8, 17}: "UNLModifyDisabling",
@@ -137,6 +151,7 @@ var encodings = map[enc]string{
 	{ST_UINT32, 12}: "WalletSize",
 	{ST_UINT32, 13}: "OwnerCount",
 	{ST_UINT32, 14}: "DestinationTag",
+	{ST_UINT32, 15}: "LastUpdateTime",
 	// 32-bit unsigned integers (uncommon)
 	{ST_UINT32, 16}: "HighQualityIn",
 	{ST_UINT32, 17}: "HighQualityOut",
@@ -171,6 +186,7 @@ var encodings = map[enc]string{
 	{ST_UINT32, 46}: "EmitGeneration",
 	{ST_UINT32, 48}: "VoteWeight",
 	{ST_UINT32, 50}: "FirstNFTokenSequence",
+	{ST_UINT32, 51}: "OracleDocumentID",
 	// 64-bit unsigned integers (common)
 	{ST_UINT64, 1}:  "IndexNext",
 	{ST_UINT64, 2}:  "IndexPrevious",
@@ -190,6 +206,13 @@ var encodings = map[enc]string{
 	{ST_UINT64, 17}: "HookInstructionCount",
 	{ST_UINT64, 18}: "HookReturnCode",
 	{ST_UINT64, 19}: "ReferenceCount",
+	{ST_UINT64, 20}: "XChainClaimID",
+	{ST_UINT64, 21}: "XChainAccountCreateCount",
+	{ST_UINT64, 22}: "XChainAccountClaimCount",
+	{ST_UINT64, 23}: "AssetPrice",
+	{ST_UINT64, 24}: "MaximumAmount",
+	{ST_UINT64, 25}: "OutstandingAmount",
+	{ST_UINT64, 26}: "MPTAmount",
 	// 128-bit (common)
 	{ST_HASH128, 1}: "EmailHash",
 
@@ -199,6 +222,9 @@ var encodings = map[enc]string{
 	{ST_HASH160, 3}: "TakerGetsCurrency",
 	{ST_HASH160, 4}: "TakerGetsIssuer",
 
+	// 192-bit (common)
+	{ST_HASH192, 1}: "MPTokenIssuanceID",
+
 	// 256-bit (common)
 	{ST_HASH256, 1}:  "LedgerHash",
 	{ST_HASH256, 2}:  "ParentHash",
@@ -291,6 +317,11 @@ var encodings = map[enc]string{
 	{ST_VL, 23}: "HookReturnString",
 	{ST_VL, 24}: "HookParameterName",
 	{ST_VL, 25}: "HookParameterValue",
+	{ST_VL, 26}: "DIDDocument",
+	{ST_VL, 27}: "Data",
+	{ST_VL, 28}: "AssetClass",
+	{ST_VL, 29}: "Provider",
+	{ST_VL, 30}: "MPTokenMetadata",
 	// account (common)
 	{ST_ACCOUNT, 1}:  "Account",
 	{ST_ACCOUNT, 2}:  "Owner",
@@ -302,8 +333,15 @@ var encodings = map[enc]string{
 	{ST_ACCOUNT, 8}:  "RegularKey",
 	{ST_ACCOUNT, 9}:  "NFTokenMinter",
 	{ST_ACCOUNT, 10}: "EmitCallback",
+	{ST_ACCOUNT, 11}: "Holder",
 	// account (uncommon)
 	{ST_ACCOUNT, 16}: "HookAccount",
+	{ST_ACCOUNT, 18}: "OtherChainSource",
+	{ST_ACCOUNT, 19}: "OtherChainDestination",
+	{ST_ACCOUNT, 20}: "AttestationSignerAccount",
+	{ST_ACCOUNT, 21}: "AttestationRewardAccount",
+	{ST_ACCOUNT, 22}: "LockingChainDoor",
+	{ST_ACCOUNT, 23}: "IssuingChainDoor",
 	// vector of 256-bit
 	{ST_VECTOR256, 1}: "Indexes",
 	{ST_VECTOR256, 2}: "Hashes",
@@ -312,8 +350,15 @@ var encodings = map[enc]string{
 	// path set
 	{ST_PATHSET, 1}: "Paths",
 	// issue
+	{ST_ISSUE, 1}: "LockingChainIssue",
+	{ST_ISSUE, 2}: "IssuingChainIssue",
 	{ST_ISSUE, 3}: "Asset",
 	{ST_ISSUE, 4}: "Asset2",
+	// cross-chain bridge
+	{ST_XCHAIN_BRIDGE, 1}: "XChainBridge",
+	// currency
+	{ST_CURRENCY, 1}: "BaseAsset",
+	{ST_CURRENCY, 2}: "QuoteAsset",
 	// inner object
 	{ST_OBJECT, 1}:  "EndOfObject",
 	{ST_OBJECT, 2}:  "TransactionMetaData",
@@ -341,6 +386,11 @@ var encodings = map[enc]string{
 	{ST_OBJECT, 25}: "VoteEntry",
 	{ST_OBJECT, 26}: "AuctionSlot",
 	{ST_OBJECT, 27}: "AuthAccount",
+	{ST_OBJECT, 28}: "XChainClaimProofSig",
+	{ST_OBJECT, 29}: "XChainCreateAccountProofSig",
+	{ST_OBJECT, 30}: "XChainClaimAttestationCollectionElement",
+	{ST_OBJECT, 31}: "XChainCreateAccountAttestationCollectionElement",
+	{ST_OBJECT, 32}: "PriceData",
 	// array of objects
 	{ST_ARRAY, 1}:  "EndOfArray",
 	{ST_ARRAY, 2}:  "SigningAccounts",
@@ -360,6 +410,9 @@ var encodings = map[enc]string{
 	{ST_ARRAY, 18}: "HookExecutions",
 	{ST_ARRAY, 19}: "HookParameters",
 	{ST_ARRAY, 20}: "HookGrants",
+	{ST_ARRAY, 21}: "XChainClaimAttestations",
+	{ST_ARRAY, 22}: "XChainCreateAccountAttestations",
+	{ST_ARRAY, 24}: "PriceDataSeries",
 	{ST_ARRAY, 25}: "AuthAccounts",
 }
 
@@ -371,7 +424,8 @@ func init() {
 	signingFields = make(map[enc]struct{})
 	for e, name := range encodings {
 		reverseEncodings[name] = e
-		if strings.Contains(name, "Signature") {
+		// SignatureReward is an amount, not a signature
+		if strings.Contains(name, "Signature") && name != "SignatureReward" {
 			signingFields[e] = struct{}{}
 		}
 	}
@@ -29,6 +29,7 @@ func (keyType KeyType) String() string {
 
 type Hash128 [16]byte
 type Hash160 [20]byte
+type Hash192 [24]byte
 type Hash256 [32]byte
 type Vector256 []Hash256
 type VariableLength []byte
@@ -82,6 +83,17 @@ func (h *Hash160) Currency() *Currency {
 	return &c
 }
 
+func (h *Hash192) Bytes() []byte {
+	if h == nil {
+		return nil
+	}
+	return h[:]
+}
+
+func (h Hash192) String() string {
+	return string(b2h(h[:]))
+}
+
 // Accepts either a hex string or a byte slice of length 32
 func NewHash256(value interface{}) (*Hash256, error) {
 	var h Hash256
@@ -25,6 +25,9 @@ func (i *NodeIndex) Next() *NodeIndex {
 	return &next
 }
 
+// LedgerIndex derives the index of a ledger entry from its fields. Some
+// entries do not hold all of the fields their index is derived from, in
+// which case the index found in JSON, or read with the entry, is returned.
 func LedgerIndex(le LedgerEntry) (*Hash256, error) {
 	switch v := le.(type) {
 	case *AccountRoot:
@@ -34,16 +37,101 @@ func LedgerIndex(le LedgerEntry) (*Hash256, error) {
 	case *Offer:
 		return GetOfferIndex(*v.Account, *v.Sequence)
 	case *LedgerHashes:
+		// The skip list cannot be told apart from the pages of older hashes
+		if known := knownIndex(le); known != nil {
+			return known, nil
+		}
 		return GetLedgerHashIndex()
 	case *Directory:
-		return GetDirectoryNodeIndex(*v.RootIndex, v.IndexPrevious.Next())
+		// Pages do not hold their own number, which can only be guessed
+		if known := knownIndex(le); known != nil {
+			return known, nil
+		}
+		return GetDirectoryNodeIndex(*v.RootIndex, v.page())
 	case *FeeSettings:
-		return buildIndex([]interface{}{NS_FEE})
+		return GetFeeIndex()
 	case *Amendments:
-		return buildIndex([]interface{}{NS_AMENDMENT})
+		return GetAmendmentsIndex()
+	case *NegativeUNL:
+		return GetNegativeUNLIndex()
+	case *Escrow:
+		if v.Sequence != nil {
+			return GetEscrowIndex(v.Account, *v.Sequence)
+		}
+	case *Check:
+		if v.Account != nil && v.Sequence != nil {
+			return GetCheckIndex(*v.Account, *v.Sequence)
+		}
+	case *PayChannel:
+		if v.Account != nil && v.Destination != nil && v.Sequence != nil {
+			return GetPayChannelIndex(*v.Account, *v.Destination, *v.Sequence)
+		}
+	case *Ticket:
+		if v.Account != nil && v.TicketSequence != nil {
+			return GetTicketIndex(*v.Account, *v.TicketSequence)
+		}
+	case *DepositPreAuth:
+		if v.Account != nil && v.Authorize != nil {
+			return GetDepositPreAuthIndex(*v.Account, *v.Authorize)
+		}
+	case *NFTokenOffer:
+		if v.Owner != nil && v.Sequence != nil {
+			return GetNFTokenOfferIndex(*v.Owner, *v.Sequence)
+		}
+	case *AMM:
+		if v.Asset != nil && v.Asset2 != nil {
+			return GetAMMIndex(*v.Asset, *v.Asset2)
+		}
+	case *Did:
+		if v.Account != nil {
+			return GetDIDIndex(*v.Account)
+		}
+	case *Oracle:
+		if v.Owner != nil && v.OracleDocumentID != nil {
+			return GetOracleIndex(*v.Owner, *v.OracleDocumentID)
+		}
+	case *Credential:
+		if v.Subject != nil && v.Issuer != nil && v.CredentialType != nil {
+			return GetCredentialIndex(*v.Subject, *v.Issuer, *v.CredentialType)
+		}
+	case *Bridge:
+		if v.Account != nil && v.XChainBridge != nil {
+			return GetBridgeIndex(*v.XChainBridge, v.Account.Equals(v.XChainBridge.LockingChainDoor))
+		}
+	case *XChainOwnedClaimID:
+		if v.XChainBridge != nil && v.XChainClaimID != nil {
+			return GetXChainClaimIDIndex(*v.XChainBridge, uint64(*v.XChainClaimID))
+		}
+	case *XChainOwnedCreateAccountClaimID:
+		if v.XChainBridge != nil && v.XChainAccountCreateCount != nil {
+			return GetXChainCreateAccountClaimIDIndex(*v.XChainBridge, uint64(*v.XChainAccountCreateCount))
+		}
+	case *MPTokenIssuance:
+		return GetMPTokenIssuanceIndex(v.MPTIssuanceID())
+	case *MPToken:
+		return GetMPTokenIndex(*v.MPTokenIssuanceID, *v.Account)
+	case *SignerList, *NFTokenPage:
+		// The owner is not held by the entry
 	default:
 		return nil, fmt.Errorf("Unknown LedgerEntry")
 	}
+	if known := knownIndex(le); known != nil {
+		return known, nil
+	}
+	return nil, fmt.Errorf("Cannot derive index of %s", le.GetType())
+}
+
+// knownIndex returns the index found in JSON, or read with the entry.
+func knownIndex(le LedgerEntry) *Hash256 {
+	switch {
+	case le.GetLedgerIndex() != nil:
+		return le.GetLedgerIndex()
+	case !le.GetHash().IsZero():
+		// ReadLedgerEntry keeps the index suffixed to the entry as its hash
+		return le.GetHash()
+	default:
+		return nil
+	}
 }
 
 func GetAccountRootIndex(account Account) (*Hash256, error) {
@@ -72,9 +160,8 @@ func GetOwnerDirectoryIndex(account Account) (*Hash256, error) {
 	return buildIndex([]interface{}{NS_OWNER_DIRECTORY, account.Bytes()})
 }
 
-func GetBookIndex(paysCurrency, getsCurrency Hash160, paysIssuer, getsIssuer Hash160) (*Hash256, error) {
-	//TODO: change types to Currency and Account
-	index, err := buildIndex([]interface{}{NS_BOOK_DIRECTORY, paysCurrency.Bytes(), getsCurrency.Bytes(), paysCurrency.Bytes(), getsCurrency.Bytes()})
+func GetBookIndex(paysCurrency, getsCurrency Currency, paysIssuer, getsIssuer Account) (*Hash256, error) {
+	index, err := buildIndex([]interface{}{NS_BOOK_DIRECTORY, paysCurrency.Bytes(), getsCurrency.Bytes(), paysIssuer.Bytes(), getsIssuer.Bytes()})
 	if err != nil {
 		return nil, err
 	}
@@ -83,6 +170,126 @@ func GetBookIndex(paysCurrency, getsCurrency Hash160, paysIssuer, getsIssuer Has
 	return index, nil
 }
 
+func GetEscrowIndex(account Account, sequence uint32) (*Hash256, error) {
+	return buildIndex([]interface{}{NS_SUSPAY, account.Bytes(), sequence})
+}
+
+func GetCheckIndex(account Account, sequence uint32) (*Hash256, error) {
+	return buildIndex([]interface{}{NS_CHECK, account.Bytes(), sequence})
+}
+
+func GetPayChannelIndex(account, destination Account, sequence uint32) (*Hash256, error) {
+	return buildIndex([]interface{}{NS_XRPU_CHANNEL, account.Bytes(), destination.Bytes(), sequence})
+}
+
+func GetTicketIndex(account Account, ticketSequence uint32) (*Hash256, error) {
+	return buildIndex([]interface{}{NS_TICKET, account.Bytes(), ticketSequence})
+}
+
+// An account has at most one signer list, whose id is always zero
+func GetSignerListIndex(account Account) (*Hash256, error) {
+	return buildIndex([]interface{}{NS_SIGNER_LIST, account.Bytes(), uint32(0)})
+}
+
+func GetDepositPreAuthIndex(account, authorized Account) (*Hash256, error) {
+	return buildIndex([]interface{}{NS_DEPOSIT_PREAUTH, account.Bytes(), authorized.Bytes()})
+}
+
+func GetNFTokenOfferIndex(owner Account, sequence uint32) (*Hash256, error) {
+	return buildIndex([]interface{}{NS_NFTOKEN_OFFER, owner.Bytes(), sequence})
+}
+
+func GetNFTokenBuyOffersIndex(id Hash256) (*Hash256, error) {
+	return buildIndex([]interface{}{NS_NFTOKEN_BUYS, id})
+}
+
+func GetNFTokenSellOffersIndex(id Hash256) (*Hash256, error) {
+	return buildIndex([]interface{}{NS_NFTOKEN_SELLS, id})
+}
+
+// NFTokenPages are not hashed, but are the owner followed by the low 96 bits
+// of the greatest token the page may hold. The page holding a token is the
+// first at or after GetNFTokenPageIndex(owner, token).
+func GetNFTokenPageIndex(owner Account, token Hash256) *Hash256 {
+	var index Hash256
+	copy(index[:], owner.Bytes())
+	copy(index[20:], token[20:])
+	return &index
+}
+
+func GetNFTokenPageMinIndex(owner Account) *Hash256 {
+	return GetNFTokenPageIndex(owner, Hash256{})
+}
+
+// The last page of an owner always has the maximum index
+func GetNFTokenPageMaxIndex(owner Account) *Hash256 {
+	var max Hash256
+	for i := range max {
+		max[i] = 0xFF
+	}
+	return GetNFTokenPageIndex(owner, max)
+}
+
+// The index of an AMM is the same whichever way round its assets are given
+func GetAMMIndex(asset, asset2 Issue) (*Hash256, error) {
+	if c := bytes.Compare(asset.Currency.Bytes(), asset2.Currency.Bytes()); c > 0 || (c == 0 && bytes.Compare(asset.Issuer.Bytes(), asset2.Issuer.Bytes()) > 0) {
+		asset, asset2 = asset2, asset
+	}
+	return buildIndex([]interface{}{NS_AMM, asset.Issuer.Bytes(), asset.Currency.Bytes(), asset2.Issuer.Bytes(), asset2.Currency.Bytes()})
+}
+
+func GetDIDIndex(account Account) (*Hash256, error) {
+	return buildIndex([]interface{}{NS_DID, account.Bytes()})
+}
+
+func GetOracleIndex(owner Account, documentID uint32) (*Hash256, error) {
+	return buildIndex([]interface{}{NS_ORACLE, owner.Bytes(), documentID})
+}
+
+func GetCredentialIndex(subject, issuer Account, credentialType VariableLength) (*Hash256, error) {
+	return buildIndex([]interface{}{NS_CREDENTIAL, subject.Bytes(), issuer.Bytes(), credentialType.Bytes()})
+}
+
+// Each bridge has an entry on both chains, owned by the door account
+// of that chain. A door has only one bridge for each currency, so the
+// issuer is not part of the index.
+func GetBridgeIndex(bridge XChainBridge, lockingChain bool) (*Hash256, error) {
+	door, currency := bridge.IssuingChainDoor, bridge.IssuingChainIssue.Currency
+	if lockingChain {
+		door, currency = bridge.LockingChainDoor, bridge.LockingChainIssue.Currency
+	}
+	return buildIndex([]interface{}{NS_BRIDGE, door.Bytes(), currency.Bytes()})
+}
+
+func GetXChainClaimIDIndex(bridge XChainBridge, claimID uint64) (*Hash256, error) {
+	return buildIndex(append([]interface{}{NS_XCHAIN_CLAIM_ID}, append(bridgeItems(bridge), claimID)...))
+}
+
+func GetXChainCreateAccountClaimIDIndex(bridge XChainBridge, count uint64) (*Hash256, error) {
+	return buildIndex(append([]interface{}{NS_XCHAIN_CREATE}, append(bridgeItems(bridge), count)...))
+}
+
+func bridgeItems(bridge XChainBridge) []interface{} {
+	return []interface{}{
+		bridge.LockingChainDoor.Bytes(), bridge.LockingChainIssue.Currency.Bytes(), bridge.LockingChainIssue.Issuer.Bytes(),
+		bridge.IssuingChainDoor.Bytes(), bridge.IssuingChainIssue.Currency.Bytes(), bridge.IssuingChainIssue.Issuer.Bytes(),
+	}
+}
+
+func GetMPTokenIssuanceIndex(id Hash192) (*Hash256, error) {
+	return buildIndex([]interface{}{NS_MPTOKEN_ISSUANCE, id.Bytes()})
+}
+
+// The index of a holder's MPToken is derived from the index of the
+// issuance, rather than its id
+func GetMPTokenIndex(id Hash192, holder Account) (*Hash256, error) {
+	issuance, err := GetMPTokenIssuanceIndex(id)
+	if err != nil {
+		return nil, err
+	}
+	return buildIndex([]interface{}{NS_MPTOKEN, issuance.Bytes(), holder.Bytes()})
+}
+
 func GetFeeIndex() (*Hash256, error) {
 	return buildIndex([]interface{}{NS_FEE})
 }
@@ -91,6 +298,10 @@ func GetAmendmentsIndex() (*Hash256, error) {
 	return buildIndex([]interface{}{NS_AMENDMENT})
 }
 
+func GetNegativeUNLIndex() (*Hash256, error) {
+	return buildIndex([]interface{}{NS_NEGATIVE_UNL})
+}
+
 func GetLedgerHashIndex() (*Hash256, error) {
 	return buildIndex([]interface{}{NS_SKIP_LIST})
 }
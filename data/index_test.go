@@ -0,0 +1,148 @@
+package data
+
+import (
+	"encoding/hex"
+
+	. "gopkg.in/check.v1"
+)
+
+type IndexSuite struct{}
+
+var _ = Suite(&IndexSuite{})
+
+func account(c *C, address string) Account {
+	a, err := NewAccountFromAddress(address)
+	c.Assert(err, IsNil)
+	return *a
+}
+
+func checkIndex(c *C, index *Hash256, err error, expected string) {
+	c.Assert(err, IsNil)
+	c.Check(index.String(), Equals, expected)
+}
+
+// MPT indexes, checked against an independent implementation of the
+// mptIssuance and mptoken keylets of rippled
+func (s *IndexSuite) TestMPTIndexes(c *C) {
+	id := NewMPTIssuanceID(1, account(c, "rJMNfiJTwXHcMdB4SpxMgL3mvV4xUVHDnd"))
+	index, err := GetMPTokenIssuanceIndex(id)
+	checkIndex(c, index, err, "3A1A4BC889CE4C1F055D1B8C188B166FAAB4884177B43E4C237AC1827268301C")
+	index, err = GetMPTokenIndex(id, account(c, "rHb9CJAWyB4rj91VRWn96DkukG4bwdtyTh"))
+	checkIndex(c, index, err, "7DE2A3051E9AFA814585D1921E188D3F1D07E8A2E38AE956DF4C04DEA1D8749F")
+}
+
+// XChain indexes, checked against an independent implementation of the
+// bridge, xChainClaimID and xChainCreateAccountClaimID keylets of rippled
+func (s *IndexSuite) TestXChainIndexes(c *C) {
+	locking, issuing := account(c, "rJMNfiJTwXHcMdB4SpxMgL3mvV4xUVHDnd"), account(c, "rHb9CJAWyB4rj91VRWn96DkukG4bwdtyTh")
+	bridge := XChainBridge{LockingChainDoor: locking, IssuingChainDoor: issuing}
+	index, err := GetBridgeIndex(bridge, true)
+	checkIndex(c, index, err, "7B71D4983B1F9543B242806447B484D271271949D29128D6342CEFF227BD3BA8")
+	index, err = GetBridgeIndex(bridge, false)
+	checkIndex(c, index, err, "20C736B81A2632BE6A0FCE130FC3649334A041B79C6017896709F39B6059DB92")
+	index, err = GetXChainClaimIDIndex(bridge, 3)
+	checkIndex(c, index, err, "1040B8CC8C5A3F90924D2D66D9625AC958D02DAC6C0914B50B9773F721C0F37C")
+	index, err = GetXChainCreateAccountClaimIDIndex(bridge, 3)
+	checkIndex(c, index, err, "41AEDA65334BBFBFE00AF9EF5808800B8B6F093A6B094114D5B045DDE139351B")
+
+	usd, err := NewCurrency("USD")
+	c.Assert(err, IsNil)
+	bridge.LockingChainIssue = Issue{Currency: usd, Issuer: locking}
+	bridge.IssuingChainIssue = Issue{Currency: usd, Issuer: issuing}
+	index, err = GetBridgeIndex(bridge, true)
+	checkIndex(c, index, err, "C4C26DB06CB1FBC8EDAE4746D58E4812B35DF14915E72433A1752E0217EE8996")
+	index, err = GetBridgeIndex(bridge, false)
+	checkIndex(c, index, err, "75705E90AEF08673970F9A5D1F7CB2EFA26426CAEACB3AB7DBB04724E2CAED04")
+	index, err = GetXChainClaimIDIndex(bridge, 1)
+	checkIndex(c, index, err, "978983C1B27489E71D6A2CF509C4DEEAE36004B82ABEC4F6B62E5E4D4815357A")
+}
+
+// Indexes of entries found in mainnet ledgers
+func (s *IndexSuite) TestMainnetIndexes(c *C) {
+	index, err := GetAccountRootIndex(account(c, "rHb9CJAWyB4rj91VRWn96DkukG4bwdtyTh"))
+	checkIndex(c, index, err, "2B6AC232AA4C4BE41BF49D2459FA4A0347E1B543A4C92FCEE0821C0201E2E9A8")
+	index, err = GetFeeIndex()
+	checkIndex(c, index, err, "4BC50C9B0D8515D3EAAE1E74B29A95804346C491EE1A95BF25E4AAB854A6A651")
+	index, err = GetAmendmentsIndex()
+	checkIndex(c, index, err, "7DB0788C020F02780A673DC74757F23823FA3014C1866E72CC4CD8B226CD6EF4")
+	index, err = GetLedgerHashIndex()
+	checkIndex(c, index, err, "B4979A36CDC7F3D3D5C31A4EAE2AC7D7209DDA877588B9AFC66799692AB0D66B")
+	index, err = GetNegativeUNLIndex()
+	checkIndex(c, index, err, "2E8A59AA9D3B5B186B0B9E0F62E6C02587CA74A4D778938E957B6357D364B244")
+	index, err = GetTicketIndex(account(c, "rpiFwLYi6Gb1ESHYorn2QG1WU5vw2u4exQ"), 104488993)
+	checkIndex(c, index, err, "7CB519773389FD3369ABDAA009E07AD8638FCE7BA6B7B31AD64BAAC71F6A2168")
+	index, err = GetTicketIndex(account(c, "rGV6cXKWcjQhCXoffHoRN6jn9QEUqg4wWm"), 97416413)
+	checkIndex(c, index, err, "A84840C4842C2F4A877965A3CEDC043F515C94725532C52E9CFF7EC7DAC3D989")
+	index, err = GetDIDIndex(account(c, "rH5RpDxc1cXJhgDF4cMKkcZQ9HLYTsTZa9"))
+	checkIndex(c, index, err, "E7B4E7672F036836096917CED6C8262EF9077B63885198C96BA6ADB74523E529")
+	index, err = GetOracleIndex(account(c, "rBEERdzDHqDyYmfw2jqzyMNBZu31yzfg6q"), 1)
+	checkIndex(c, index, err, "99D9629FC946D6B57DB9EDE033E6234ED765E07B6112F7CBA52683C9DD730924")
+	index, err = GetCredentialIndex(account(c, "rPEPPER7kfTD9w2To4CQk6UCfuHM9c6GDY"), account(c, "rBEERdzDHqDyYmfw2jqzyMNBZu31yzfg6q"), VariableLength("KYC"))
+	checkIndex(c, index, err, "80908278168016845B737C8092C2C2A677C621E6D49499ACB99674101C8FC15D")
+
+	// XRP/USD book in ledger 6000000
+	usd, err := NewCurrency("USD")
+	c.Assert(err, IsNil)
+	b, err := hex.DecodeString("2B6C42A95B3F7EE1971E4A10098E8F1B5F66AA08")
+	c.Assert(err, IsNil)
+	var issuer Account
+	copy(issuer[:], b)
+	index, err = GetBookIndex(usd, Currency{}, issuer, Account{})
+	checkIndex(c, index, err, "2FB4904ACFB96228FC002335B1B5A4C5584D9D727BBE82140000000000000000")
+
+	token, err := NewHash256("0018000025B89F24B381CABA5921FF0B634DE9111D915B2A4E84C37C000008E1")
+	c.Assert(err, IsNil)
+	owner := account(c, "rhSTwqSK13zdRmzHMZZP8i7DnuG27pwX76")
+	page, err := NewHash256("25B89F24B381CABA5921FF0B634DE9111D915B2AD2BEF90D7C99A8F7B2DCF3B0")
+	c.Assert(err, IsNil)
+	c.Check(GetNFTokenPageIndex(owner, *token).Compare(*page) <= 0, Equals, true)
+	c.Check(GetNFTokenPageMinIndex(owner).Compare(*page) < 0, Equals, true)
+	c.Check(GetNFTokenPageMaxIndex(owner).Compare(*page) > 0, Equals, true)
+}
+
+func (s *IndexSuite) TestLedgerIndex(c *C) {
+	alice, bob := account(c, "rH5RpDxc1cXJhgDF4cMKkcZQ9HLYTsTZa9"), account(c, "rBEERdzDHqDyYmfw2jqzyMNBZu31yzfg6q")
+	usd, err := NewCurrency("USD")
+	c.Assert(err, IsNil)
+	sequence, count := uint32(7), Uint64Hex(3)
+	bridge := XChainBridge{
+		LockingChainDoor:  alice,
+		IssuingChainDoor:  bob,
+		IssuingChainIssue: Issue{Currency: usd, Issuer: bob},
+	}
+	xrp, iou := Issue{}, Issue{Currency: usd, Issuer: alice}
+	for _, test := range []struct {
+		le    LedgerEntry
+		index func() (*Hash256, error)
+	}{
+		{&Escrow{Account: alice, Sequence: &sequence}, func() (*Hash256, error) { return GetEscrowIndex(alice, sequence) }},
+		{&Check{Account: &alice, Sequence: &sequence}, func() (*Hash256, error) { return GetCheckIndex(alice, sequence) }},
+		{&PayChannel{Account: &alice, Destination: &bob, Sequence: &sequence}, func() (*Hash256, error) { return GetPayChannelIndex(alice, bob, sequence) }},
+		{&DepositPreAuth{Account: &alice, Authorize: &bob}, func() (*Hash256, error) { return GetDepositPreAuthIndex(alice, bob) }},
+		{&NFTokenOffer{Owner: &alice, Sequence: &sequence}, func() (*Hash256, error) { return GetNFTokenOfferIndex(alice, sequence) }},
+		{&AMM{Asset: &iou, Asset2: &xrp}, func() (*Hash256, error) { return GetAMMIndex(xrp, iou) }},
+		{&Bridge{Account: &bob, XChainBridge: &bridge}, func() (*Hash256, error) { return GetBridgeIndex(bridge, false) }},
+		{&XChainOwnedClaimID{XChainBridge: &bridge, XChainClaimID: &count}, func() (*Hash256, error) { return GetXChainClaimIDIndex(bridge, 3) }},
+		{&XChainOwnedCreateAccountClaimID{XChainBridge: &bridge, XChainAccountCreateCount: &count}, func() (*Hash256, error) { return GetXChainCreateAccountClaimIDIndex(bridge, 3) }},
+	} {
+		expected, err := test.index()
+		c.Assert(err, IsNil)
+		index, err := LedgerIndex(test.le)
+		c.Assert(err, IsNil, Commentf("%T", test.le))
+		c.Check(*index, Equals, *expected, Commentf("%T", test.le))
+	}
+	locking, err := GetBridgeIndex(bridge, true)
+	c.Assert(err, IsNil)
+	issuing, err := GetBridgeIndex(bridge, false)
+	c.Assert(err, IsNil)
+	c.Check(*locking, Not(Equals), *issuing)
+
+	// Entries without the fields of their index use the one they were read with
+	_, err = LedgerIndex(&SignerList{})
+	c.Check(err, NotNil)
+	known, err := GetSignerListIndex(alice)
+	c.Assert(err, IsNil)
+	index, err := LedgerIndex(&SignerList{leBase: leBase{LedgerIndex: known}})
+	c.Assert(err, IsNil)
+	c.Check(*index, Equals, *known)
+}
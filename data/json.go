@@ -80,6 +80,10 @@ func (txm *TransactionWithMetaData) UnmarshalJSON(b []byte) error {
 		return fmt.Errorf("Not a valid transaction with metadata: Missing TransactionType")
 	}
 	txType := txTypeMatch[1]
+	b, err := ExpandXAddresses(b)
+	if err != nil {
+		return err
+	}
 	txm.Transaction = GetTxFactoryByType(txType)()
 	if err := json.Unmarshal(b, txm.Transaction); err != nil {
 		return err
@@ -370,10 +374,18 @@ type amountJSON struct {
 	Issuer   Account         `json:"issuer"`
 }
 
+type mptAmountJSON struct {
+	Value         string   `json:"value"`
+	MPTIssuanceID *Hash192 `json:"mpt_issuance_id"`
+}
+
 func (a Amount) MarshalJSON() ([]byte, error) {
 	if a.Value == nil {
 		return nil, fmt.Errorf("Value has a nil Value")
 	}
+	if a.IsMPT() {
+		return json.Marshal(mptAmountJSON{mptString(a.Value), a.MPTIssuanceID})
+	}
 	if a.IsNative() {
 		return []byte(`"` + strconv.FormatUint(a.num, 10) + `"`), nil
 	}
@@ -385,6 +397,18 @@ func (a *Amount) UnmarshalJSON(b []byte) (err error) {
 		a.Value = new(Value)
 		return json.Unmarshal(b, a.Value)
 	}
+	var mpt mptAmountJSON
+	if err := json.Unmarshal(b, &mpt); err != nil {
+		return err
+	}
+	if mpt.MPTIssuanceID != nil {
+		amount, err := newMPTAmount(mpt.Value, mpt.MPTIssuanceID.String())
+		if err != nil {
+			return err
+		}
+		*a = *amount
+		return nil
+	}
 	var dummy amountJSON
 	if err := json.Unmarshal(b, &dummy); err != nil {
 		return err
@@ -393,6 +417,17 @@ func (a *Amount) UnmarshalJSON(b []byte) (err error) {
 	return nil
 }
 
+// The issuer of XRP is omitted
+func (i Issue) MarshalJSON() ([]byte, error) {
+	if i.Currency.IsNative() {
+		return json.Marshal(struct {
+			Currency Currency `json:"currency"`
+		}{i.Currency})
+	}
+	type issue Issue
+	return json.Marshal(issue(i))
+}
+
 func (c Currency) MarshalText() ([]byte, error) {
 	return []byte(c.Machine()), nil
 }
@@ -421,6 +456,15 @@ func (h *Hash160) UnmarshalText(b []byte) error {
 	return err
 }
 
+func (h Hash192) MarshalText() ([]byte, error) {
+	return b2h(h[:]), nil
+}
+
+func (h *Hash192) UnmarshalText(b []byte) error {
+	_, err := hex.Decode(h[:], b)
+	return err
+}
+
 func (h Hash256) MarshalText() ([]byte, error) {
 	return b2h(h[:]), nil
 }
@@ -438,15 +482,19 @@ func (a Account) MarshalText() ([]byte, error) {
 	return address.MarshalText()
 }
 
-// Expects base58-encoded account id
+// Expects base58-encoded account id or an X-address without a tag. Tagged
+// X-addresses must first be expanded with ExpandXAddresses.
 func (a *Account) UnmarshalText(b []byte) error {
 	if len(b) == 0 {
 		return nil
 	}
-	account, err := NewAccountFromAddress(string(b))
+	account, tag, err := ParseAddress(string(b))
 	if err != nil {
 		return err
 	}
+	if tag != nil {
+		return fmt.Errorf("X-address %s has a tag which has no field to go in", string(b))
+	}
 	copy(a[:], account[:])
 	return nil
 }
@@ -507,6 +555,9 @@ func (p PublicKey) MarshalText() ([]byte, error) {
 
 // Expects public key hex
 func (p *PublicKey) UnmarshalText(b []byte) error {
+	if len(b) > 0 && hex.DecodedLen(len(b)) != len(p) {
+		return fmt.Errorf("Bad public key: %s", b)
+	}
 	_, err := hex.Decode(p[:], b)
 	return err
 }
@@ -523,6 +574,49 @@ func (h *Uint64Hex) UnmarshalText(b []byte) error {
 	return err
 }
 
+// A uint64 which gets represented as a decimal string in json
+type Uint64Decimal uint64
+
+func (d Uint64Decimal) MarshalText() ([]byte, error) {
+	return []byte(strconv.FormatUint(uint64(d), 10)), nil
+}
+
+func (d *Uint64Decimal) UnmarshalText(b []byte) error {
+	n, err := strconv.ParseUint(string(b), 10, 64)
+	*d = Uint64Decimal(n)
+	return err
+}
+
 func (keyType KeyType) MarshalText() ([]byte, error) {
 	return []byte(keyType.String()), nil
 }
+
+// Accepts the names of MarshalText and those of rippled's key_type
+func (keyType *KeyType) UnmarshalText(b []byte) error {
+	switch strings.ToLower(string(b)) {
+	case "ecdsa", "secp256k1":
+		*keyType = ECDSA
+	case "ed25519":
+		*keyType = Ed25519
+	default:
+		return fmt.Errorf("Unknown KeyType: %s", string(b))
+	}
+	return nil
+}
+
+// Accepts the numbers of KeyType as well as the names of UnmarshalText
+func (keyType *KeyType) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		var name string
+		if err := json.Unmarshal(b, &name); err != nil {
+			return err
+		}
+		return keyType.UnmarshalText([]byte(name))
+	}
+	var n int
+	if err := json.Unmarshal(b, &n); err != nil || (KeyType(n) != ECDSA && KeyType(n) != Ed25519) {
+		return fmt.Errorf("Unknown KeyType: %s", string(b))
+	}
+	*keyType = KeyType(n)
+	return nil
+}
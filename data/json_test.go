@@ -1,7 +1,9 @@
 package data
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"path/filepath"
 
@@ -37,3 +39,116 @@ func (s *JSONSuite) TestTransactionsJSON(c *C) {
 		compare(c, f, b, out)
 	}
 }
+
+func (s *JSONSuite) TestTransactionsBinary(c *C) {
+	files, err := filepath.Glob("testdata/transaction_*.json")
+	c.Assert(err, IsNil)
+	for _, f := range files {
+		b, err := ioutil.ReadFile(f)
+		c.Assert(err, IsNil)
+		var txm TransactionWithMetaData
+		c.Assert(json.Unmarshal(b, &txm), IsNil)
+		hash, raw, err := Raw(txm.Transaction)
+		c.Assert(err, IsNil, Commentf(f))
+		c.Check(hash.String(), Equals, txm.GetHash().String(), Commentf(f))
+		tx, err := ReadTransaction(bytes.NewReader(raw))
+		c.Assert(err, IsNil, Commentf(f))
+		_, reencoded, err := Raw(tx)
+		c.Assert(err, IsNil, Commentf(f))
+		c.Check(string(b2h(reencoded)), Equals, string(b2h(raw)), Commentf(f))
+	}
+}
+
+const xAddressPayment = `{"TransactionType":"Payment","Account":"XVLhHMPHU98es4dbozjVtdWzVrDjtV5fdx1mHp98tDMoQXb",` +
+	`"Destination":"XVLhHMPHU98es4dbozjVtdWzVrDjtV8xvjGQTYPiAx6gwDC","Amount":"100","Fee":"10","Sequence":1%s}`
+
+func (s *JSONSuite) TestXAddresses(c *C) {
+	account, tag, err := ParseAddress("XVLhHMPHU98es4dbozjVtdWzVrDjtV8xvjGQTYPiAx6gwDC")
+	c.Assert(err, IsNil)
+	c.Check(account.String(), Equals, "rGWrZyQqhTp9Xu7G5Pkayo7bXjH4k4QYpf")
+	c.Check(*tag, Equals, uint32(1))
+	c.Check(account.XAddress(tag, false), Equals, "XVLhHMPHU98es4dbozjVtdWzVrDjtV8xvjGQTYPiAx6gwDC")
+	_, tag, err = ParseAddress("rGWrZyQqhTp9Xu7G5Pkayo7bXjH4k4QYpf")
+	c.Assert(err, IsNil)
+	c.Check(tag, IsNil)
+
+	// An untagged X-address is as good as a classic one
+	var a Account
+	c.Assert(a.UnmarshalText([]byte("TVE26TYGhfLC7tQDno7G8dGtxSkYQn49b3qD26PK7FcGSKE")), IsNil)
+	c.Check(a, Equals, *account)
+	c.Check(a.UnmarshalText([]byte("XVLhHMPHU98es4dbozjVtdWzVrDjtV8xvjGQTYPiAx6gwDC")), ErrorMatches, "X-address .* has a tag which has no field to go in")
+
+	for _, test := range []struct {
+		extra string
+		err   string
+	}{
+		{"", ""},
+		{`,"DestinationTag":1`, ""},
+		{`,"DestinationTag":2`, "Destination .* has tag 1 which conflicts with DestinationTag 2"},
+	} {
+		var txm TransactionWithMetaData
+		err := json.Unmarshal([]byte(fmt.Sprintf(xAddressPayment, test.extra)), &txm)
+		if test.err != "" {
+			c.Check(err, ErrorMatches, test.err)
+			continue
+		}
+		c.Assert(err, IsNil)
+		payment := txm.Transaction.(*Payment)
+		c.Check(payment.Account, Equals, *account)
+		c.Check(payment.SourceTag, IsNil)
+		c.Check(payment.Destination, Equals, *account)
+		c.Check(*payment.DestinationTag, Equals, uint32(1))
+	}
+
+	// Field names are matched as encoding/json matches them
+	b, err := ExpandXAddresses([]byte(`{"destination":"XVLhHMPHU98es4dbozjVtdWzVrDjtV8xvjGQTYPiAx6gwDC","destinationtag":1}`))
+	c.Assert(err, IsNil)
+	c.Check(string(b), Equals, `{"destination":"rGWrZyQqhTp9Xu7G5Pkayo7bXjH4k4QYpf","destinationtag":1}`)
+}
+
+func (s *JSONSuite) TestSecrets(c *C) {
+	seed, err := NewSeedFromAddress("snoPBrXtMeMyMHUVTgbuqAfg1SUTb")
+	c.Assert(err, IsNil)
+	for _, test := range []struct {
+		secret, account string
+		keyType         KeyType
+	}{
+		{"snoPBrXtMeMyMHUVTgbuqAfg1SUTb", "rHb9CJAWyB4rj91VRWn96DkukG4bwdtyTh", ECDSA},
+		{seed.Encode(Ed25519), "rGWrZyQqhTp9Xu7G5Pkayo7bXjH4k4QYpf", Ed25519},
+		{seed.SecretNumbers(), "rHb9CJAWyB4rj91VRWn96DkukG4bwdtyTh", ECDSA},
+	} {
+		var secret Secret
+		c.Assert(json.Unmarshal([]byte(`"`+test.secret+`"`), &secret), IsNil)
+		c.Check(*secret.Seed, Equals, *seed)
+		c.Check(secret.KeyType, Equals, test.keyType)
+		c.Check(secret.AccountId().String(), Equals, test.account)
+		c.Check(secret.String(), Equals, seed.Encode(test.keyType))
+	}
+	c.Check(seed.Encode(Ed25519)[:3], Equals, "sEd")
+
+	mnemonic, err := NewSecret("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about")
+	c.Assert(err, IsNil)
+	c.Check(mnemonic.Seed, IsNil)
+	c.Check(mnemonic.Sequence(), IsNil)
+	tx := &AccountSet{TxBase: TxBase{TransactionType: ACCOUNT_SET, Account: mnemonic.AccountId()}}
+	c.Assert(Sign(tx, mnemonic.Key(), mnemonic.Sequence()), IsNil)
+	ok, err := CheckSignature(tx)
+	c.Assert(err, IsNil)
+	c.Check(ok, Equals, true)
+
+	_, err = NewSecret("abandon abandon")
+	c.Check(err, ErrorMatches, "Bad mnemonic length: 2 words")
+	var keyType KeyType
+	c.Check(json.Unmarshal([]byte(`"secp256k1"`), &keyType), IsNil)
+	c.Check(json.Unmarshal([]byte(`"ed25519"`), &keyType), IsNil)
+	c.Check(keyType, Equals, Ed25519)
+	c.Check(json.Unmarshal([]byte(`0`), &keyType), IsNil)
+	c.Check(keyType, Equals, ECDSA)
+	var key struct {
+		KeyType KeyType `json:"keytype"`
+	}
+	c.Check(json.Unmarshal([]byte(`{"keytype":1}`), &key), IsNil)
+	c.Check(key.KeyType, Equals, Ed25519)
+	c.Check(json.Unmarshal([]byte(`2`), &keyType), ErrorMatches, "Unknown KeyType: 2")
+	c.Check(json.Unmarshal([]byte(`"rsa"`), &keyType), ErrorMatches, "Unknown KeyType: rsa")
+}
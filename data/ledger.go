@@ -1,5 +1,7 @@
 package data
 
+import "fmt"
+
 type LedgerHeader struct {
 	LedgerSequence  uint32      `json:"ledger_index,string"`
 	TotalXRP        uint64      `json:"total_coins,string"`
@@ -35,3 +37,22 @@ func (l Ledger) NodeType() NodeType { return NT_LEDGER }
 func (l Ledger) Ledger() uint32     { return l.LedgerSequence }
 func (l Ledger) NodeId() *Hash256   { return &l.Hash }
 func (l Ledger) GetHash() *Hash256  { return &l.Hash }
+
+// CalculateHash returns the hash of the header, which identifies the ledger.
+func (h *LedgerHeader) CalculateHash() (Hash256, error) {
+	return NodeId(&Ledger{LedgerHeader: *h})
+}
+
+// VerifyHash checks that the hash of the ledger is that of its header.
+// It says nothing of the state and transactions, which are verified
+// against the header by the shamap package.
+func (l *Ledger) VerifyHash() error {
+	hash, err := l.CalculateHash()
+	if err != nil {
+		return err
+	}
+	if hash != l.Hash {
+		return fmt.Errorf("Ledger %d hash: %s expected: %s", l.LedgerSequence, hash, l.Hash)
+	}
+	return nil
+}
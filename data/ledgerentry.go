@@ -78,6 +78,22 @@ type Directory struct {
 	NFTokenID         *Hash256         `json:",omitempty"`
 }
 
+// page guesses the number of a directory page, nil for the root. Pages are
+// usually numbered in order, with the root linking back to the last page.
+func (d *Directory) page() *NodeIndex {
+	switch {
+	case d.IndexNext != nil && *d.IndexNext == 1:
+		return nil
+	case d.IndexPrevious != nil:
+		return d.IndexPrevious.Next()
+	case d.IndexNext != nil:
+		first := NodeIndex(1)
+		return &first
+	default:
+		return nil
+	}
+}
+
 type LedgerHashes struct {
 	leBase
 	Flags               *LedgerEntryFlag `json:",omitempty"`
@@ -129,6 +145,7 @@ type Escrow struct {
 	Account         Account          `json:",omitempty"`
 	Destination     Account          `json:",omitempty"`
 	Amount          Amount           `json:",omitempty"`
+	Sequence        *uint32          `json:",omitempty"`
 	Condition       *VariableLength  `json:",omitempty"`
 	CancelAfter     *uint32          `json:",omitempty"`
 	FinishAfter     *uint32          `json:",omitempty"`
@@ -185,6 +202,7 @@ type PayChannel struct {
 	Destination     *Account         `json:",omitempty"`
 	Amount          *Amount          `json:",omitempty"`
 	Balance         *Amount          `json:",omitempty"`
+	Sequence        *uint32          `json:",omitempty"`
 	PublicKey       *PublicKey       `json:",omitempty"`
 	SettleDelay     *uint32          `json:",omitempty"`
 	Expiration      *uint32          `json:",omitempty"`
@@ -232,6 +250,7 @@ type NFTokenOffer struct {
 	Owner            *Account         `json:",omitempty"`
 	NFTokenID        *Hash256         `json:",omitempty"`
 	Amount           *Amount          `json:",omitempty"`
+	Sequence         *uint32          `json:",omitempty"`
 	OwnerNode        *NodeIndex       `json:",omitempty"`
 	NFTokenOfferNode *NodeIndex       `json:",omitempty"`
 	Destination      *Account         `json:",omitempty"`
@@ -279,45 +298,126 @@ type AMM struct {
 
 type Bridge struct {
 	leBase
-	Flags   *LedgerEntryFlag `json:",omitempty"`
-	Account *Account         `json:",omitempty"`
+	Flags                    *LedgerEntryFlag `json:",omitempty"`
+	Account                  *Account         `json:",omitempty"`
+	SignatureReward          *Amount          `json:",omitempty"`
+	MinAccountCreateAmount   *Amount          `json:",omitempty"`
+	XChainBridge             *XChainBridge    `json:",omitempty"`
+	XChainClaimID            *Uint64Hex       `json:",omitempty"`
+	XChainAccountCreateCount *Uint64Hex       `json:",omitempty"`
+	XChainAccountClaimCount  *Uint64Hex       `json:",omitempty"`
+	OwnerNode                *NodeIndex       `json:",omitempty"`
 }
 
 func (b *Bridge) Affects(account Account) bool {
-	return b.Account.Equals(account)
+	return b.Account != nil && b.Account.Equals(account)
 }
 
 type Did struct {
 	leBase
-	Account *Account `json:",omitempty"`
+	Flags       *LedgerEntryFlag `json:",omitempty"`
+	Account     *Account         `json:",omitempty"`
+	DIDDocument *VariableLength  `json:",omitempty"`
+	Data        *VariableLength  `json:",omitempty"`
+	URI         *VariableLength  `json:",omitempty"`
+	OwnerNode   *NodeIndex       `json:",omitempty"`
 }
 
 func (d *Did) Affects(account Account) bool {
-	return d.Account.Equals(account)
+	return d.Account != nil && d.Account.Equals(account)
+}
+
+type PriceDataItem struct {
+	BaseAsset  *Currency  `json:",omitempty"`
+	QuoteAsset *Currency  `json:",omitempty"`
+	AssetPrice *Uint64Hex `json:",omitempty"`
+	Scale      *uint8     `json:",omitempty"`
+}
+
+type PriceData struct {
+	PriceData PriceDataItem
 }
 
 type Oracle struct {
 	leBase
+	Flags            *LedgerEntryFlag `json:",omitempty"`
+	Owner            *Account         `json:",omitempty"`
+	OracleDocumentID *uint32          `json:",omitempty"`
+	Provider         *VariableLength  `json:",omitempty"`
+	PriceDataSeries  []PriceData      `json:",omitempty"`
+	LastUpdateTime   *uint32          `json:",omitempty"`
+	URI              *VariableLength  `json:",omitempty"`
+	AssetClass       *VariableLength  `json:",omitempty"`
+	OwnerNode        *NodeIndex       `json:",omitempty"`
 }
 
-func (o *Oracle) Affects(account Account) bool { return false }
+func (o *Oracle) Affects(account Account) bool {
+	return o.Owner != nil && o.Owner.Equals(account)
+}
 
 type XChainOwnedClaimID struct {
 	leBase
-	Account *Account `json:",omitempty"`
+	Flags                   *LedgerEntryFlag      `json:",omitempty"`
+	Account                 *Account              `json:",omitempty"`
+	XChainBridge            *XChainBridge         `json:",omitempty"`
+	XChainClaimID           *Uint64Hex            `json:",omitempty"`
+	OtherChainSource        *Account              `json:",omitempty"`
+	XChainClaimAttestations []XChainClaimProofSig `json:",omitempty"`
+	SignatureReward         *Amount               `json:",omitempty"`
+	OwnerNode               *NodeIndex            `json:",omitempty"`
 }
 
 func (x *XChainOwnedClaimID) Affects(account Account) bool {
-	return x.Account.Equals(account)
+	return x.Account != nil && x.Account.Equals(account)
 }
 
 type XChainOwnedCreateAccountClaimID struct {
 	leBase
-	Account *Account `json:",omitempty"`
+	Flags                           *LedgerEntryFlag              `json:",omitempty"`
+	Account                         *Account                      `json:",omitempty"`
+	XChainBridge                    *XChainBridge                 `json:",omitempty"`
+	XChainAccountCreateCount        *Uint64Hex                    `json:",omitempty"`
+	XChainCreateAccountAttestations []XChainCreateAccountProofSig `json:",omitempty"`
+	OwnerNode                       *NodeIndex                    `json:",omitempty"`
 }
 
 func (x *XChainOwnedCreateAccountClaimID) Affects(account Account) bool {
-	return x.Account.Equals(account)
+	return x.Account != nil && x.Account.Equals(account)
+}
+
+type MPTokenIssuance struct {
+	leBase
+	Flags             *LedgerEntryFlag `json:",omitempty"`
+	Issuer            *Account         `json:",omitempty"`
+	Sequence          *uint32          `json:",omitempty"`
+	AssetScale        *uint8           `json:",omitempty"`
+	MaximumAmount     *Uint64Decimal   `json:",omitempty"`
+	OutstandingAmount *Uint64Decimal   `json:",omitempty"`
+	TransferFee       *uint16          `json:",omitempty"`
+	MPTokenMetadata   *VariableLength  `json:",omitempty"`
+	OwnerNode         *NodeIndex       `json:",omitempty"`
+}
+
+// MPTIssuanceID returns the id by which the issuance is known.
+func (m *MPTokenIssuance) MPTIssuanceID() Hash192 {
+	return NewMPTIssuanceID(*m.Sequence, *m.Issuer)
+}
+
+func (m *MPTokenIssuance) Affects(account Account) bool {
+	return m.Issuer != nil && m.Issuer.Equals(account)
+}
+
+type MPToken struct {
+	leBase
+	Flags             *LedgerEntryFlag `json:",omitempty"`
+	Account           *Account         `json:",omitempty"`
+	MPTokenIssuanceID *Hash192         `json:",omitempty"`
+	MPTAmount         *Uint64Decimal   `json:",omitempty"`
+	OwnerNode         *NodeIndex       `json:",omitempty"`
+}
+
+func (m *MPToken) Affects(account Account) bool {
+	return m.Account != nil && m.Account.Equals(account)
 }
 
 func (a *AccountRoot) Affects(account Account) bool {
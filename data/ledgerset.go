@@ -82,6 +82,12 @@ func (l *LedgerSet) Set(i uint32) time.Duration {
 	return time.Duration(0)
 }
 
+// Return gives back a taken ledger which was not acquired, so that it
+// can be taken again straight away.
+func (l *LedgerSet) Return(i uint32) {
+	delete(l.taken, i)
+}
+
 func (l *LedgerSet) take(i uint32) bool {
 	if !l.ledgers.Test(uint(i)) {
 		return false
@@ -25,6 +25,13 @@ func (s *LedgerSetSuite) TestLedgerSet(c *C) {
 	c.Assert(len(tooLargeTop), Equals, 0)
 }
 
+func (s *LedgerSetSuite) TestLedgerSetReturn(c *C) {
+	l := NewLedgerSet(100, 110)
+	c.Assert(l.TakeTop(2), DeepEquals, LedgerSlice{108, 109})
+	l.Return(109)
+	c.Assert(l.TakeTop(2), DeepEquals, LedgerSlice{107, 109})
+}
+
 func (s *LedgerSetSuite) TestLedgerSetMiddle(c *C) {
 	l := NewLedgerSet(32570, 32670)
 	r := &LedgerRange{
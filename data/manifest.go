@@ -0,0 +1,141 @@
+package data
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+
+	"github.com/parihaaraka/ripple/crypto"
+)
+
+// RevokedSequence is the Sequence of a manifest which revokes its master
+// key, after which nothing signed by the validator is trusted.
+const RevokedSequence = 0xFFFFFFFF
+
+// Manifest binds the ephemeral key with which a validator signs to its
+// long lived master key. A manifest with a higher Sequence replaces the
+// previous one, so that the ephemeral key can be changed without the
+// master key ever being online.
+type Manifest struct {
+	Hash            Hash256
+	Sequence        uint32
+	Version         *uint16
+	PublicKey       PublicKey
+	SigningPubKey   *PublicKey
+	Signature       *VariableLength
+	Domain          *VariableLength
+	MasterSignature VariableLength
+}
+
+func (m Manifest) GetType() string           { return "Manifest" }
+func (m Manifest) Prefix() HashPrefix        { return HP_MANIFEST }
+func (m Manifest) SigningPrefix() HashPrefix { return HP_MANIFEST }
+func (m Manifest) GetHash() *Hash256         { return &m.Hash }
+
+// Revoked is true when the manifest revokes its master key.
+func (m *Manifest) Revoked() bool {
+	return m.Sequence == RevokedSequence
+}
+
+func ReadManifest(r Reader) (*Manifest, error) {
+	manifest := new(Manifest)
+	v := reflect.ValueOf(manifest)
+	if err := readObject(r, &v); err != nil {
+		return nil, err
+	}
+	var err error
+	if manifest.Hash, err = NodeId(manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// NewManifestFromBase64 decodes a manifest as published in validator
+// lists and returned by the manifest command.
+func NewManifestFromBase64(s string) (*Manifest, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return ReadManifest(bytes.NewReader(b))
+}
+
+// Base64 encodes the manifest as published in validator lists.
+func (m *Manifest) Base64() (string, error) {
+	_, b, err := Raw(m)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// signingHash returns the hash and the message which both keys sign.
+func (m *Manifest) signingHash() (Hash256, []byte, error) {
+	hash, msg, err := raw(m, HP_MANIFEST, nil, true)
+	return hash, append(HP_MANIFEST.Bytes(), msg...), err
+}
+
+// SignManifest sets the signatures of m. The ephemeral key, which is
+// not needed for a revocation, is used as is, without a sequence.
+func SignManifest(m *Manifest, master, ephemeral crypto.Key) error {
+	copy(m.PublicKey[:], master.Public(nil))
+	if ephemeral != nil {
+		var pub PublicKey
+		copy(pub[:], ephemeral.Public(nil))
+		m.SigningPubKey = &pub
+	}
+	hash, msg, err := m.signingHash()
+	if err != nil {
+		return err
+	}
+	if ephemeral != nil {
+		sig, err := crypto.Sign(ephemeral.Private(nil), hash.Bytes(), msg)
+		if err != nil {
+			return err
+		}
+		signature := VariableLength(sig)
+		m.Signature = &signature
+	}
+	if m.MasterSignature, err = crypto.Sign(master.Private(nil), hash.Bytes(), msg); err != nil {
+		return err
+	}
+	m.Hash, err = NodeId(m)
+	return err
+}
+
+// Verify checks the master signature, and the ephemeral signature unless
+// the manifest is a revocation.
+func (m *Manifest) Verify() error {
+	hash, msg, err := m.signingHash()
+	if err != nil {
+		return err
+	}
+	if ok, err := crypto.Verify(m.PublicKey.Bytes(), hash.Bytes(), msg, m.MasterSignature.Bytes()); err != nil || !ok {
+		return fmt.Errorf("Bad master signature for %s", m.PublicKey.NodePublicKey())
+	}
+	if m.Revoked() {
+		return nil
+	}
+	switch {
+	case m.SigningPubKey == nil || m.Signature == nil:
+		return fmt.Errorf("No ephemeral key for %s", m.PublicKey.NodePublicKey())
+	case *m.SigningPubKey == m.PublicKey:
+		return fmt.Errorf("Ephemeral key is the master key for %s", m.PublicKey.NodePublicKey())
+	}
+	if ok, err := crypto.Verify(m.SigningPubKey.Bytes(), hash.Bytes(), msg, m.Signature.Bytes()); err != nil || !ok {
+		return fmt.Errorf("Bad ephemeral signature for %s", m.PublicKey.NodePublicKey())
+	}
+	return nil
+}
+
+func (m Manifest) String() string {
+	if m.Revoked() {
+		return fmt.Sprintf("Manifest: %s revoked", m.PublicKey.NodePublicKey())
+	}
+	var signing string
+	if m.SigningPubKey != nil {
+		signing = m.SigningPubKey.NodePublicKey()
+	}
+	return fmt.Sprintf("Manifest: %s %d %s", m.PublicKey.NodePublicKey(), m.Sequence, signing)
+}
@@ -0,0 +1,67 @@
+package data
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+)
+
+// NewMPTIssuanceID returns the id of the Multi-Purpose Token issuance
+// created by the issuer's MPTokenIssuanceCreate with sequence.
+func NewMPTIssuanceID(sequence uint32, issuer Account) Hash192 {
+	var id Hash192
+	binary.BigEndian.PutUint32(id[:4], sequence)
+	copy(id[4:], issuer[:])
+	return id
+}
+
+// MPTSequence returns the sequence of the MPTokenIssuanceCreate
+// which created the issuance.
+func (h Hash192) MPTSequence() uint32 {
+	return binary.BigEndian.Uint32(h[:4])
+}
+
+// MPTIssuer returns the issuer of the Multi-Purpose Token.
+func (h Hash192) MPTIssuer() Account {
+	var issuer Account
+	copy(issuer[:], h[4:])
+	return issuer
+}
+
+// NewMPTAmount returns an amount of n units of the Multi-Purpose Token.
+func NewMPTAmount(n int64, id Hash192) *Amount {
+	return &Amount{Value: newMPTValue(n), MPTIssuanceID: &id}
+}
+
+// newMPTValue holds MPT units in a native Value, which like drops are
+// whole numbers, but without the native limit.
+func newMPTValue(n int64) *Value {
+	if n < 0 {
+		return newValue(true, true, uint64(-n), 0)
+	}
+	return newValue(true, false, uint64(n), 0)
+}
+
+// mptString formats the whole number of units in an MPT value.
+func mptString(v *Value) string {
+	if v == nil {
+		return ""
+	}
+	return v.Rat().FloatString(0)
+}
+
+// newMPTAmount parses a value and hex id, as found in "100/<id>".
+func newMPTAmount(value, id string) (*Amount, error) {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("MPT amounts are whole numbers: %s", value)
+	}
+	var mpt Hash192
+	if len(id) != len(mpt)*2 {
+		return nil, fmt.Errorf("Bad MPT issuance id: %s", id)
+	}
+	if err := mpt.UnmarshalText([]byte(id)); err != nil {
+		return nil, err
+	}
+	return NewMPTAmount(n, mpt), nil
+}
@@ -448,6 +448,30 @@ func (r TransactionResult) Queued() bool {
 	return r == terQUEUED
 }
 
+// Retry reports whether the transaction was neither applied nor rejected
+// outright, so may succeed if submitted again. That is, it is either a
+// tel (local) or ter (retry) result.
+func (r TransactionResult) Retry() bool {
+	return (r >= telLOCAL_ERROR && r < temMALFORMED) || (r >= terRETRY && r < tesSUCCESS)
+}
+
+// Malformed reports whether the transaction can never be applied, being a tem result.
+func (r TransactionResult) Malformed() bool {
+	return r >= temMALFORMED && r < tefFAILURE
+}
+
+// Failed reports whether the transaction failed without claiming a fee,
+// being a tef result. It could still be applied if submitted earlier.
+func (r TransactionResult) Failed() bool {
+	return r >= tefFAILURE && r < terRETRY
+}
+
+// Past reports whether a resubmission failed because the transaction,
+// or another with the same sequence, has already been applied.
+func (r TransactionResult) Past() bool {
+	return r == tefPAST_SEQ || r == tefALREADY
+}
+
 func (r TransactionResult) Symbol() string {
 	switch r {
 	case tesSUCCESS, tecCLAIM:
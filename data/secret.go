@@ -0,0 +1,123 @@
+package data
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/parihaaraka/ripple/crypto"
+)
+
+// NewSeed decodes a seed in either form, reporting Ed25519 for an "sEd"
+// seed and ECDSA otherwise.
+func NewSeed(s string) (*Seed, KeyType, error) {
+	b, ed25519, err := crypto.DecodeSeed(s)
+	if err != nil {
+		return nil, ECDSA, err
+	}
+	var seed Seed
+	copy(seed[:], b)
+	if ed25519 {
+		return &seed, Ed25519, nil
+	}
+	return &seed, ECDSA, nil
+}
+
+// Encode returns the seed in the form for keyType, which starts with "sEd"
+// for Ed25519.
+func (s Seed) Encode(keyType KeyType) string {
+	encoded, err := crypto.EncodeSeed(s[:], keyType == Ed25519)
+	if err != nil {
+		return fmt.Sprintf("Bad Address: %s", b2h(s[:]))
+	}
+	return encoded
+}
+
+// SecretNumbers returns the seed as 8 groups of 6 digits.
+func (s Seed) SecretNumbers() string {
+	numbers, _ := crypto.EncodeSecretNumbers(s[:])
+	return numbers
+}
+
+// Secret is what the key of an account is derived from: a family seed,
+// secret numbers or a BIP39 mnemonic.
+type Secret struct {
+	Seed    *Seed // Nil for a mnemonic
+	KeyType KeyType
+	key     crypto.Key
+}
+
+// NewSecretFromSeed returns the secret of a seed used for keyType.
+func NewSecretFromSeed(seed Seed, keyType KeyType) *Secret {
+	return &Secret{Seed: &seed, KeyType: keyType, key: seed.Key(keyType)}
+}
+
+// NewSecret accepts a family seed, whose key type is Ed25519 when it
+// starts with "sEd", secret numbers, which are ECDSA family seeds, or an
+// English BIP39 mnemonic without a passphrase.
+func NewSecret(s string) (*Secret, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case crypto.IsSecretNumbers(s):
+		b, err := crypto.DecodeSecretNumbers(s)
+		if err != nil {
+			return nil, err
+		}
+		var seed Seed
+		copy(seed[:], b)
+		return NewSecretFromSeed(seed, ECDSA), nil
+	case strings.Contains(s, " "):
+		return NewSecretFromMnemonic(s, "")
+	default:
+		seed, keyType, err := NewSeed(s)
+		if err != nil {
+			return nil, err
+		}
+		return NewSecretFromSeed(*seed, keyType), nil
+	}
+}
+
+// NewSecretFromMnemonic returns the secret of a BIP39 mnemonic and
+// passphrase, at the XRPL derivation path.
+func NewSecretFromMnemonic(mnemonic, passphrase string) (*Secret, error) {
+	key, err := crypto.NewMnemonicKey(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return &Secret{KeyType: ECDSA, key: key}, nil
+}
+
+func (s *Secret) Key() crypto.Key {
+	return s.key
+}
+
+// Sequence returns the sequence of the account key to pass with Key when
+// signing, which is nil unless the key is the root of an ECDSA family.
+func (s *Secret) Sequence() *uint32 {
+	if s.Seed == nil || s.KeyType == Ed25519 {
+		return nil
+	}
+	var sequence uint32
+	return &sequence
+}
+
+func (s *Secret) AccountId() Account {
+	var account Account
+	copy(account[:], s.key.Id(s.Sequence()))
+	return account
+}
+
+func (s *Secret) String() string {
+	if s.Seed == nil {
+		return fmt.Sprintf("Mnemonic for %s", s.AccountId())
+	}
+	return s.Seed.Encode(s.KeyType)
+}
+
+func (s *Secret) UnmarshalText(b []byte) error {
+	secret, err := NewSecret(string(b))
+	if err != nil {
+		return err
+	}
+	*s = *secret
+	return nil
+}
@@ -31,6 +31,8 @@ func CheckSignature(s Signable) (bool, error) {
 	if err != nil {
 		return false, err
 	}
+	// Ed25519 signs the prefixed message rather than its hash
+	msg = append(s.SigningPrefix().Bytes(), msg...)
 	return crypto.Verify(s.GetPublicKey().Bytes(), hash.Bytes(), msg, s.GetSignature().Bytes())
 }
 
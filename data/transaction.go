@@ -2,6 +2,7 @@ package data
 
 type TxBase struct {
 	TransactionType    TransactionType
+	NetworkID          *uint32          `json:",omitempty"`
 	Flags              *TransactionFlag `json:",omitempty"`
 	SourceTag          *uint32          `json:",omitempty"`
 	Account            Account
@@ -141,6 +142,9 @@ type CredentialCreate struct {
 
 type DidSet struct {
 	TxBase
+	DIDDocument *VariableLength `json:",omitempty"`
+	Data        *VariableLength `json:",omitempty"`
+	URI         *VariableLength `json:",omitempty"`
 }
 
 type DidDelete struct {
@@ -149,59 +153,121 @@ type DidDelete struct {
 
 type OracleSet struct {
 	TxBase
+	OracleDocumentID uint32
+	Provider         *VariableLength `json:",omitempty"`
+	URI              *VariableLength `json:",omitempty"`
+	AssetClass       *VariableLength `json:",omitempty"`
+	LastUpdateTime   uint32
+	PriceDataSeries  []PriceData
 }
 
 type OracleDelete struct {
 	TxBase
+	OracleDocumentID uint32
 }
 
 type XChainAccountCreateCommit struct {
 	TxBase
+	XChainBridge    XChainBridge
+	Destination     Account
+	Amount          Amount
+	SignatureReward Amount
 }
 
 type XChainAddAccountCreateAttestation struct {
 	TxBase
+	XChainBridge             XChainBridge
+	PublicKey                PublicKey
+	Signature                VariableLength
+	OtherChainSource         Account
+	Amount                   Amount
+	AttestationRewardAccount Account
+	AttestationSignerAccount Account
+	WasLockingChainSend      uint8
+	XChainAccountCreateCount Uint64Hex
+	Destination              Account
+	SignatureReward          Amount
 }
 
 type XChainAddClaimAttestation struct {
 	TxBase
+	XChainBridge             XChainBridge
+	PublicKey                PublicKey
+	Signature                VariableLength
+	OtherChainSource         Account
+	Amount                   Amount
+	AttestationRewardAccount Account
+	AttestationSignerAccount Account
+	WasLockingChainSend      uint8
+	XChainClaimID            Uint64Hex
+	Destination              *Account `json:",omitempty"`
 }
 
 type XChainClaim struct {
 	TxBase
+	XChainBridge   XChainBridge
+	XChainClaimID  Uint64Hex
+	Destination    Account
+	DestinationTag *uint32 `json:",omitempty"`
+	Amount         Amount
 }
 
 type XChainCommit struct {
 	TxBase
+	XChainBridge          XChainBridge
+	XChainClaimID         Uint64Hex
+	Amount                Amount
+	OtherChainDestination *Account `json:",omitempty"`
 }
 
 type XChainCreateBridge struct {
 	TxBase
+	XChainBridge           XChainBridge
+	SignatureReward        Amount
+	MinAccountCreateAmount *Amount `json:",omitempty"`
 }
 
 type XChainCreateClaimID struct {
 	TxBase
+	XChainBridge     XChainBridge
+	SignatureReward  Amount
+	OtherChainSource Account
 }
 
 type XChainModifyBridge struct {
 	TxBase
+	XChainBridge           XChainBridge
+	SignatureReward        *Amount `json:",omitempty"`
+	MinAccountCreateAmount *Amount `json:",omitempty"`
 }
 
 type MPTokenIssuanceCreate struct {
 	TxBase
 	AssetScale      *uint8          `json:",omitempty"`
-	MaximumAmount   *Amount         `json:",omitempty"`
+	MaximumAmount   *Uint64Decimal  `json:",omitempty"`
 	TransferFee     *uint16         `json:",omitempty"`
 	MPTokenMetadata *VariableLength `json:",omitempty"`
+	TicketSequence  *uint32         `json:",omitempty"`
+}
+
+type MPTokenIssuanceDestroy struct {
+	TxBase
+	MPTokenIssuanceID Hash192
+	TicketSequence    *uint32 `json:",omitempty"`
 }
 
-type MPTokenIssuance struct {
+type MPTokenIssuanceSet struct {
 	TxBase
+	MPTokenIssuanceID Hash192
+	Holder            *Account `json:",omitempty"`
+	TicketSequence    *uint32  `json:",omitempty"`
 }
 
-type MPTokenIssuanceDestroy struct {
+type MPTokenAuthorize struct {
 	TxBase
-	MPTokenIssuanceID *VariableLength `json:",omitempty"`
+	MPTokenIssuanceID Hash192
+	Holder            *Account `json:",omitempty"`
+	TicketSequence    *uint32  `json:",omitempty"`
 }
 
 type TrustSet struct {
@@ -392,6 +458,7 @@ type NFTAcceptOffer struct {
 type Clawback struct {
 	TxBase
 	Amount Amount
+	Holder *Account `json:",omitempty"` // Only for MPT amounts
 }
 
 func (t *TxBase) GetBase() *TxBase                    { return t }
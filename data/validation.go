@@ -1,26 +1,46 @@
 package data
 
+// Validation flags
+const (
+	// A full validation, rather than one only showing that the
+	// validator is keeping up
+	VF_FULL_VALIDATION uint32 = 0x00000001
+)
+
 type Validation struct {
-	Hash             Hash256
-	Flags            uint32
-	LedgerHash       Hash256
-	LedgerSequence   uint32
-	Amendments       Vector256
-	SigningTime      RippleTime
-	SigningPubKey    PublicKey
-	Signature        VariableLength
-	CloseTime        *uint32
-	LoadFee          *uint32
-	BaseFee          *uint64
-	ReserveBase      *uint32
-	ReserveIncrement *uint32
+	Hash                  Hash256
+	Flags                 uint32
+	LedgerHash            Hash256
+	LedgerSequence        uint32
+	Amendments            Vector256
+	SigningTime           RippleTime
+	SigningPubKey         PublicKey
+	Signature             VariableLength
+	NetworkID             *uint32
+	CloseTime             *uint32
+	LoadFee               *uint32
+	BaseFee               *uint64
+	ReserveBase           *uint32
+	ReserveIncrement      *uint32
+	Cookie                *uint64
+	ServerVersion         *uint64
+	ConsensusHash         *Hash256
+	ValidatedHash         *Hash256
+	BaseFeeDrops          *Amount
+	ReserveBaseDrops      *Amount
+	ReserveIncrementDrops *Amount
 }
 
 func (v Validation) GetType() string                 { return "Validation" }
-func (v Validation) GetPublicKey() *PublicKey        { return &v.SigningPubKey }
-func (v Validation) GetSignature() *VariableLength   { return &v.Signature }
+func (v *Validation) GetPublicKey() *PublicKey       { return &v.SigningPubKey }
+func (v *Validation) GetSignature() *VariableLength  { return &v.Signature }
 func (v Validation) Prefix() HashPrefix              { return HP_VALIDATION }
 func (v Validation) SigningPrefix() HashPrefix       { return HP_VALIDATION }
 func (v Validation) SuppressionId() (Hash256, error) { return NodeId(&v) }
-func (v Validation) GetHash() *Hash256               { return &v.Hash }
-func (v Validation) InitialiseForSigning()           {}
+func (v *Validation) GetHash() *Hash256              { return &v.Hash }
+func (v *Validation) InitialiseForSigning()          {}
+
+// Full is true for a full validation, which votes for its ledger.
+func (v *Validation) Full() bool {
+	return v.Flags&VF_FULL_VALIDATION != 0
+}
@@ -14,7 +14,7 @@ func (i *Issue) Marshal(w io.Writer) error {
 	if i.Currency.IsNative() {
 		return nil
 	}
-	return i.Issuer.Marshal(w)
+	return write(w, i.Issuer.Bytes())
 }
 
 func (i *Issue) Unmarshal(r Reader) error {
@@ -27,6 +27,32 @@ func (i *Issue) Unmarshal(r Reader) error {
 	return unmarshalSlice(i.Issuer[:], r, "Issuer")
 }
 
+func (b *XChainBridge) Marshal(w io.Writer) error {
+	if err := b.LockingChainDoor.Marshal(w); err != nil {
+		return err
+	}
+	if err := b.LockingChainIssue.Marshal(w); err != nil {
+		return err
+	}
+	if err := b.IssuingChainDoor.Marshal(w); err != nil {
+		return err
+	}
+	return b.IssuingChainIssue.Marshal(w)
+}
+
+func (b *XChainBridge) Unmarshal(r Reader) error {
+	if err := b.LockingChainDoor.Unmarshal(r); err != nil {
+		return err
+	}
+	if err := b.LockingChainIssue.Unmarshal(r); err != nil {
+		return err
+	}
+	if err := b.IssuingChainDoor.Unmarshal(r); err != nil {
+		return err
+	}
+	return b.IssuingChainIssue.Unmarshal(r)
+}
+
 func (v *Value) Unmarshal(r Reader) error {
 	var u uint64
 	if err := binary.Read(r, binary.BigEndian, &u); err != nil {
@@ -49,6 +75,16 @@ func (v *Value) Marshal(w io.Writer) error {
 }
 
 func (a *Amount) Unmarshal(r Reader) error {
+	flags, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	if flags&0x80 == 0 && flags&0x20 != 0 {
+		return a.unmarshalMPT(r, flags)
+	}
+	if err := r.UnreadByte(); err != nil {
+		return err
+	}
 	a.Value = new(Value)
 	if err := a.Value.Unmarshal(r); err != nil {
 		return err
@@ -65,6 +101,18 @@ func (a *Amount) Unmarshal(r Reader) error {
 	return nil
 }
 
+// An MPT amount has a byte of flags, rather than a value which
+// begins with them
+func (a *Amount) unmarshalMPT(r Reader, flags byte) error {
+	var num uint64
+	if err := binary.Read(r, binary.BigEndian, &num); err != nil {
+		return err
+	}
+	a.Value = newValue(true, flags&0x40 == 0, num, 0)
+	a.MPTIssuanceID = new(Hash192)
+	return a.MPTIssuanceID.Unmarshal(r)
+}
+
 func (a *Amount) Marshal(w io.Writer) error {
 	return binary.Write(w, binary.BigEndian, a.Bytes())
 }
@@ -93,6 +141,14 @@ func (h *Hash160) Marshal(w io.Writer) error {
 	return binary.Write(w, binary.BigEndian, h.Bytes())
 }
 
+func (h *Hash192) Unmarshal(r Reader) error {
+	return unmarshalSlice(h[:], r, "Hash192")
+}
+
+func (h *Hash192) Marshal(w io.Writer) error {
+	return binary.Write(w, binary.BigEndian, h.Bytes())
+}
+
 func (h *Hash256) Unmarshal(r Reader) error {
 	return unmarshalSlice(h[:], r, "Hash256")
 }
@@ -0,0 +1,138 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/parihaaraka/ripple/crypto"
+)
+
+// XAddress is an account with an optional tag, as encoded in a single
+// X-address.
+type XAddress struct {
+	Account Account
+	Tag     *uint32
+	Test    bool // For testnet rather than mainnet
+}
+
+// Expects an X-address
+func NewXAddress(s string) (*XAddress, error) {
+	account, tag, test, err := crypto.DecodeXAddress(s)
+	if err != nil {
+		return nil, err
+	}
+	x := &XAddress{Tag: tag, Test: test}
+	copy(x.Account[:], account)
+	return x, nil
+}
+
+// ParseAddress accepts either a classic address or an X-address, returning
+// the tag of the latter if it has one.
+func ParseAddress(s string) (*Account, *uint32, error) {
+	if !crypto.IsXAddress(s) {
+		account, err := NewAccountFromAddress(s)
+		return account, nil, err
+	}
+	x, err := NewXAddress(s)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &x.Account, x.Tag, nil
+}
+
+// XAddress returns the X-address of a with an optional tag.
+func (a Account) XAddress(tag *uint32, test bool) string {
+	return XAddress{a, tag, test}.String()
+}
+
+func (x XAddress) String() string {
+	s, err := crypto.EncodeXAddress(x.Account[:], x.Tag, x.Test)
+	if err != nil {
+		return fmt.Sprintf("Bad X-address: %s", b2h(x.Account[:]))
+	}
+	return s
+}
+
+func (x XAddress) MarshalText() ([]byte, error) {
+	return []byte(x.String()), nil
+}
+
+func (x *XAddress) UnmarshalText(b []byte) error {
+	decoded, err := NewXAddress(string(b))
+	if err != nil {
+		return err
+	}
+	*x = *decoded
+	return nil
+}
+
+// The tag fields which take the tag of an X-address in an account field
+var xAddressTags = []struct {
+	Field, Tag string
+}{
+	{"Account", "SourceTag"},
+	{"Destination", "DestinationTag"},
+}
+
+var xAddressRegex = regexp.MustCompile(`(?i)"(Account|Destination)"\s*:\s*"[XT]`)
+
+// key returns the key of fields which encoding/json would match to name
+func key(fields map[string]json.RawMessage, name string) string {
+	if _, ok := fields[name]; ok {
+		return name
+	}
+	for k := range fields {
+		if strings.EqualFold(k, name) {
+			return k
+		}
+	}
+	return name
+}
+
+// ExpandXAddresses rewrites the X-addresses in the Account and Destination
+// fields of a transaction in JSON form as classic addresses, moving their
+// tags into SourceTag and DestinationTag. An X-address with a tag which
+// conflicts with an explicit one is an error. Field names are matched without
+// regard to case, as encoding/json does.
+func ExpandXAddresses(b []byte) ([]byte, error) {
+	if !xAddressRegex.Match(b) {
+		return b, nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	for _, t := range xAddressTags {
+		field, tag := key(fields, t.Field), key(fields, t.Tag)
+		var address string
+		if raw, ok := fields[field]; !ok || json.Unmarshal(raw, &address) != nil || !crypto.IsXAddress(address) {
+			continue
+		}
+		x, err := NewXAddress(address)
+		if err != nil {
+			return nil, err
+		}
+		classic, err := x.Account.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		fields[field] = json.RawMessage(strconv.Quote(string(classic)))
+		if x.Tag == nil {
+			continue
+		}
+		if raw, ok := fields[tag]; ok && string(raw) != "null" {
+			var explicit uint32
+			if err := json.Unmarshal(raw, &explicit); err != nil {
+				return nil, err
+			}
+			if explicit != *x.Tag {
+				return nil, fmt.Errorf("%s %s has tag %d which conflicts with %s %d", t.Field, address, *x.Tag, t.Tag, explicit)
+			}
+		}
+		fields[tag] = json.RawMessage(strconv.FormatUint(uint64(*x.Tag), 10))
+	}
+	return json.Marshal(fields)
+}
@@ -0,0 +1,46 @@
+package data
+
+import "fmt"
+
+// XChainBridge identifies a bridge by the door accounts and issues
+// on the locking and issuing chains.
+type XChainBridge struct {
+	LockingChainDoor  Account
+	LockingChainIssue Issue
+	IssuingChainDoor  Account
+	IssuingChainIssue Issue
+}
+
+func (b XChainBridge) String() string {
+	return fmt.Sprintf("%s:%s->%s:%s", b.LockingChainDoor, b.LockingChainIssue, b.IssuingChainDoor, b.IssuingChainIssue)
+}
+
+type XChainClaimProofSigItem struct {
+	AttestationSignerAccount *Account   `json:",omitempty"`
+	PublicKey                *PublicKey `json:",omitempty"`
+	Amount                   *Amount    `json:",omitempty"`
+	AttestationRewardAccount *Account   `json:",omitempty"`
+	WasLockingChainSend      *uint8     `json:",omitempty"`
+	Destination              *Account   `json:",omitempty"`
+}
+
+// XChainClaimProofSig is an attestation held by an XChainOwnedClaimID.
+type XChainClaimProofSig struct {
+	XChainClaimProofSig XChainClaimProofSigItem
+}
+
+type XChainCreateAccountProofSigItem struct {
+	AttestationSignerAccount *Account   `json:",omitempty"`
+	PublicKey                *PublicKey `json:",omitempty"`
+	Amount                   *Amount    `json:",omitempty"`
+	SignatureReward          *Amount    `json:",omitempty"`
+	AttestationRewardAccount *Account   `json:",omitempty"`
+	WasLockingChainSend      *uint8     `json:",omitempty"`
+	Destination              *Account   `json:",omitempty"`
+}
+
+// XChainCreateAccountProofSig is an attestation held by an
+// XChainOwnedCreateAccountClaimID.
+type XChainCreateAccountProofSig struct {
+	XChainCreateAccountProofSig XChainCreateAccountProofSigItem
+}
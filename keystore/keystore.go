@@ -0,0 +1,344 @@
+// Package keystore keeps the secrets of accounts in a file, each encrypted
+// with a key derived from a passphrase, so that config and tools can refer
+// to them by name or address instead of handling raw seeds.
+package keystore
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/parihaaraka/ripple/data"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/term"
+)
+
+// The version of the file format written
+const Version = 1
+
+// The scrypt parameters of new entries
+const (
+	DefaultScryptN = 1 << 17
+	scryptR        = 8
+	scryptP        = 1
+	keySize        = 32
+	saltSize       = 32
+)
+
+// The largest scrypt parameters accepted, so that a tampered file cannot
+// make decryption allocate and compute without limit
+const (
+	maxScryptN = 1 << 20
+	maxScryptR = 8
+	maxScryptP = 16
+)
+
+const (
+	kdfScrypt    = "scrypt"
+	cipherAESGCM = "aes-256-gcm"
+)
+
+// The environment variables ReadPassphrase looks in before asking
+const (
+	PassphraseEnv    = "RIPPLE_KEYSTORE_PASSPHRASE"
+	NewPassphraseEnv = "RIPPLE_KEYSTORE_NEW_PASSPHRASE"
+)
+
+type KDF struct {
+	Name string              `json:"name"`
+	N    int                 `json:"n"`
+	R    int                 `json:"r"`
+	P    int                 `json:"p"`
+	Salt data.VariableLength `json:"salt"`
+}
+
+// Entry is a secret encrypted with a key derived from a passphrase. The
+// account is authenticated along with the secret, so cannot be changed
+// without the entry failing to decrypt.
+type Entry struct {
+	Name       string              `json:"name"`
+	Account    data.Account        `json:"account"`
+	KeyType    data.KeyType        `json:"key_type"`
+	KDF        KDF                 `json:"kdf"`
+	Cipher     string              `json:"cipher"`
+	Nonce      data.VariableLength `json:"nonce"`
+	Ciphertext data.VariableLength `json:"ciphertext"`
+}
+
+func (e *Entry) String() string {
+	return fmt.Sprintf("%-20s %s %s", e.Name, e.Account, e.KeyType)
+}
+
+// Keystore is the contents of a keystore file.
+type Keystore struct {
+	Version int      `json:"version"`
+	Keys    []*Entry `json:"keys"`
+	ScryptN int      `json:"-"` // For new entries, DefaultScryptN if zero
+	path    string
+}
+
+// DefaultPath returns ~/.ripple/keystore.json.
+func DefaultPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "keystore.json"
+	}
+	return filepath.Join(home, ".ripple", "keystore.json")
+}
+
+// Open reads the keystore at path, which is empty if there is no file.
+func Open(path string) (*Keystore, error) {
+	k := &Keystore{Version: Version, path: path}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return k, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, k); err != nil {
+		return nil, fmt.Errorf("Bad keystore %s: %s", path, err)
+	}
+	if k.Version != Version {
+		return nil, fmt.Errorf("Unsupported keystore version %d in %s", k.Version, path)
+	}
+	return k, nil
+}
+
+// Save writes the keystore back to its file, readable only by its owner.
+func (k *Keystore) Save() error {
+	b, err := json.MarshalIndent(k, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(k.path), 0700); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(k.path), ".keystore")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), k.path)
+}
+
+// Find returns the entry with a name or account of ref.
+func (k *Keystore) Find(ref string) (*Entry, error) {
+	for _, e := range k.Keys {
+		if e.Name == ref || e.Account.String() == ref {
+			return e, nil
+		}
+	}
+	return nil, fmt.Errorf("No key %s in keystore", ref)
+}
+
+// Add encrypts secret, in any form data.NewSecret accepts, under name.
+func (k *Keystore) Add(name, secret string, passphrase []byte) (*Entry, error) {
+	s, err := data.NewSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		return nil, fmt.Errorf("Key needs a name")
+	}
+	account := s.AccountId()
+	for _, e := range k.Keys {
+		if e.Name == name || e.Account == account {
+			return nil, fmt.Errorf("Keystore already has %s", e)
+		}
+	}
+	e := &Entry{Name: name, Account: account, KeyType: s.KeyType}
+	if err := e.encrypt(strings.TrimSpace(secret), passphrase, k.ScryptN); err != nil {
+		return nil, err
+	}
+	k.Keys = append(k.Keys, e)
+	return e, nil
+}
+
+// Remove deletes the entry for ref.
+func (k *Keystore) Remove(ref string) error {
+	e, err := k.Find(ref)
+	if err != nil {
+		return err
+	}
+	for i := range k.Keys {
+		if k.Keys[i] == e {
+			k.Keys = append(k.Keys[:i], k.Keys[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// Export returns the secret of ref as it was added.
+func (k *Keystore) Export(ref string, passphrase []byte) (string, error) {
+	e, err := k.Find(ref)
+	if err != nil {
+		return "", err
+	}
+	return e.decrypt(passphrase)
+}
+
+// Secret returns the decrypted secret of ref.
+func (k *Keystore) Secret(ref string, passphrase []byte) (*data.Secret, error) {
+	e, err := k.Find(ref)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := e.decrypt(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := data.NewSecret(plain)
+	if err != nil {
+		return nil, err
+	}
+	if secret.AccountId() != e.Account {
+		return nil, fmt.Errorf("Key %s is for %s not %s", e.Name, secret.AccountId(), e.Account)
+	}
+	return secret, nil
+}
+
+// Rotate encrypts the secret of ref again with a new passphrase, salt
+// and nonce.
+func (k *Keystore) Rotate(ref string, from, to []byte) error {
+	e, err := k.Find(ref)
+	if err != nil {
+		return err
+	}
+	plain, err := e.decrypt(from)
+	if err != nil {
+		return err
+	}
+	return e.encrypt(plain, to, k.ScryptN)
+}
+
+func (e *Entry) aead(passphrase []byte) (cipher.AEAD, error) {
+	if e.KDF.Name != kdfScrypt || e.Cipher != cipherAESGCM {
+		return nil, fmt.Errorf("Unsupported encryption of %s: %s with %s", e.Name, e.KDF.Name, e.Cipher)
+	}
+	if n, r, p := e.KDF.N, e.KDF.R, e.KDF.P; n <= 1 || n > maxScryptN || n&(n-1) != 0 || r < 1 || r > maxScryptR || p < 1 || p > maxScryptP {
+		return nil, fmt.Errorf("Bad scrypt parameters for %s: N=%d r=%d p=%d", e.Name, n, r, p)
+	}
+	key, err := scrypt.Key(passphrase, e.KDF.Salt, e.KDF.N, e.KDF.R, e.KDF.P, keySize)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (e *Entry) encrypt(secret string, passphrase []byte, n int) error {
+	if n == 0 {
+		n = DefaultScryptN
+	}
+	e.KDF = KDF{Name: kdfScrypt, N: n, R: scryptR, P: scryptP, Salt: make([]byte, saltSize)}
+	e.Cipher = cipherAESGCM
+	if _, err := rand.Read(e.KDF.Salt); err != nil {
+		return err
+	}
+	aead, err := e.aead(passphrase)
+	if err != nil {
+		return err
+	}
+	e.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(e.Nonce); err != nil {
+		return err
+	}
+	e.Ciphertext = aead.Seal(nil, e.Nonce, []byte(secret), e.Account[:])
+	return nil
+}
+
+func (e *Entry) decrypt(passphrase []byte) (string, error) {
+	aead, err := e.aead(passphrase)
+	if err != nil {
+		return "", err
+	}
+	if len(e.Nonce) != aead.NonceSize() {
+		return "", fmt.Errorf("Bad nonce length for %s: %d", e.Name, len(e.Nonce))
+	}
+	plain, err := aead.Open(nil, e.Nonce, e.Ciphertext, e.Account[:])
+	if err != nil {
+		return "", fmt.Errorf("Wrong passphrase for %s", e.Name)
+	}
+	return string(plain), nil
+}
+
+// ReadPassphrase returns the value of the environment variable env if it
+// is set, or else reads it from the terminal after showing prompt, without
+// echoing it.
+func ReadPassphrase(env, prompt string) ([]byte, error) {
+	return readPassphrase(env, prompt, false)
+}
+
+// ReadNewPassphrase is like ReadPassphrase, except that a passphrase read
+// from the terminal is asked for twice, and must be the same both times.
+func ReadNewPassphrase(env, prompt string) ([]byte, error) {
+	return readPassphrase(env, prompt, true)
+}
+
+func readPassphrase(env, prompt string, confirm bool) ([]byte, error) {
+	if passphrase, ok := os.LookupEnv(env); ok {
+		return []byte(passphrase), nil
+	}
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("No terminal to ask for passphrase and %s is not set", env)
+	}
+	defer tty.Close()
+	fmt.Fprint(tty, prompt)
+	passphrase, err := readHidden(tty, tty)
+	if err != nil || !confirm {
+		return passphrase, err
+	}
+	fmt.Fprint(tty, "Repeat passphrase: ")
+	repeated, err := readHidden(tty, tty)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(passphrase, repeated) {
+		return nil, fmt.Errorf("Passphrases do not match")
+	}
+	return passphrase, nil
+}
+
+// ReadSecret reads a line from stdin after showing prompt on stderr. It is
+// not echoed if stdin is a terminal, and may also be piped in.
+func ReadSecret(prompt string) ([]byte, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		return readHidden(os.Stdin, os.Stderr)
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return nil, err
+	}
+	return []byte(strings.TrimRight(line, "\r\n")), nil
+}
+
+// readHidden reads a line from the terminal f with echo turned off, then
+// ends the line of the prompt on w.
+func readHidden(f *os.File, w io.Writer) ([]byte, error) {
+	line, err := term.ReadPassword(int(f.Fd()))
+	fmt.Fprintln(w)
+	return line, err
+}
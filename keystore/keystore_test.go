@@ -0,0 +1,139 @@
+package keystore
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/parihaaraka/ripple/data"
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type KeystoreSuite struct{}
+
+var _ = Suite(&KeystoreSuite{})
+
+const (
+	root     = "snoPBrXtMeMyMHUVTgbuqAfg1SUTb"
+	mnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+)
+
+func newKeystore(c *C) *Keystore {
+	k, err := Open(filepath.Join(c.MkDir(), "keys", "keystore.json"))
+	c.Assert(err, IsNil)
+	c.Check(k.Keys, HasLen, 0)
+	k.ScryptN = 1 << 10
+	return k
+}
+
+func (s *KeystoreSuite) TestKeystore(c *C) {
+	k := newKeystore(c)
+	_, err := k.Add("root", root, []byte("one"))
+	c.Assert(err, IsNil)
+	_, err = k.Add("wallet", mnemonic, []byte("two"))
+	c.Assert(err, IsNil)
+	_, err = k.Add("again", root, []byte("one"))
+	c.Check(err, ErrorMatches, "Keystore already has root .*")
+	_, err = k.Add("bad", "snoPBrXtMeMyMHUVTgbuqAfg1SUTa", []byte("one"))
+	c.Check(err, NotNil)
+	c.Assert(k.Save(), IsNil)
+
+	// The secrets are not in the file
+	b, err := ioutil.ReadFile(k.path)
+	c.Assert(err, IsNil)
+	c.Check(strings.Contains(string(b), root), Equals, false)
+	c.Check(strings.Contains(string(b), "abandon"), Equals, false)
+	info, err := os.Stat(k.path)
+	c.Assert(err, IsNil)
+	c.Check(info.Mode().Perm(), Equals, os.FileMode(0600))
+
+	k, err = Open(k.path)
+	c.Assert(err, IsNil)
+	c.Assert(k.Keys, HasLen, 2)
+	c.Check(k.Keys[0].String(), Matches, "root +rHb9CJAWyB4rj91VRWn96DkukG4bwdtyTh ECDSA")
+
+	secret, err := k.Secret("rHb9CJAWyB4rj91VRWn96DkukG4bwdtyTh", []byte("one"))
+	c.Assert(err, IsNil)
+	c.Check(secret.String(), Equals, root)
+	secret, err = k.Secret("wallet", []byte("two"))
+	c.Assert(err, IsNil)
+	c.Check(secret.AccountId().String(), Equals, "rHsMGQEkVNJmpGWs8XUBoTBiAAbwxZN5v3")
+	exported, err := k.Export("wallet", []byte("two"))
+	c.Assert(err, IsNil)
+	c.Check(exported, Equals, mnemonic)
+
+	_, err = k.Secret("root", []byte("two"))
+	c.Check(err, ErrorMatches, "Wrong passphrase for root")
+	_, err = k.Secret("nobody", []byte("one"))
+	c.Check(err, ErrorMatches, "No key nobody in keystore")
+
+	// Rotating changes the passphrase, salt and nonce
+	salt, nonce := k.Keys[0].KDF.Salt, k.Keys[0].Nonce
+	c.Assert(k.Rotate("root", []byte("one"), []byte("three")), IsNil)
+	c.Check(k.Keys[0].KDF.Salt, Not(DeepEquals), salt)
+	c.Check(k.Keys[0].Nonce, Not(DeepEquals), nonce)
+	_, err = k.Secret("root", []byte("one"))
+	c.Check(err, NotNil)
+	_, err = k.Secret("root", []byte("three"))
+	c.Check(err, IsNil)
+	c.Check(k.Rotate("root", []byte("one"), []byte("four")), ErrorMatches, "Wrong passphrase for root")
+
+	// The account can't be swapped for another
+	other, err := data.NewAccountFromAddress("rHsMGQEkVNJmpGWs8XUBoTBiAAbwxZN5v3")
+	c.Assert(err, IsNil)
+	k.Keys[0].Account = *other
+	_, err = k.Secret("root", []byte("three"))
+	c.Check(err, ErrorMatches, "Wrong passphrase for root")
+
+	c.Assert(k.Remove("wallet"), IsNil)
+	c.Check(k.Keys, HasLen, 1)
+	c.Check(k.Remove("wallet"), NotNil)
+}
+
+func (s *KeystoreSuite) TestVersion(c *C) {
+	path := filepath.Join(c.MkDir(), "keystore.json")
+	c.Assert(ioutil.WriteFile(path, []byte(`{"version":2,"keys":[]}`), 0600), IsNil)
+	_, err := Open(path)
+	c.Check(err, ErrorMatches, "Unsupported keystore version 2 in .*")
+}
+
+func (s *KeystoreSuite) TestScryptBounds(c *C) {
+	k := newKeystore(c)
+	_, err := k.Add("root", root, []byte("one"))
+	c.Assert(err, IsNil)
+	for _, kdf := range []KDF{
+		{N: 1 << 40, R: 8, P: 1},
+		{N: 1000, R: 8, P: 1},
+		{N: 1 << 10, R: 1 << 20, P: 1},
+		{N: 1 << 10, R: 8, P: 0},
+		{N: 1 << 10, R: 8, P: 1 << 20},
+	} {
+		e := *k.Keys[0]
+		e.KDF.N, e.KDF.R, e.KDF.P = kdf.N, kdf.R, kdf.P
+		k.Keys[0] = &e
+		_, err = k.Secret("root", []byte("one"))
+		c.Check(err, ErrorMatches, "Bad scrypt parameters for root: .*")
+	}
+	k.ScryptN = 1 << 24
+	_, err = k.Add("wallet", mnemonic, []byte("two"))
+	c.Check(err, ErrorMatches, "Bad scrypt parameters for wallet: .*")
+}
+
+func (s *KeystoreSuite) TestReadPassphrase(c *C) {
+	os.Setenv(PassphraseEnv, "secret")
+	defer os.Unsetenv(PassphraseEnv)
+	p, err := ReadPassphrase(PassphraseEnv, "")
+	c.Assert(err, IsNil)
+	c.Check(string(p), Equals, "secret")
+
+	// A new passphrase from the environment needs no confirmation
+	os.Setenv(NewPassphraseEnv, "new secret")
+	defer os.Unsetenv(NewPassphraseEnv)
+	p, err = ReadNewPassphrase(NewPassphraseEnv, "")
+	c.Assert(err, IsNil)
+	c.Check(string(p), Equals, "new secret")
+}
@@ -0,0 +1,427 @@
+package ledger
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/parihaaraka/ripple/data"
+	"github.com/parihaaraka/ripple/peers"
+	"github.com/parihaaraka/ripple/shamap"
+)
+
+const (
+	// Node ids asked for in each TMGetLedger.
+	DefaultBatchSize = 128
+
+	// Time waited for each reply before asking again.
+	DefaultTimeout = 10 * time.Second
+
+	// Requests repeated without any progress before giving up on a peer.
+	DefaultRetries = 3
+)
+
+// Acquirer fetches whole ledgers from peers with TMGetLedger. The header
+// of a ledger is fetched first, and then its state and transaction trees
+// are walked down from their roots, asking for the missing nodes in
+// batches. Every node is checked against the hash held by its parent, and
+// so against the header, before its children are asked for.
+//
+// The ledgers of a range are shared among the peers with a LedgerSet, from
+// the top down, so that the parent hash of each trusted ledger is usually
+// known when its parent is fetched. A ledger fetched before then is held
+// until it is known, and fetched again if it does not match.
+type Acquirer struct {
+	BatchSize int
+	Timeout   time.Duration
+	Retries   int
+
+	mu        sync.Mutex
+	ledgers   *data.LedgerSet
+	first     uint32
+	last      uint32
+	remaining int
+	trusted   map[uint32]data.Hash256
+	held      map[uint32]*data.Ledger // Fetched before their hash was trusted
+	changed   chan struct{}
+}
+
+// NewAcquirer returns an Acquirer for the ledgers first to last inclusive.
+func NewAcquirer(first, last uint32) *Acquirer {
+	return &Acquirer{
+		BatchSize: DefaultBatchSize,
+		Timeout:   DefaultTimeout,
+		Retries:   DefaultRetries,
+		ledgers:   data.NewLedgerSet(first, last+1),
+		first:     first,
+		last:      last,
+		remaining: int(last - first + 1),
+		trusted:   make(map[uint32]data.Hash256),
+		held:      make(map[uint32]*data.Ledger),
+		changed:   make(chan struct{}),
+	}
+}
+
+// Trust sets the hash which ledger seq must have. Without it, the last
+// ledger of the range is only checked to be whole, and the others are
+// checked against the chain from it.
+func (a *Acquirer) Trust(seq uint32, hash data.Hash256) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.trusted[seq] = hash
+}
+
+// take returns the next ledger to fetch, and the hash it must have if
+// known, or nil if none are left to take.
+func (a *Acquirer) take() (*data.Work, data.Hash256, <-chan struct{}) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	work := &data.Work{
+		LedgerRange:    &data.LedgerRange{Start: a.first, End: a.last, Max: 1},
+		MissingLedgers: a.ledgers.TakeTop(1),
+	}
+	if len(work.MissingLedgers) == 0 {
+		return nil, data.Hash256{}, a.changed
+	}
+	return work, a.trusted[work.MissingLedgers[0]], a.changed
+}
+
+// finish records the outcome of fetching a ledger, and wakes any peer
+// waiting for work. It returns the ledgers whose hashes are now trusted,
+// from the top down. A ledger whose hash is not yet known is held until
+// its child's is, and one which turns out not to be in the chain is
+// returned to be fetched again.
+func (a *Acquirer) finish(seq uint32, ledger *data.Ledger) []*data.Ledger {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	defer func() {
+		close(a.changed)
+		a.changed = make(chan struct{})
+	}()
+	if ledger == nil {
+		a.ledgers.Return(seq)
+		return nil
+	}
+	a.held[seq] = ledger
+	var verified []*data.Ledger
+	for ledger := a.held[seq]; ledger != nil; ledger = a.held[seq] {
+		trusted, known := a.trusted[seq]
+		switch {
+		case !known && seq != a.last:
+			return verified
+		case known && ledger.Hash != trusted:
+			delete(a.held, seq)
+			a.ledgers.Return(seq)
+			return verified
+		}
+		delete(a.held, seq)
+		a.ledgers.Set(seq)
+		a.remaining--
+		verified = append(verified, ledger)
+		if seq == a.first {
+			break
+		}
+		a.trusted[seq-1] = ledger.PreviousLedger
+		seq--
+	}
+	return verified
+}
+
+func (a *Acquirer) done() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.remaining == 0
+}
+
+// Run fetches the range with one worker for each peer, calling found as
+// each ledger is completed and checked against the chain, never
+// concurrently. A peer which fails is
+// given no more work, and its ledger goes to the others. Run returns when
+// the range is complete, found returns an error, ctx is done or every
+// peer has failed.
+func (a *Acquirer) Run(ctx context.Context, conns []*peers.Peer, found func(*data.Ledger) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg       sync.WaitGroup
+		foundMu  sync.Mutex
+		errMu    sync.Mutex
+		firstErr error
+		foundErr error
+	)
+	fail := func(err error) {
+		errMu.Lock()
+		defer errMu.Unlock()
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	for _, p := range conns {
+		wg.Add(1)
+		go func(p *peers.Peer) {
+			defer wg.Done()
+			for !a.done() {
+				work, expected, changed := a.take()
+				if work == nil {
+					select {
+					case <-changed:
+						continue
+					case <-ctx.Done():
+						return
+					}
+				}
+				seq := work.MissingLedgers[0]
+				ledger, err := a.Fetch(ctx, p, seq, expected)
+				if err != nil {
+					a.finish(seq, nil)
+					fail(err)
+					return
+				}
+				verified := a.finish(seq, ledger)
+				foundMu.Lock()
+				for _, ledger := range verified {
+					if err = found(ledger); err != nil {
+						break
+					}
+				}
+				foundMu.Unlock()
+				if err != nil {
+					errMu.Lock()
+					foundErr = err
+					errMu.Unlock()
+					cancel()
+					return
+				}
+			}
+		}(p)
+	}
+	wg.Wait()
+	if foundErr != nil {
+		return foundErr
+	}
+	if a.done() {
+		return nil
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// Fetch acquires ledger seq from p. The ledger must hash to expected
+// unless it is zero.
+func (a *Acquirer) Fetch(ctx context.Context, p *peers.Peer, seq uint32, expected data.Hash256) (*data.Ledger, error) {
+	req := &peers.TMGetLedger{InfoType: peers.LI_BASE, LedgerSeq: seq}
+	if !expected.IsZero() {
+		req.LedgerHash = expected.Bytes()
+	}
+	reply, err := a.request(ctx, p, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(reply.Nodes) == 0 {
+		return nil, fmt.Errorf("Ledger %d from %s has no header", seq, p)
+	}
+	ledger, err := data.ReadLedger(bytes.NewReader(reply.Nodes[0].NodeData), data.Hash256{})
+	if err != nil {
+		return nil, err
+	}
+	if ledger.Hash, err = ledger.CalculateHash(); err != nil {
+		return nil, err
+	}
+	switch {
+	case ledger.LedgerSequence != seq:
+		return nil, fmt.Errorf("Ledger %d from %s is ledger %d", seq, p, ledger.LedgerSequence)
+	case !expected.IsZero() && ledger.Hash != expected:
+		return nil, fmt.Errorf("Ledger %d hash: %s expected: %s", seq, ledger.Hash, expected)
+	}
+	// The roots of the state and transaction trees follow the header
+	roots := reply.Nodes[1:]
+	state, err := a.walk(ctx, p, ledger, peers.LI_AS_NODE, ledger.StateHash, &roots)
+	if err != nil {
+		return nil, err
+	}
+	txs, err := a.walk(ctx, p, ledger, peers.LI_TX_NODE, ledger.TransactionHash, &roots)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range state {
+		le, ok := item.(data.LedgerEntry)
+		if !ok {
+			return nil, fmt.Errorf("Ledger %d state holds %s", seq, item.GetType())
+		}
+		ledger.AccountState = append(ledger.AccountState, le)
+	}
+	for _, item := range txs {
+		txm, ok := item.(*data.TransactionWithMetaData)
+		if !ok {
+			return nil, fmt.Errorf("Ledger %d transactions hold %s", seq, item.GetType())
+		}
+		ledger.Transactions = append(ledger.Transactions, txm)
+	}
+	ledger.Closed, ledger.Accepted = true, true
+	return ledger, nil
+}
+
+// walk fetches the tree with the root hash, whose root node may be the
+// first of roots, and returns its items in order of key.
+func (a *Acquirer) walk(ctx context.Context, p *peers.Peer, ledger *data.Ledger, info peers.LedgerInfoType, root data.Hash256, roots *[]peers.TMLedgerNode) ([]data.Hashable, error) {
+	if root.IsZero() {
+		return nil, nil
+	}
+	typ := data.NT_ACCOUNT_NODE
+	if info == peers.LI_TX_NODE {
+		typ = data.NT_TRANSACTION_NODE
+	}
+	var (
+		pending  = map[shamap.NodeID]data.Hash256{{}: root}
+		received = make(map[shamap.NodeID][]byte)
+		items    = make(map[data.Hash256]data.Hashable)
+		stalled  int
+	)
+	if len(*roots) > 0 {
+		received[shamap.NodeID{}] = (*roots)[0].NodeData
+		*roots = (*roots)[1:]
+	}
+	for {
+		progress, err := a.consume(ledger, typ, pending, received, items)
+		if err != nil {
+			return nil, fmt.Errorf("%s from %s", err, p)
+		}
+		if len(pending) == 0 {
+			break
+		}
+		if progress {
+			stalled = 0
+		} else if stalled++; stalled > a.Retries {
+			return nil, fmt.Errorf("Ledger %d from %s is missing %d nodes", ledger.LedgerSequence, p, len(pending))
+		}
+		reply, err := a.request(ctx, p, &peers.TMGetLedger{
+			InfoType:   info,
+			LedgerHash: ledger.Hash.Bytes(),
+			LedgerSeq:  ledger.LedgerSequence,
+			NodeIDs:    a.batch(pending),
+		})
+		if err != nil {
+			return nil, err
+		}
+		received = make(map[shamap.NodeID][]byte)
+		for _, node := range reply.Nodes {
+			if id, err := shamap.NewNodeID(node.NodeID); err == nil {
+				received[id] = node.NodeData
+			}
+		}
+	}
+	keys := make([]data.Hash256, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i][:], keys[j][:]) < 0 })
+	sorted := make([]data.Hashable, len(keys))
+	for i, key := range keys {
+		sorted[i] = items[key]
+	}
+	return sorted, nil
+}
+
+// consume checks the received nodes which are pending, and replaces each
+// inner node with its children in pending. Fat replies hold descendants
+// of the requested nodes, so it repeats until nothing changes.
+func (a *Acquirer) consume(ledger *data.Ledger, typ data.NodeType, pending map[shamap.NodeID]data.Hash256, received map[shamap.NodeID][]byte, items map[data.Hash256]data.Hashable) (bool, error) {
+	var progress bool
+	for changed := true; changed; {
+		changed = false
+		for id, b := range received {
+			expected, ok := pending[id]
+			if !ok {
+				continue
+			}
+			node, err := shamap.ReadWireNode(b, typ, ledger.LedgerSequence)
+			if err != nil {
+				return progress, fmt.Errorf("Ledger %d node %s: %s", ledger.LedgerSequence, id, err)
+			}
+			if node.Hash != expected {
+				return progress, fmt.Errorf("Ledger %d node %s hash: %s expected: %s", ledger.LedgerSequence, id, node.Hash, expected)
+			}
+			delete(pending, id)
+			delete(received, id)
+			if node.Children != nil {
+				for i, child := range node.Children {
+					if !child.IsZero() {
+						pending[id.Child(i)] = child
+					}
+				}
+			} else {
+				items[*node.Item.GetHash()] = node.Item
+			}
+			changed, progress = true, true
+		}
+	}
+	return progress, nil
+}
+
+// batch returns up to BatchSize of the pending node ids, shallowest first.
+func (a *Acquirer) batch(pending map[shamap.NodeID]data.Hash256) [][]byte {
+	ids := make([]shamap.NodeID, 0, len(pending))
+	for id := range pending {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if ids[i].Depth != ids[j].Depth {
+			return ids[i].Depth < ids[j].Depth
+		}
+		return bytes.Compare(ids[i].Path[:], ids[j].Path[:]) < 0
+	})
+	if len(ids) > a.BatchSize {
+		ids = ids[:a.BatchSize]
+	}
+	batch := make([][]byte, len(ids))
+	for i, id := range ids {
+		batch[i] = id.Bytes()
+	}
+	return batch
+}
+
+// request sends req to p until it answers, ignoring other messages.
+func (a *Acquirer) request(ctx context.Context, p *peers.Peer, req *peers.TMGetLedger) (*peers.TMLedgerData, error) {
+	for attempt := 0; attempt <= a.Retries; attempt++ {
+		if err := p.Send(req); err != nil {
+			return nil, err
+		}
+		timeout := time.NewTimer(a.Timeout)
+		for waiting := true; waiting; {
+			select {
+			case m, ok := <-p.Incoming:
+				if !ok {
+					timeout.Stop()
+					return nil, fmt.Errorf("Connection to %s lost: %v", p, p.Err())
+				}
+				reply, ok := m.(*peers.TMLedgerData)
+				if !ok || !answers(reply, req) {
+					continue
+				}
+				timeout.Stop()
+				if reply.Error != 0 {
+					return nil, fmt.Errorf("Ledger %d from %s: error %d", req.LedgerSeq, p, reply.Error)
+				}
+				return reply, nil
+			case <-timeout.C:
+				waiting = false
+			case <-ctx.Done():
+				timeout.Stop()
+				return nil, ctx.Err()
+			}
+		}
+	}
+	return nil, fmt.Errorf("Ledger %d from %s timed out", req.LedgerSeq, p)
+}
+
+func answers(reply *peers.TMLedgerData, req *peers.TMGetLedger) bool {
+	if reply.InfoType != req.InfoType || reply.LedgerSeq != req.LedgerSeq {
+		return false
+	}
+	return len(req.LedgerHash) == 0 || bytes.Equal(reply.LedgerHash, req.LedgerHash)
+}
@@ -0,0 +1,287 @@
+package ledger
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/parihaaraka/ripple/data"
+	"github.com/parihaaraka/ripple/peers"
+	"github.com/parihaaraka/ripple/shamap"
+	. "gopkg.in/check.v1"
+)
+
+type AcquireSuite struct{}
+
+var _ = Suite(&AcquireSuite{})
+
+type served struct {
+	header     data.LedgerHeader
+	hash       data.Hash256
+	state, txs *shamap.SHAMap
+}
+
+// fakePeer answers TMGetLedger from the ledgers it holds, with the
+// children of each inner node asked for when fat.
+type fakePeer struct {
+	ledgers map[uint32]*served
+	fat     bool
+	tamper  func(id shamap.NodeID, b []byte) []byte
+	delay   time.Duration // Before each reply
+	headers int32         // Served, counted atomically
+}
+
+func (f *fakePeer) add(c *C, header *data.LedgerHeader, state, txs *shamap.SHAMap) {
+	hash, err := header.CalculateHash()
+	c.Assert(err, IsNil)
+	if f.ledgers == nil {
+		f.ledgers = make(map[uint32]*served)
+	}
+	f.ledgers[header.LedgerSequence] = &served{*header, hash, state, txs}
+}
+
+func (f *fakePeer) node(m *shamap.SHAMap, id shamap.NodeID) []byte {
+	b, err := m.WireNode(id)
+	if err != nil {
+		return nil
+	}
+	if f.tamper != nil {
+		b = f.tamper(id, b)
+	}
+	return b
+}
+
+func (f *fakePeer) answer(req *peers.TMGetLedger) *peers.TMLedgerData {
+	reply := &peers.TMLedgerData{
+		LedgerHash: req.LedgerHash,
+		LedgerSeq:  req.LedgerSeq,
+		InfoType:   req.InfoType,
+	}
+	l, ok := f.ledgers[req.LedgerSeq]
+	if !ok || (len(req.LedgerHash) > 0 && l.hash != *hashOf(req.LedgerHash)) {
+		reply.Error = peers.RE_NO_LEDGER
+		return reply
+	}
+	reply.LedgerHash = l.hash.Bytes()
+	m := l.state
+	switch req.InfoType {
+	case peers.LI_BASE:
+		_, header, _ := data.Raw(&data.Ledger{LedgerHeader: l.header})
+		reply.Nodes = append(reply.Nodes, peers.TMLedgerNode{NodeData: header})
+		atomic.AddInt32(&f.headers, 1)
+		reply.Nodes = append(reply.Nodes, peers.TMLedgerNode{NodeData: f.node(l.state, shamap.NodeID{})})
+		if l.txs.Len() > 0 {
+			reply.Nodes = append(reply.Nodes, peers.TMLedgerNode{NodeData: f.node(l.txs, shamap.NodeID{})})
+		}
+		return reply
+	case peers.LI_TX_NODE:
+		m = l.txs
+	}
+	for _, b := range req.NodeIDs {
+		id, err := shamap.NewNodeID(b)
+		if err != nil {
+			continue
+		}
+		ids := []shamap.NodeID{id}
+		if f.fat {
+			for i := 0; i < 16; i++ {
+				ids = append(ids, id.Child(i))
+			}
+		}
+		for _, id := range ids {
+			if node := f.node(m, id); node != nil {
+				reply.Nodes = append(reply.Nodes, peers.TMLedgerNode{NodeData: node, NodeID: id.Bytes()})
+			}
+		}
+	}
+	return reply
+}
+
+func hashOf(b []byte) *data.Hash256 {
+	var h data.Hash256
+	copy(h[:], b)
+	return &h
+}
+
+// connect returns a connection to f.
+func (f *fakePeer) connect(c *C) *peers.Peer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, IsNil)
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		p, err := peers.Accept(context.Background(), conn, nil)
+		if err != nil {
+			return
+		}
+		defer p.Close()
+		for m := range p.Incoming {
+			if req, ok := m.(*peers.TMGetLedger); ok {
+				time.Sleep(f.delay)
+				if err := p.Send(f.answer(req)); err != nil {
+					return
+				}
+			}
+		}
+	}()
+	p, err := peers.Dial(context.Background(), l.Addr().String(), nil)
+	c.Assert(err, IsNil)
+	return p
+}
+
+func load38129(c *C) (*data.Ledger, *shamap.SHAMap, *shamap.SHAMap) {
+	b, err := ioutil.ReadFile("../data/testdata/ledger_6000000.json")
+	c.Assert(err, IsNil)
+	var ledger data.Ledger
+	c.Assert(json.Unmarshal(b, &ledger), IsNil)
+	state, err := shamap.NewState(ledger.AccountState)
+	c.Assert(err, IsNil)
+	txs, err := shamap.NewTransactions(ledger.Transactions)
+	c.Assert(err, IsNil)
+	return &ledger, state, txs
+}
+
+func (s *AcquireSuite) TestFetch(c *C) {
+	ledger, state, txs := load38129(c)
+	ctx := context.Background()
+	for _, fat := range []bool{false, true} {
+		f := &fakePeer{fat: fat}
+		f.add(c, &ledger.LedgerHeader, state, txs)
+		p := f.connect(c)
+		a := NewAcquirer(38129, 38129)
+		a.BatchSize = 8
+
+		fetched, err := a.Fetch(ctx, p, 38129, ledger.Hash)
+		c.Assert(err, IsNil)
+		c.Check(fetched.Hash, Equals, ledger.Hash)
+		c.Check(fetched.AccountState, HasLen, 261)
+		c.Check(fetched.Transactions, HasLen, 1)
+		c.Check(shamap.VerifyLedger(fetched), IsNil)
+
+		// Without a trusted hash the ledger is asked for by sequence
+		fetched, err = a.Fetch(ctx, p, 38129, data.Hash256{})
+		c.Assert(err, IsNil)
+		c.Check(fetched.Hash, Equals, ledger.Hash)
+
+		_, err = a.Fetch(ctx, p, 38129, data.Hash256{1})
+		c.Check(err, ErrorMatches, "Ledger 38129 from .*: error 1")
+		p.Close()
+	}
+}
+
+func (s *AcquireSuite) TestFetchTampered(c *C) {
+	ledger, state, txs := load38129(c)
+	f := &fakePeer{}
+	f.add(c, &ledger.LedgerHeader, state, txs)
+	f.tamper = func(id shamap.NodeID, b []byte) []byte {
+		if id.Depth == 2 {
+			b[len(b)-2] ^= 1
+		}
+		return b
+	}
+	p := f.connect(c)
+	defer p.Close()
+	a := NewAcquirer(38129, 38129)
+	_, err := a.Fetch(context.Background(), p, 38129, ledger.Hash)
+	c.Check(err, ErrorMatches, "Ledger 38129 node 2:.* hash: .* expected: .* from .*")
+}
+
+func (s *AcquireSuite) TestFetchMissing(c *C) {
+	ledger, state, txs := load38129(c)
+	f := &fakePeer{}
+	f.add(c, &ledger.LedgerHeader, state, txs)
+	f.tamper = func(id shamap.NodeID, b []byte) []byte {
+		if id.Depth == 1 {
+			return nil
+		}
+		return b
+	}
+	p := f.connect(c)
+	defer p.Close()
+	a := NewAcquirer(38129, 38129)
+	_, err := a.Fetch(context.Background(), p, 38129, ledger.Hash)
+	c.Check(err, ErrorMatches, "Ledger 38129 from .* is missing 16 nodes")
+}
+
+func (s *AcquireSuite) TestRun(c *C) {
+	const first, last = 1000, 1019
+	source := newFakeSource(c, first, last)
+	good, bad := &fakePeer{fat: true}, &fakePeer{}
+	for seq := uint32(first); seq <= last; seq++ {
+		good.add(c, source.headers[seq], source.states[seq], shamap.New())
+	}
+	conns := []*peers.Peer{bad.connect(c), good.connect(c)}
+	defer conns[0].Close()
+	defer conns[1].Close()
+
+	a := NewAcquirer(first, last)
+	trusted, err := source.headers[last].CalculateHash()
+	c.Assert(err, IsNil)
+	a.Trust(last, trusted)
+	found := make(map[uint32]*data.Ledger)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c.Assert(a.Run(ctx, conns, func(ledger *data.Ledger) error {
+		found[ledger.LedgerSequence] = ledger
+		return nil
+	}), IsNil)
+	c.Assert(found, HasLen, last-first+1)
+	for seq := uint32(first); seq <= last; seq++ {
+		hash, err := source.headers[seq].CalculateHash()
+		c.Assert(err, IsNil)
+		c.Check(found[seq].Hash, Equals, hash)
+		c.Check(shamap.VerifyLedger(found[seq]), IsNil)
+	}
+
+	// Every peer failing ends the run
+	a = NewAcquirer(first-10, first-1)
+	c.Check(a.Run(ctx, conns, func(*data.Ledger) error { return nil }), ErrorMatches, "Ledger .* error 1")
+}
+
+func (s *AcquireSuite) TestRunTampered(c *C) {
+	const first, last = 1000, 1019
+	source := newFakeSource(c, first, last)
+	// The tampered peer answers quickly with whole ledgers which are not
+	// in the chain, apart from the trusted one.
+	good, other, tampered := &fakePeer{delay: 20 * time.Millisecond}, &fakePeer{delay: 20 * time.Millisecond}, &fakePeer{}
+	for seq := uint32(first); seq <= last; seq++ {
+		good.add(c, source.headers[seq], source.states[seq], shamap.New())
+		other.add(c, source.headers[seq], source.states[seq], shamap.New())
+		fake := *source.headers[seq]
+		if seq != last {
+			fake.CloseTime = data.NewRippleTime(seq*10 + 1)
+		}
+		tampered.add(c, &fake, source.states[seq], shamap.New())
+	}
+	conns := []*peers.Peer{good.connect(c), tampered.connect(c), other.connect(c)}
+	for _, p := range conns {
+		defer p.Close()
+	}
+
+	a := NewAcquirer(first, last)
+	trusted, err := source.headers[last].CalculateHash()
+	c.Assert(err, IsNil)
+	a.Trust(last, trusted)
+	found := make(map[uint32]*data.Ledger)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c.Assert(a.Run(ctx, conns, func(ledger *data.Ledger) error {
+		_, seen := found[ledger.LedgerSequence]
+		c.Check(seen, Equals, false)
+		found[ledger.LedgerSequence] = ledger
+		return nil
+	}), IsNil)
+	c.Check(atomic.LoadInt32(&tampered.headers) > 0, Equals, true)
+	c.Assert(found, HasLen, last-first+1)
+	for seq := uint32(first); seq <= last; seq++ {
+		hash, err := source.headers[seq].CalculateHash()
+		c.Assert(err, IsNil)
+		c.Check(found[seq].Hash, Equals, hash)
+	}
+}
@@ -0,0 +1,161 @@
+// Package ledger proves that old ledgers are ancestors of a trusted one,
+// so that a ledger fetched from an untrusted server can be relied upon
+// given only the hash of a recent validated ledger.
+//
+// Each ledger header holds the hash of its parent, and each ledger's state
+// holds skip lists of the hashes of earlier ledgers: the hashes of the
+// previous 256 ledgers, and pages of the hashes of every 256th ledger. A
+// ledger any distance back is reached in at most two hops through them.
+package ledger
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/parihaaraka/ripple/data"
+	"github.com/parihaaraka/ripple/shamap"
+)
+
+// Source provides ledger headers and proofs of state entries, none of
+// which need be trusted.
+type Source interface {
+	// Header returns the header of the ledger with the sequence.
+	Header(ctx context.Context, seq uint32) (*data.LedgerHeader, error)
+	// Prove returns a proof of the state entry at key in the ledger
+	// with the sequence.
+	Prove(ctx context.Context, seq uint32, key data.Hash256) (*shamap.Proof, error)
+}
+
+// The number of hashes in the recent skip list, and the distance between
+// the ledgers in the pages of older hashes.
+const skip = 256
+
+// DefaultMaxWalk is the MaxWalk of a new Chain.
+const DefaultMaxWalk = 8
+
+// Chain verifies ledgers against a trusted ledger.
+type Chain struct {
+	source Source
+	// Ledgers at most this far back are reached through the PreviousLedger
+	// of each header in turn, which needs no proofs.
+	MaxWalk uint32
+}
+
+// NewChain returns a Chain which fetches from source.
+func NewChain(source Source) *Chain {
+	return &Chain{
+		source:  source,
+		MaxWalk: DefaultMaxWalk,
+	}
+}
+
+// Header returns the header of the ledger with the sequence, after
+// checking that it has the expected hash.
+func (c *Chain) Header(ctx context.Context, seq uint32, expected data.Hash256) (*data.LedgerHeader, error) {
+	header, err := c.source.Header(ctx, seq)
+	if err != nil {
+		return nil, err
+	}
+	if header.LedgerSequence != seq {
+		return nil, fmt.Errorf("Ledger %d returned for %d", header.LedgerSequence, seq)
+	}
+	hash, err := header.CalculateHash()
+	if err != nil {
+		return nil, err
+	}
+	if hash != expected {
+		return nil, fmt.Errorf("Ledger %d hash: %s expected: %s", seq, hash, expected)
+	}
+	return header, nil
+}
+
+// Ancestor returns the header of ledger seq, after proving that it is an
+// ancestor of the trusted ledger with the sequence and hash.
+func (c *Chain) Ancestor(ctx context.Context, trustedSeq uint32, trusted data.Hash256, seq uint32) (*data.LedgerHeader, error) {
+	if seq > trustedSeq || seq == 0 {
+		return nil, fmt.Errorf("Ledger %d cannot be an ancestor of %d", seq, trustedSeq)
+	}
+	current, err := c.Header(ctx, trustedSeq, trusted)
+	if err != nil {
+		return nil, err
+	}
+	for current.LedgerSequence != seq {
+		next, hash, err := c.step(ctx, current, seq)
+		if err != nil {
+			return nil, err
+		}
+		if current, err = c.Header(ctx, next, hash); err != nil {
+			return nil, err
+		}
+	}
+	return current, nil
+}
+
+// step returns the sequence and hash of the next ledger on the way back
+// from current to seq.
+func (c *Chain) step(ctx context.Context, current *data.LedgerHeader, seq uint32) (uint32, data.Hash256, error) {
+	distance := current.LedgerSequence - seq
+	switch {
+	case distance <= c.MaxWalk:
+		return current.LedgerSequence - 1, current.PreviousLedger, nil
+	case distance <= skip:
+		key, err := data.GetLedgerHashIndex()
+		if err != nil {
+			return 0, data.Hash256{}, err
+		}
+		hash, err := c.skipped(ctx, current, *key, seq, 1)
+		return seq, hash, err
+	default:
+		// The first ledger in the pages at or after seq, whose recent
+		// skip list holds seq
+		next := (seq + skip - 1) / skip * skip
+		key, err := data.GetPreviousLedgerHashIndex(next)
+		if err != nil {
+			return 0, data.Hash256{}, err
+		}
+		hash, err := c.skipped(ctx, current, *key, next, skip)
+		return next, hash, err
+	}
+}
+
+// skipped returns the hash of ledger seq from the skip list at key in the
+// state of current.
+func (c *Chain) skipped(ctx context.Context, current *data.LedgerHeader, key data.Hash256, seq, interval uint32) (data.Hash256, error) {
+	var zero data.Hash256
+	proof, err := c.source.Prove(ctx, current.LedgerSequence, key)
+	if err != nil {
+		return zero, err
+	}
+	if proof.Key != key || proof.Type != data.NT_ACCOUNT_NODE {
+		return zero, fmt.Errorf("Proof of %s returned for %s", proof.Key, key)
+	}
+	if err := proof.Verify(current); err != nil {
+		return zero, fmt.Errorf("Ledger %d: %s", current.LedgerSequence, err)
+	}
+	value, err := proof.Value()
+	if err != nil {
+		return zero, err
+	}
+	hashes, ok := value.(*data.LedgerHashes)
+	if !ok {
+		return zero, fmt.Errorf("No skip list at %s in ledger %d", key, current.LedgerSequence)
+	}
+	hash, err := lookup(hashes, seq, interval)
+	if err != nil {
+		return zero, fmt.Errorf("Ledger %d: %s", current.LedgerSequence, err)
+	}
+	return hash, nil
+}
+
+// lookup returns the hash of ledger seq from a skip list whose ledgers are
+// interval apart, and end with its LastLedgerSequence.
+func lookup(hashes *data.LedgerHashes, seq, interval uint32) (data.Hash256, error) {
+	if hashes.LastLedgerSequence == nil || hashes.Hashes == nil {
+		return data.Hash256{}, fmt.Errorf("Incomplete skip list")
+	}
+	last, list := *hashes.LastLedgerSequence, *hashes.Hashes
+	if seq > last || (last-seq)%interval != 0 || int64((last-seq)/interval) >= int64(len(list)) {
+		return data.Hash256{}, fmt.Errorf("Ledger %d not in skip list ending at %d", seq, last)
+	}
+	return list[len(list)-1-int((last-seq)/interval)], nil
+}
@@ -0,0 +1,223 @@
+package ledger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/parihaaraka/ripple/data"
+	"github.com/parihaaraka/ripple/shamap"
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type ChainSuite struct{}
+
+var _ = Suite(&ChainSuite{})
+
+// fakeSource is a chain of ledgers whose state holds only skip lists,
+// kept the way rippled keeps them.
+type fakeSource struct {
+	headers map[uint32]*data.LedgerHeader
+	states  map[uint32]*shamap.SHAMap
+	fetched int
+	proved  int
+	tamper  func(*data.LedgerHeader)
+}
+
+func copyHashes(le *data.LedgerHashes, key data.Hash256, hash data.Hash256, seq uint32) *data.LedgerHashes {
+	next := &data.LedgerHashes{}
+	next.LedgerEntryType = data.LEDGER_HASHES
+	next.LedgerIndex = &key
+	var hashes data.Vector256
+	if le != nil {
+		hashes = append(hashes, *le.Hashes...)
+	}
+	hashes = append(hashes, hash)
+	next.Hashes = &hashes
+	next.LastLedgerSequence = &seq
+	return next
+}
+
+func newFakeSource(c *C, first, last uint32) *fakeSource {
+	s := &fakeSource{
+		headers: make(map[uint32]*data.LedgerHeader),
+		states:  make(map[uint32]*shamap.SHAMap),
+	}
+	recentKey, err := data.GetLedgerHashIndex()
+	c.Assert(err, IsNil)
+	entries := make(map[data.Hash256]*data.LedgerHashes)
+	var parent data.Hash256
+	for seq := first; seq <= last; seq++ {
+		if seq > first {
+			previous := seq - 1
+			if previous%skip == 0 {
+				key, err := data.GetPreviousLedgerHashIndex(previous)
+				c.Assert(err, IsNil)
+				entries[*key] = copyHashes(entries[*key], *key, parent, previous)
+			}
+			recent := copyHashes(entries[*recentKey], *recentKey, parent, previous)
+			if len(*recent.Hashes) > skip {
+				*recent.Hashes = (*recent.Hashes)[1:]
+			}
+			entries[*recentKey] = recent
+		}
+		state := shamap.New()
+		for key, le := range entries {
+			c.Assert(state.Put(key, le), IsNil)
+		}
+		header := &data.LedgerHeader{
+			LedgerSequence:  seq,
+			TotalXRP:        100000000000000000,
+			PreviousLedger:  parent,
+			StateHash:       state.Hash(),
+			ParentCloseTime: data.NewRippleTime(seq*10 - 10),
+			CloseTime:       data.NewRippleTime(seq * 10),
+			CloseResolution: 10,
+		}
+		parent, err = header.CalculateHash()
+		c.Assert(err, IsNil)
+		s.headers[seq], s.states[seq] = header, state
+	}
+	return s
+}
+
+func (s *fakeSource) Header(ctx context.Context, seq uint32) (*data.LedgerHeader, error) {
+	s.fetched++
+	header, ok := s.headers[seq]
+	if !ok {
+		return nil, fmt.Errorf("No ledger %d", seq)
+	}
+	fetched := *header
+	if s.tamper != nil {
+		s.tamper(&fetched)
+	}
+	return &fetched, nil
+}
+
+func (s *fakeSource) Prove(ctx context.Context, seq uint32, key data.Hash256) (*shamap.Proof, error) {
+	s.proved++
+	state, ok := s.states[seq]
+	if !ok {
+		return nil, fmt.Errorf("No ledger %d", seq)
+	}
+	return state.Prove(key)
+}
+
+func (s *ChainSuite) TestAncestor(c *C) {
+	const first, last = 3<<16 - 1000, 3<<16 + 1000
+	source := newFakeSource(c, first, last)
+	chain := NewChain(source)
+	trusted, err := source.headers[last].CalculateHash()
+	c.Assert(err, IsNil)
+	ctx := context.Background()
+
+	for _, test := range []struct {
+		seq             uint32
+		fetched, proved int
+	}{
+		{last, 1, 0},
+		{last - 1, 2, 0},
+		{last - DefaultMaxWalk, DefaultMaxWalk + 1, 0},
+		{last - DefaultMaxWalk - 1, 2, 1},
+		{last - skip, 2, 1},
+		{last - skip - 1, 3, 2},
+		{3 << 16, 2, 1},
+		{3<<16 - 1, 3, 1},
+		{3<<16 - 3, 5, 1},
+		{first + 1, 3, 2},
+		{first, 3, 2},
+	} {
+		source.fetched, source.proved = 0, 0
+		header, err := chain.Ancestor(ctx, last, trusted, test.seq)
+		comment := Commentf("Ledger %d", test.seq)
+		c.Assert(err, IsNil, comment)
+		c.Check(header, DeepEquals, source.headers[test.seq], comment)
+		c.Check(source.fetched, Equals, test.fetched, comment)
+		c.Check(source.proved, Equals, test.proved, comment)
+	}
+
+	_, err = chain.Ancestor(ctx, last, trusted, last+1)
+	c.Check(err, ErrorMatches, "Ledger .* cannot be an ancestor of .*")
+	_, err = chain.Ancestor(ctx, last, trusted, first-1)
+	c.Check(err, ErrorMatches, "Ledger 195840: Ledger 195607 not in skip list ending at 195839")
+	_, err = chain.Ancestor(ctx, last, data.Hash256{}, last-1)
+	c.Check(err, ErrorMatches, "Ledger .* hash: .* expected: 0+")
+}
+
+func (s *ChainSuite) TestTampered(c *C) {
+	const first, last = 1000, 2000
+	source := newFakeSource(c, first, last)
+	chain := NewChain(source)
+	trusted, err := source.headers[last].CalculateHash()
+	c.Assert(err, IsNil)
+	ctx := context.Background()
+
+	// A header which is not the one hashed by its child
+	source.tamper = func(header *data.LedgerHeader) {
+		if header.LedgerSequence == 1500 {
+			header.TotalXRP--
+		}
+	}
+	_, err = chain.Ancestor(ctx, last, trusted, 1500)
+	c.Check(err, ErrorMatches, "Ledger 1500 hash: .*")
+	_, err = chain.Ancestor(ctx, last, trusted, 1300)
+	c.Check(err, IsNil)
+
+	// A skip list which is not in the state of the trusted ledger
+	source.tamper = nil
+	key, err := data.GetLedgerHashIndex()
+	c.Assert(err, IsNil)
+	item, ok := source.states[last].Get(*key)
+	c.Assert(ok, Equals, true)
+	recent := *item.(*data.LedgerHashes)
+	hashes := append(data.Vector256{}, *recent.Hashes...)
+	hashes[0][0] ^= 1
+	recent.Hashes = &hashes
+	c.Assert(source.states[last].Put(*key, &recent), IsNil)
+	_, err = chain.Ancestor(ctx, last, trusted, 1900)
+	c.Check(err, ErrorMatches, "Ledger 2000: Proof root: .* expected: .*")
+}
+
+func (s *ChainSuite) TestLookup(c *C) {
+	b, err := ioutil.ReadFile("../data/testdata/ledger_6000000.json")
+	c.Assert(err, IsNil)
+	var ledger data.Ledger
+	c.Assert(json.Unmarshal(b, &ledger), IsNil)
+	recentKey, err := data.GetLedgerHashIndex()
+	c.Assert(err, IsNil)
+	pageKey, err := data.GetPreviousLedgerHashIndex(ledger.LedgerSequence)
+	c.Assert(err, IsNil)
+	var recent, page *data.LedgerHashes
+	for _, le := range ledger.AccountState {
+		switch {
+		case *le.GetLedgerIndex() == *recentKey:
+			recent = le.(*data.LedgerHashes)
+		case *le.GetLedgerIndex() == *pageKey:
+			page = le.(*data.LedgerHashes)
+		}
+	}
+	c.Assert(recent, NotNil)
+	c.Assert(page, NotNil)
+
+	hash, err := lookup(recent, ledger.LedgerSequence-1, 1)
+	c.Assert(err, IsNil)
+	c.Check(hash, Equals, ledger.PreviousLedger)
+	hash, err = lookup(recent, ledger.LedgerSequence-skip, 1)
+	c.Assert(err, IsNil)
+	c.Check(hash, Equals, (*recent.Hashes)[0])
+	_, err = lookup(recent, ledger.LedgerSequence-skip-1, 1)
+	c.Check(err, ErrorMatches, "Ledger 37872 not in skip list ending at 38128")
+
+	hash, err = lookup(page, 37888, skip)
+	c.Assert(err, IsNil)
+	c.Check(hash, Equals, (*page.Hashes)[len(*page.Hashes)-1])
+	hash, err = lookup(page, skip, skip)
+	c.Assert(err, IsNil)
+	c.Check(hash, Equals, (*page.Hashes)[0])
+	_, err = lookup(page, 37889, skip)
+	c.Check(err, ErrorMatches, "Ledger 37889 not in skip list ending at 37888")
+}
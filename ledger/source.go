@@ -0,0 +1,67 @@
+package ledger
+
+import (
+	"context"
+	"sync"
+
+	"github.com/parihaaraka/ripple/data"
+	"github.com/parihaaraka/ripple/shamap"
+	"github.com/parihaaraka/ripple/websockets"
+)
+
+// ClientSource is a Source which fetches from a websockets Client.
+// Servers do not provide proofs, so the whole state of a ledger is fetched
+// and kept to prove its entries. On a large network that is slow, so a
+// larger MaxWalk may be the better trade.
+type ClientSource struct {
+	client websockets.Client
+
+	mu    sync.Mutex
+	seq   uint32
+	state *shamap.SHAMap
+}
+
+// NewClientSource returns a Source which fetches from client.
+func NewClientSource(client websockets.Client) *ClientSource {
+	return &ClientSource{client: client}
+}
+
+func (s *ClientSource) Header(ctx context.Context, seq uint32) (*data.LedgerHeader, error) {
+	result, err := s.client.LedgerContext(ctx, seq, false)
+	if err != nil {
+		return nil, err
+	}
+	return &result.Ledger.LedgerHeader, nil
+}
+
+func (s *ClientSource) Prove(ctx context.Context, seq uint32, key data.Hash256) (*shamap.Proof, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state == nil || s.seq != seq {
+		state, err := s.fetch(ctx, seq)
+		if err != nil {
+			return nil, err
+		}
+		s.seq, s.state = seq, state
+	}
+	return s.state.Prove(key)
+}
+
+// fetch returns the state tree of the ledger. Entries which the stream
+// fails to fetch are missing, which makes any proof from it fail.
+func (s *ClientSource) fetch(ctx context.Context, seq uint32) (*shamap.SHAMap, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	state := shamap.New()
+	for les := range s.client.StreamLedgerDataContext(ctx, seq) {
+		for _, le := range les {
+			if err := state.PutEntry(le); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
@@ -0,0 +1,181 @@
+package peers
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/parihaaraka/ripple/crypto"
+	"github.com/parihaaraka/ripple/data"
+)
+
+// Protocol versions offered when upgrading, in order of preference
+var protocolVersions = []string{"XRPL/2.2", "XRPL/2.1", "XRPL/2.0"}
+
+const defaultUserAgent = "parihaaraka-ripple"
+
+// Config describes the local node to a peer.
+type Config struct {
+	Key       crypto.Key // secp256k1 node key, random if nil
+	NetworkID uint32     // Rejects peers on other networks, 0 is mainnet
+	UserAgent string
+}
+
+func (c *Config) key() (crypto.Key, error) {
+	if c.Key != nil {
+		return c.Key, nil
+	}
+	return crypto.NewECDSAKey(nil)
+}
+
+func (c *Config) userAgent() string {
+	if c.UserAgent != "" {
+		return c.UserAgent
+	}
+	return defaultUserAgent
+}
+
+// headers returns the handshake headers proving that the local node holds
+// its key at this end of the TLS session.
+func (c *Config) headers(key crypto.Key, shared []byte) (http.Header, error) {
+	sig, err := crypto.Sign(key.Private(nil), shared, shared)
+	if err != nil {
+		return nil, err
+	}
+	public, err := crypto.NewNodePublicKey(key.Public(nil))
+	if err != nil {
+		return nil, err
+	}
+	var cookie [8]byte
+	if _, err := rand.Read(cookie[:]); err != nil {
+		return nil, err
+	}
+	h := http.Header{}
+	h.Set("Connect-As", "Peer")
+	h.Set("Crawl", "private")
+	h.Set("Public-Key", public.String())
+	h.Set("Session-Signature", base64.StdEncoding.EncodeToString(sig))
+	h.Set("Network-Time", strconv.FormatUint(uint64(data.Now().Uint32()), 10))
+	h.Set("Instance-Cookie", strconv.FormatUint(binary.BigEndian.Uint64(cookie[:]), 10))
+	if c.NetworkID != 0 {
+		h.Set("Network-ID", strconv.FormatUint(uint64(c.NetworkID), 10))
+	}
+	return h, nil
+}
+
+// verify checks the handshake headers of the peer and returns its node
+// public key.
+func (c *Config) verify(h http.Header, key crypto.Key, shared []byte) (data.PublicKey, error) {
+	var public data.PublicKey
+	if id := h.Get("Network-ID"); id != "" {
+		networkID, err := strconv.ParseUint(id, 10, 32)
+		if err != nil {
+			return public, fmt.Errorf("Bad Network-ID: %s", id)
+		}
+		if uint32(networkID) != c.NetworkID {
+			return public, fmt.Errorf("Peer is on network %d", networkID)
+		}
+	}
+	node, err := crypto.NewRippleHashCheck(h.Get("Public-Key"), crypto.RIPPLE_NODE_PUBLIC)
+	if err != nil {
+		return public, fmt.Errorf("Bad Public-Key: %s", err)
+	}
+	if len(node.Payload()) != len(public) {
+		return public, fmt.Errorf("Bad Public-Key: %s", node)
+	}
+	copy(public[:], node.Payload())
+	if bytes.Equal(public[:], key.Public(nil)) {
+		return public, fmt.Errorf("Connected to self")
+	}
+	sig, err := base64.StdEncoding.DecodeString(h.Get("Session-Signature"))
+	if err != nil || len(sig) == 0 {
+		return public, fmt.Errorf("Bad Session-Signature from %s", node)
+	}
+	if ok, err := crypto.Verify(public[:], shared, shared, sig); !ok || err != nil {
+		return public, fmt.Errorf("Bad Session-Signature from %s", node)
+	}
+	return public, nil
+}
+
+// upgrade requests the peer protocol over an established TLS session.
+func (c *Config) upgrade(conn *tls.Conn, r *bufio.Reader, host string, header http.Header) (*http.Response, error) {
+	header.Set("User-Agent", c.userAgent())
+	header.Set("Connection", "Upgrade")
+	header.Set("Upgrade", strings.Join(protocolVersions, ", "))
+	req := &http.Request{
+		Method:     http.MethodGet,
+		URL:        &url.URL{Path: "/"},
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     header,
+		Host:       host,
+	}
+	if err := req.Write(conn); err != nil {
+		return nil, err
+	}
+	resp, err := http.ReadResponse(r, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		// A busy rippled suggests other peers in the body
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+		return nil, fmt.Errorf("Peer refused connection: %s %s", resp.Status, bytes.TrimSpace(body))
+	}
+	if version := resp.Header.Get("Upgrade"); version == "" || negotiate(version) != version {
+		return nil, fmt.Errorf("Unsupported protocol: %s", version)
+	}
+	return resp, nil
+}
+
+// accept answers a request for the peer protocol.
+func (c *Config) accept(conn *tls.Conn, r *bufio.Reader, header http.Header) (*http.Request, string, error) {
+	req, err := http.ReadRequest(r)
+	if err != nil {
+		return nil, "", err
+	}
+	version := negotiate(req.Header.Get("Upgrade"))
+	if version == "" || !strings.EqualFold(req.Header.Get("Connect-As"), "Peer") {
+		resp := &http.Response{
+			StatusCode: http.StatusBadRequest,
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+		}
+		resp.Write(conn)
+		return nil, "", fmt.Errorf("Unsupported protocol: %s", req.Header.Get("Upgrade"))
+	}
+	header.Set("Server", c.userAgent())
+	header.Set("Connection", "Upgrade")
+	header.Set("Upgrade", version)
+	resp := &http.Response{
+		StatusCode: http.StatusSwitchingProtocols,
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     header,
+	}
+	return req, version, resp.Write(conn)
+}
+
+// negotiate returns the preferred protocol version from a comma
+// separated list, or an empty string if none is supported.
+func negotiate(offered string) string {
+	for _, version := range protocolVersions {
+		for _, o := range strings.Split(offered, ",") {
+			if strings.TrimSpace(o) == version {
+				return version
+			}
+		}
+	}
+	return ""
+}
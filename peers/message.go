@@ -0,0 +1,130 @@
+package peers
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+type MessageType uint16
+
+const (
+	MT_MANIFESTS           MessageType = 2
+	MT_PING                MessageType = 3
+	MT_CLUSTER             MessageType = 5
+	MT_ENDPOINTS           MessageType = 15
+	MT_TRANSACTION         MessageType = 30
+	MT_GET_LEDGER          MessageType = 31
+	MT_LEDGER_DATA         MessageType = 32
+	MT_PROPOSE_LEDGER      MessageType = 33
+	MT_STATUS_CHANGE       MessageType = 34
+	MT_HAVE_SET            MessageType = 35
+	MT_VALIDATION          MessageType = 41
+	MT_GET_OBJECTS         MessageType = 42
+	MT_VALIDATOR_LIST      MessageType = 54
+	MT_SQUELCH             MessageType = 55
+	MT_VALIDATOR_LIST_COLL MessageType = 56
+	MT_PROOF_PATH_REQ      MessageType = 57
+	MT_PROOF_PATH_RESPONSE MessageType = 58
+	MT_REPLAY_DELTA_REQ    MessageType = 59
+	MT_REPLAY_DELTA_RESP   MessageType = 60
+	MT_HAVE_TRANSACTIONS   MessageType = 63
+	MT_TRANSACTIONS        MessageType = 64
+)
+
+var messageTypes = map[MessageType]string{
+	MT_MANIFESTS:           "Manifests",
+	MT_PING:                "Ping",
+	MT_CLUSTER:             "Cluster",
+	MT_ENDPOINTS:           "Endpoints",
+	MT_TRANSACTION:         "Transaction",
+	MT_GET_LEDGER:          "GetLedger",
+	MT_LEDGER_DATA:         "LedgerData",
+	MT_PROPOSE_LEDGER:      "ProposeLedger",
+	MT_STATUS_CHANGE:       "StatusChange",
+	MT_HAVE_SET:            "HaveSet",
+	MT_VALIDATION:          "Validation",
+	MT_GET_OBJECTS:         "GetObjects",
+	MT_VALIDATOR_LIST:      "ValidatorList",
+	MT_SQUELCH:             "Squelch",
+	MT_VALIDATOR_LIST_COLL: "ValidatorListCollection",
+	MT_PROOF_PATH_REQ:      "ProofPathRequest",
+	MT_PROOF_PATH_RESPONSE: "ProofPathResponse",
+	MT_REPLAY_DELTA_REQ:    "ReplayDeltaRequest",
+	MT_REPLAY_DELTA_RESP:   "ReplayDeltaResponse",
+	MT_HAVE_TRANSACTIONS:   "HaveTransactions",
+	MT_TRANSACTIONS:        "Transactions",
+}
+
+func (t MessageType) String() string {
+	if name, ok := messageTypes[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("Unknown(%d)", uint16(t))
+}
+
+const (
+	headerSize = 6
+	// Largest message rippled will send or accept
+	maxMessageSize = 64 << 20
+)
+
+// Message is one of the TM* protocol buffer messages exchanged by peers.
+type Message interface {
+	Type() MessageType
+	marshal() []byte
+	unmarshal([]byte) error
+}
+
+var messageFactory = map[MessageType]func() Message{
+	MT_MANIFESTS:      func() Message { return &TMManifests{} },
+	MT_PING:           func() Message { return &TMPing{} },
+	MT_TRANSACTION:    func() Message { return &TMTransaction{} },
+	MT_GET_LEDGER:     func() Message { return &TMGetLedger{} },
+	MT_LEDGER_DATA:    func() Message { return &TMLedgerData{} },
+	MT_PROPOSE_LEDGER: func() Message { return &TMProposeSet{} },
+	MT_STATUS_CHANGE:  func() Message { return &TMStatusChange{} },
+	MT_VALIDATION:     func() Message { return &TMValidation{} },
+}
+
+// ReadMessage reads and decodes the next framed message. Messages of
+// types without a decoder are returned as *TMUnknown.
+func ReadMessage(r io.Reader) (Message, error) {
+	var header [headerSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	// The top six bits flag compression, which is never requested,
+	// leaving 26 bits of size
+	if header[0]&0xFC != 0 {
+		return nil, fmt.Errorf("Unsupported message header: %X", header)
+	}
+	size := binary.BigEndian.Uint32(header[:4])
+	typ := MessageType(binary.BigEndian.Uint16(header[4:]))
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	factory, ok := messageFactory[typ]
+	if !ok {
+		return &TMUnknown{MessageType: typ, Payload: payload}, nil
+	}
+	m := factory()
+	if err := m.unmarshal(payload); err != nil {
+		return nil, fmt.Errorf("Bad %s message: %s", typ, err)
+	}
+	return m, nil
+}
+
+// WriteMessage frames and writes m with a single call to w.Write.
+func WriteMessage(w io.Writer, m Message) error {
+	payload := m.marshal()
+	if len(payload) > maxMessageSize {
+		return fmt.Errorf("%s message too large: %d bytes", m.Type(), len(payload))
+	}
+	b := make([]byte, headerSize, headerSize+len(payload))
+	binary.BigEndian.PutUint32(b, uint32(len(payload)))
+	binary.BigEndian.PutUint16(b[4:], uint16(m.Type()))
+	_, err := w.Write(append(b, payload...))
+	return err
+}
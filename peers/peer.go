@@ -0,0 +1,213 @@
+// Package peers speaks the rippled peer protocol: the TLS handshake
+// binding each node key to its session, the HTTP upgrade and the framed
+// protocol buffer messages which follow it.
+package peers
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/parihaaraka/ripple/data"
+)
+
+const (
+	// Time allowed to write a message to the peer.
+	writeWait = 10 * time.Second
+
+	// Time allowed for the TLS handshake and upgrade, unless the
+	// context has an earlier deadline.
+	handshakeTimeout = 10 * time.Second
+)
+
+// Peer is a connection to another node. Pings are answered as they
+// arrive and all messages, pings included, are sent on Incoming, which
+// is closed when the connection is lost or closed.
+type Peer struct {
+	Incoming  chan Message
+	PublicKey data.PublicKey // The node key of the peer
+	Protocol  string         // The negotiated protocol version
+	Header    http.Header    // The handshake headers of the peer
+	conn      *tls.Conn
+	reader    *bufio.Reader
+	mu        sync.Mutex
+	closed    chan struct{}
+	done      chan struct{}
+	once      sync.Once
+	err       error
+}
+
+// Dial connects to the peer protocol port of a node at address.
+func Dial(ctx context.Context, address string, config *Config) (*Peer, error) {
+	glog.Infoln(address)
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	p, err := handshake(ctx, conn, config, host)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("Handshake with %s failed: %s", address, err)
+	}
+	return p, nil
+}
+
+// Accept performs the server side of the handshake on an accepted
+// connection, for instance in a fake peer.
+func Accept(ctx context.Context, conn net.Conn, config *Config) (*Peer, error) {
+	p, err := handshake(ctx, conn, config, "")
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("Handshake with %s failed: %s", conn.RemoteAddr(), err)
+	}
+	return p, nil
+}
+
+// handshake upgrades conn as the client when host is not empty.
+func handshake(ctx context.Context, conn net.Conn, config *Config, host string) (*Peer, error) {
+	if config == nil {
+		config = &Config{}
+	}
+	key, err := config.key()
+	if err != nil {
+		return nil, err
+	}
+	deadline, ok := ctx.Deadline()
+	if timeout := time.Now().Add(handshakeTimeout); !ok || timeout.Before(deadline) {
+		deadline = timeout
+	}
+	conn.SetDeadline(deadline)
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Unix(1, 0))
+		case <-stop:
+		}
+	}()
+
+	client := host != ""
+	t := &transcript{client: client}
+	recording := &recordingConn{Conn: conn, transcript: t}
+	var tc *tls.Conn
+	if client {
+		tc = tls.Client(recording, t.config(nil))
+	} else {
+		cert, err := newCertificate()
+		if err != nil {
+			return nil, err
+		}
+		tc = tls.Server(recording, t.config(cert))
+	}
+	if err := tc.HandshakeContext(ctx); err != nil {
+		return nil, err
+	}
+	shared, err := t.sharedValue(tc.ConnectionState())
+	if err != nil {
+		return nil, err
+	}
+	header, err := config.headers(key, shared)
+	if err != nil {
+		return nil, err
+	}
+	p := &Peer{
+		Incoming: make(chan Message, 1000),
+		conn:     tc,
+		reader:   bufio.NewReader(tc),
+		closed:   make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if client {
+		resp, err := config.upgrade(tc, p.reader, host, header)
+		if err != nil {
+			return nil, err
+		}
+		p.Header, p.Protocol = resp.Header, resp.Header.Get("Upgrade")
+	} else {
+		req, version, err := config.accept(tc, p.reader, header)
+		if err != nil {
+			return nil, err
+		}
+		p.Header, p.Protocol = req.Header, version
+	}
+	if p.PublicKey, err = config.verify(p.Header, key, shared); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	go p.run()
+	return p, nil
+}
+
+func (p *Peer) String() string {
+	return fmt.Sprintf("%s %s", p.conn.RemoteAddr(), p.PublicKey.NodePublicKey())
+}
+
+func (p *Peer) run() {
+	defer func() {
+		close(p.Incoming)
+		close(p.done)
+	}()
+	for {
+		m, err := ReadMessage(p.reader)
+		if err != nil {
+			select {
+			case <-p.closed:
+			default:
+				glog.Errorf("%s: %s", p, err)
+				p.err = err
+			}
+			return
+		}
+		if ping, ok := m.(*TMPing); ok && ping.PingType == PT_PING {
+			pong := *ping
+			pong.PingType = PT_PONG
+			if err := p.Send(&pong); err != nil {
+				glog.Errorf("%s: %s", p, err)
+			}
+		}
+		select {
+		case p.Incoming <- m:
+		case <-p.closed:
+			return
+		}
+	}
+}
+
+// Send writes a message to the peer.
+func (p *Peer) Send(m Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return WriteMessage(p.conn, m)
+}
+
+// Close disconnects from the peer and blocks until Incoming is closed.
+func (p *Peer) Close() {
+	p.once.Do(func() {
+		close(p.closed)
+		p.conn.Close()
+	})
+	<-p.done
+}
+
+// Err returns the error which ended the connection, if it was not
+// closed with Close. It is only valid once Incoming is closed.
+func (p *Peer) Err() error {
+	return p.err
+}
@@ -0,0 +1,269 @@
+package peers
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/parihaaraka/ripple/crypto"
+	"github.com/parihaaraka/ripple/data"
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type PeersSuite struct{}
+
+var _ = Suite(&PeersSuite{})
+
+func nodeKey(c *C, seed string) crypto.Key {
+	k, err := crypto.NewECDSAKey([]byte(seed))
+	c.Assert(err, IsNil)
+	return k
+}
+
+type accepted struct {
+	peer *Peer
+	err  error
+}
+
+// fakePeer accepts a single connection with config.
+func fakePeer(c *C, config *Config) (string, chan accepted) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, IsNil)
+	result := make(chan accepted, 1)
+	go func() {
+		defer l.Close()
+		conn, err := l.Accept()
+		if err != nil {
+			result <- accepted{err: err}
+			return
+		}
+		p, err := Accept(context.Background(), conn, config)
+		result <- accepted{p, err}
+	}()
+	return l.Addr().String(), result
+}
+
+func connect(c *C, client, server *Config) (*Peer, *Peer) {
+	address, result := fakePeer(c, server)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	p, err := Dial(ctx, address, client)
+	c.Assert(err, IsNil)
+	a := <-result
+	c.Assert(a.err, IsNil)
+	return p, a.peer
+}
+
+func next(c *C, p *Peer) Message {
+	select {
+	case m, ok := <-p.Incoming:
+		c.Assert(ok, Equals, true, Commentf("%s", p.Err()))
+		return m
+	case <-time.After(5 * time.Second):
+		c.Fatal("No message")
+		return nil
+	}
+}
+
+func (s *PeersSuite) TestHandshake(c *C) {
+	clientKey, serverKey := nodeKey(c, "client"), nodeKey(c, "server")
+	client, server := connect(c, &Config{Key: clientKey, NetworkID: 21338}, &Config{Key: serverKey, NetworkID: 21338})
+	defer client.Close()
+	defer server.Close()
+	c.Check(client.PublicKey.Bytes(), DeepEquals, serverKey.Public(nil))
+	c.Check(server.PublicKey.Bytes(), DeepEquals, clientKey.Public(nil))
+	c.Check(client.Protocol, Equals, "XRPL/2.2")
+	c.Check(server.Protocol, Equals, "XRPL/2.2")
+	c.Check(server.Header.Get("User-Agent"), Equals, defaultUserAgent)
+	c.Check(client.Header.Get("Network-ID"), Equals, "21338")
+
+	// Closing one end ends the other
+	client.Close()
+	_, ok := <-server.Incoming
+	c.Check(ok, Equals, false)
+	c.Check(server.Err(), NotNil)
+	c.Check(client.Err(), IsNil)
+}
+
+func (s *PeersSuite) TestHandshakeRefused(c *C) {
+	key := nodeKey(c, "node")
+	for _, test := range []struct {
+		client, server *Config
+		err            string
+	}{
+		{&Config{Key: key}, &Config{Key: key}, ".*Connected to self"},
+		{&Config{NetworkID: 1}, &Config{}, ".*Peer is on network 0|.*Peer is on network 1"},
+		{&Config{}, &Config{NetworkID: 1}, ".*Peer is on network 1"},
+	} {
+		address, result := fakePeer(c, test.server)
+		_, err := Dial(context.Background(), address, test.client)
+		a := <-result
+		if err == nil {
+			err = a.err
+		}
+		c.Check(err, ErrorMatches, test.err)
+		if a.peer != nil {
+			a.peer.Close()
+		}
+	}
+}
+
+func (s *PeersSuite) TestVerify(c *C) {
+	local, remote := nodeKey(c, "local"), nodeKey(c, "remote")
+	shared := crypto.Sha512Half([]byte("shared"))
+	config := &Config{}
+	header, err := config.headers(remote, shared)
+	c.Assert(err, IsNil)
+	public, err := config.verify(header, local, shared)
+	c.Assert(err, IsNil)
+	c.Check(public.Bytes(), DeepEquals, remote.Public(nil))
+
+	// A signature of another session
+	_, err = config.verify(header, local, crypto.Sha512Half([]byte("other")))
+	c.Check(err, ErrorMatches, "Bad Session-Signature from n.*")
+	header.Set("Session-Signature", "")
+	_, err = config.verify(header, local, shared)
+	c.Check(err, ErrorMatches, "Bad Session-Signature from n.*")
+	header.Set("Public-Key", "rHb9CJAWyB4rj91VRWn96DkukG4bwdtyTh")
+	_, err = config.verify(header, local, shared)
+	c.Check(err, ErrorMatches, "Bad Public-Key: .*")
+}
+
+func signedMessages(c *C) []Message {
+	master, ephemeral := nodeKey(c, "master"), nodeKey(c, "ephemeral")
+	manifest := &data.Manifest{Sequence: 1}
+	c.Assert(data.SignManifest(manifest, master, ephemeral), IsNil)
+	s, err := manifest.Base64()
+	c.Assert(err, IsNil)
+	rawManifest, err := base64.StdEncoding.DecodeString(s)
+	c.Assert(err, IsNil)
+
+	validation := &data.Validation{
+		Flags:          data.VF_FULL_VALIDATION,
+		LedgerHash:     data.Hash256{1},
+		LedgerSequence: 100,
+		SigningTime:    *data.NewRippleTime(1000),
+	}
+	c.Assert(data.Sign(validation, ephemeral, nil), IsNil)
+	_, rawValidation, err := data.Raw(validation)
+	c.Assert(err, IsNil)
+
+	proposal := &data.Proposal{
+		LedgerHash:     data.Hash256{2},
+		PreviousLedger: data.Hash256{1},
+		Sequence:       3,
+		CloseTime:      *data.NewRippleTime(1010),
+	}
+	c.Assert(data.Sign(proposal, ephemeral, nil), IsNil)
+
+	seed, err := data.NewSeedFromAddress("snoPBrXtMeMyMHUVTgbuqAfg1SUTb")
+	c.Assert(err, IsNil)
+	var sequence uint32
+	tx := &data.AccountSet{TxBase: data.TxBase{
+		TransactionType: data.ACCOUNT_SET,
+		Account:         seed.AccountId(data.ECDSA, &sequence),
+		Sequence:        7,
+	}}
+	c.Assert(data.Sign(tx, seed.Key(data.ECDSA), &sequence), IsNil)
+	_, rawTx, err := data.Raw(tx)
+	c.Assert(err, IsNil)
+
+	return []Message{
+		&TMManifests{List: [][]byte{rawManifest}},
+		&TMValidation{Validation: rawValidation},
+		NewTMProposeSet(proposal),
+		&TMTransaction{RawTransaction: rawTx, Status: TS_NEW},
+	}
+}
+
+func (s *PeersSuite) TestDecode(c *C) {
+	messages := signedMessages(c)
+
+	manifests, err := messages[0].(*TMManifests).Manifests()
+	c.Assert(err, IsNil)
+	c.Assert(manifests, HasLen, 1)
+	c.Check(manifests[0].Verify(), IsNil)
+
+	validation, err := messages[1].(*TMValidation).Decode()
+	c.Assert(err, IsNil)
+	c.Check(validation.LedgerSequence, Equals, uint32(100))
+	ok, err := data.CheckSignature(validation)
+	c.Check(err, IsNil)
+	c.Check(ok, Equals, true)
+
+	proposal, err := messages[2].(*TMProposeSet).Proposal()
+	c.Assert(err, IsNil)
+	c.Check(proposal.Sequence, Equals, uint32(3))
+	ok, err = data.CheckSignature(proposal)
+	c.Check(err, IsNil)
+	c.Check(ok, Equals, true)
+	proposal.Sequence++
+	ok, _ = data.CheckSignature(proposal)
+	c.Check(ok, Equals, false)
+
+	tx, err := messages[3].(*TMTransaction).Transaction()
+	c.Assert(err, IsNil)
+	c.Check(tx.GetBase().Sequence, Equals, uint32(7))
+	ok, err = data.CheckSignature(tx)
+	c.Check(err, IsNil)
+	c.Check(ok, Equals, true)
+
+	_, err = (&TMProposeSet{NodePubKey: []byte{2}}).Proposal()
+	c.Check(err, ErrorMatches, "Proposal has bad transaction set hash length: 0")
+}
+
+func (s *PeersSuite) TestMessages(c *C) {
+	client, server := connect(c, nil, nil)
+	defer client.Close()
+	defer server.Close()
+
+	ledgerType := LT_CLOSED
+	depth := uint32(2)
+	messages := append(signedMessages(c),
+		&TMGetLedger{InfoType: LI_AS_NODE, LedgerType: &ledgerType, LedgerSeq: 5, NodeIDs: [][]byte{make([]byte, 33)}, RequestCookie: 9, QueryDepth: &depth},
+		&TMLedgerData{LedgerHash: make([]byte, 32), LedgerSeq: 5, InfoType: LI_BASE, Nodes: []TMLedgerNode{{NodeData: []byte{1, 2}}, {NodeData: []byte{3}, NodeID: []byte{4}}}, RequestCookie: 9},
+		&TMLedgerData{LedgerHash: make([]byte, 32), LedgerSeq: 5, Error: RE_NO_LEDGER},
+		&TMStatusChange{NewStatus: NS_VALIDATING, NewEvent: NE_ACCEPTED_LEDGER, LedgerSeq: 5, LedgerHash: []byte{1}, NetworkTime: 1 << 40},
+		&TMUnknown{MessageType: MT_SQUELCH, Payload: []byte{8, 1}},
+	)
+	for _, m := range messages {
+		c.Assert(server.Send(m), IsNil)
+		c.Check(next(c, client), DeepEquals, m)
+	}
+
+	// Pings are answered with a pong of the same sequence
+	c.Assert(server.Send(&TMPing{Seq: 42, PingTime: 1}), IsNil)
+	c.Check(next(c, client), DeepEquals, &TMPing{Seq: 42, PingTime: 1})
+	c.Check(next(c, server), DeepEquals, &TMPing{PingType: PT_PONG, Seq: 42, PingTime: 1})
+}
+
+func (s *PeersSuite) TestFraming(c *C) {
+	var buf bytes.Buffer
+	c.Assert(WriteMessage(&buf, &TMPing{}), IsNil)
+	// A ping of type PT_PING must still carry the type field
+	c.Check(buf.Bytes(), DeepEquals, []byte{0, 0, 0, 2, 0, 3, 8, 0})
+
+	for _, test := range []struct {
+		frame []byte
+		err   string
+	}{
+		{[]byte{0, 0, 0, 0, 0, 3}, "Bad Ping message: TMPing missing required field 1"},
+		{[]byte{0x90, 0, 0, 2, 0, 3, 8, 0}, "Unsupported message header: 900000020003"},
+		{[]byte{0, 0, 0, 4, 0, 3, 8, 0}, "unexpected EOF"},
+		{[]byte{0, 0, 0, 2, 0, 41, 10, 5}, "Bad Validation message: Bad length of field 1"},
+		{[]byte{0, 0, 0, 2, 0, 41, 11, 0}, "Bad Validation message: Unsupported wire type 3 for field 1"},
+	} {
+		_, err := ReadMessage(bytes.NewReader(test.frame))
+		c.Check(err, ErrorMatches, test.err)
+	}
+
+	// Unknown fields are skipped
+	m, err := ReadMessage(bytes.NewReader([]byte{0, 0, 0, 11, 0, 3, 8, 1, 0x29, 1, 2, 3, 4, 5, 6, 7, 8}))
+	c.Assert(err, IsNil)
+	c.Check(m, DeepEquals, &TMPing{PingType: PT_PONG})
+}
@@ -0,0 +1,132 @@
+package peers
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// A minimal protocol buffers (proto2) wire format codec, covering the
+// scalar and length delimited fields used by the rippled peer messages.
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+type encoder []byte
+
+func (e *encoder) varint(v uint64) {
+	*e = binary.AppendUvarint(*e, v)
+}
+
+func (e *encoder) tag(field, wire int) {
+	e.varint(uint64(field<<3 | wire))
+}
+
+func (e *encoder) uint(field int, v uint64) {
+	e.tag(field, wireVarint)
+	e.varint(v)
+}
+
+func (e *encoder) bool(field int, v bool) {
+	if v {
+		e.uint(field, 1)
+	} else {
+		e.uint(field, 0)
+	}
+}
+
+func (e *encoder) bytes(field int, b []byte) {
+	e.tag(field, wireBytes)
+	e.varint(uint64(len(b)))
+	*e = append(*e, b...)
+}
+
+// Optional fields are only written when they differ from their default.
+
+func (e *encoder) optionalUint(field int, v uint64) {
+	if v != 0 {
+		e.uint(field, v)
+	}
+}
+
+func (e *encoder) optionalBool(field int, v bool) {
+	if v {
+		e.bool(field, v)
+	}
+}
+
+func (e *encoder) optionalBytes(field int, b []byte) {
+	if len(b) > 0 {
+		e.bytes(field, b)
+	}
+}
+
+type field struct {
+	number int
+	wire   int
+	value  uint64 // varint and fixed fields
+	data   []byte // length delimited fields
+}
+
+func (f *field) uint32() uint32 { return uint32(f.value) }
+func (f *field) bool() bool     { return f.value != 0 }
+
+func (f *field) bytes() []byte {
+	return append([]byte(nil), f.data...)
+}
+
+// decode calls fn with each field of a message in turn.
+// Fields unknown to fn should be ignored, as protobuf requires.
+func decode(b []byte, fn func(*field) error) error {
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return fmt.Errorf("Bad field key")
+		}
+		b = b[n:]
+		f := field{number: int(key >> 3), wire: int(key & 7)}
+		switch f.wire {
+		case wireVarint:
+			if f.value, n = binary.Uvarint(b); n <= 0 {
+				return fmt.Errorf("Bad varint in field %d", f.number)
+			}
+		case wireFixed64:
+			if n = 8; len(b) < n {
+				return fmt.Errorf("Short fixed64 field %d", f.number)
+			}
+			f.value = binary.LittleEndian.Uint64(b)
+		case wireFixed32:
+			if n = 4; len(b) < n {
+				return fmt.Errorf("Short fixed32 field %d", f.number)
+			}
+			f.value = uint64(binary.LittleEndian.Uint32(b))
+		case wireBytes:
+			length, m := binary.Uvarint(b)
+			if m <= 0 || length > uint64(len(b)-m) {
+				return fmt.Errorf("Bad length of field %d", f.number)
+			}
+			f.data = b[m : m+int(length)]
+			n = m + int(length)
+		default:
+			return fmt.Errorf("Unsupported wire type %d for field %d", f.wire, f.number)
+		}
+		b = b[n:]
+		if err := fn(&f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// required checks that all the required fields of a message were present.
+func required(name string, seen map[int]bool, fields ...int) error {
+	for _, f := range fields {
+		if !seen[f] {
+			return fmt.Errorf("%s missing required field %d", name, f)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package peers
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/parihaaraka/ripple/crypto"
+)
+
+// rippled binds a peer's identity to the TLS session by signing a value
+// derived from the Finished messages of both sides, which crypto/tls does
+// not expose. Instead the plaintext handshake messages are recorded as
+// they pass through the connection, and the Finished messages recomputed
+// from them and the master secret, which is only ever held in memory.
+// This limits the protocol to TLS 1.2, which rippled supports, and to
+// full handshakes, since session resumption is disabled.
+
+// The AEAD suites with ECDHE, whose PRF hash is given by their name.
+var cipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+}
+
+const (
+	recordHeaderSize       = 5
+	recordChangeCipherSpec = 20
+	recordHandshake        = 22
+	handshakeFinished      = 20
+	finishedSize           = 12
+)
+
+// direction parses the TLS records flowing one way until they are
+// encrypted.
+type direction struct {
+	buf       []byte
+	encrypted bool
+}
+
+type transcript struct {
+	sync.Mutex
+	client       bool
+	in, out      direction
+	clientCipher bool   // the client's ChangeCipherSpec has been seen
+	before       []byte // handshake messages before the client's Finished
+	after        []byte // and those sent by the server after it
+	masterSecret []byte
+}
+
+func (t *transcript) config(cert *tls.Certificate) *tls.Config {
+	config := &tls.Config{
+		MinVersion:             tls.VersionTLS12,
+		MaxVersion:             tls.VersionTLS12,
+		CipherSuites:           cipherSuites,
+		SessionTicketsDisabled: true,
+		// Peers identify themselves with their node keys, not certificates
+		InsecureSkipVerify: true,
+		KeyLogWriter:       t,
+	}
+	if cert != nil {
+		config.Certificates = []tls.Certificate{*cert}
+	}
+	return config
+}
+
+// Write receives the NSS key log line of the connection.
+func (t *transcript) Write(b []byte) (int, error) {
+	fields := strings.Fields(string(b))
+	if len(fields) == 3 && fields[0] == "CLIENT_RANDOM" {
+		secret, err := hex.DecodeString(fields[2])
+		if err != nil {
+			return 0, err
+		}
+		t.Lock()
+		t.masterSecret = secret
+		t.Unlock()
+	}
+	return len(b), nil
+}
+
+func (t *transcript) record(outgoing bool, b []byte) {
+	t.Lock()
+	defer t.Unlock()
+	d := &t.in
+	if outgoing {
+		d = &t.out
+	}
+	if d.encrypted {
+		return
+	}
+	d.buf = append(d.buf, b...)
+	for !d.encrypted && len(d.buf) >= recordHeaderSize {
+		length := int(binary.BigEndian.Uint16(d.buf[3:]))
+		if len(d.buf) < recordHeaderSize+length {
+			return
+		}
+		payload := d.buf[recordHeaderSize : recordHeaderSize+length]
+		switch d.buf[0] {
+		case recordChangeCipherSpec:
+			d.encrypted = true
+			if outgoing == t.client {
+				t.clientCipher = true
+			}
+		case recordHandshake:
+			if t.clientCipher {
+				t.after = append(t.after, payload...)
+			} else {
+				t.before = append(t.before, payload...)
+			}
+		}
+		d.buf = d.buf[recordHeaderSize+length:]
+	}
+	if d.encrypted {
+		d.buf = nil
+	}
+}
+
+// finished recomputes the verify data of both Finished messages.
+func (t *transcript) finished(state tls.ConnectionState) ([]byte, []byte, error) {
+	t.Lock()
+	defer t.Unlock()
+	switch {
+	case state.Version != tls.VersionTLS12:
+		return nil, nil, fmt.Errorf("Unsupported TLS version: %04X", state.Version)
+	case state.DidResume:
+		return nil, nil, fmt.Errorf("Resumed TLS session")
+	case t.masterSecret == nil:
+		return nil, nil, fmt.Errorf("No TLS master secret")
+	}
+	newHash := sha256.New
+	if strings.HasSuffix(tls.CipherSuiteName(state.CipherSuite), "SHA384") {
+		newHash = sha512.New384
+	}
+	h := newHash()
+	h.Write(t.before)
+	client := prf(newHash, t.masterSecret, "client finished", h.Sum(nil))
+	// The client's Finished comes first in a full handshake
+	if !bytes.Equal(client, state.TLSUnique) {
+		return nil, nil, fmt.Errorf("TLS transcript does not match")
+	}
+	h.Write([]byte{handshakeFinished, 0, 0, finishedSize})
+	h.Write(client)
+	h.Write(t.after)
+	server := prf(newHash, t.masterSecret, "server finished", h.Sum(nil))
+	return client, server, nil
+}
+
+// sharedValue is the value whose signature proves a node key holder is
+// at the other end of the TLS session.
+func (t *transcript) sharedValue(state tls.ConnectionState) ([]byte, error) {
+	client, server, err := t.finished(state)
+	if err != nil {
+		return nil, err
+	}
+	cookie1, cookie2 := crypto.Sha512(client), crypto.Sha512(server)
+	for i := range cookie1 {
+		cookie1[i] ^= cookie2[i]
+	}
+	return crypto.Sha512Half(cookie1), nil
+}
+
+// prf is the TLS 1.2 pseudorandom function, producing the 12 bytes of a
+// Finished message.
+func prf(newHash func() hash.Hash, secret []byte, label string, seed []byte) []byte {
+	seed = append([]byte(label), seed...)
+	mac := hmac.New(newHash, secret)
+	mac.Write(seed)
+	a := mac.Sum(nil)
+	mac.Reset()
+	mac.Write(a)
+	mac.Write(seed)
+	return mac.Sum(nil)[:finishedSize]
+}
+
+// recordingConn passes the bytes read and written to the transcript.
+type recordingConn struct {
+	net.Conn
+	transcript *transcript
+}
+
+func (c *recordingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.transcript.record(false, b[:n])
+	return n, err
+}
+
+func (c *recordingConn) Write(b []byte) (int, error) {
+	c.transcript.record(true, b)
+	return c.Conn.Write(b)
+}
+
+// newCertificate returns the throwaway self signed certificate with
+// which a node accepts connections, as rippled does.
+func newCertificate() (*tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "ripple"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(365 * 24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+	}, nil
+}
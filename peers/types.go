@@ -0,0 +1,623 @@
+package peers
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/parihaaraka/ripple/data"
+)
+
+// The messages below mirror those of the same name in rippled's
+// xrpl.proto, keeping the raw serialized objects alongside helpers
+// which decode them.
+
+type PingType uint32
+
+const (
+	PT_PING PingType = 0
+	PT_PONG PingType = 1
+)
+
+type TransactionStatus uint32
+
+const (
+	TS_NEW             TransactionStatus = 1
+	TS_CURRENT         TransactionStatus = 2
+	TS_COMMITED        TransactionStatus = 3
+	TS_REJECT_CONFLICT TransactionStatus = 4
+	TS_REJECT_INVALID  TransactionStatus = 5
+	TS_REJECT_FUNDS    TransactionStatus = 6
+	TS_HELD_SEQ        TransactionStatus = 7
+	TS_HELD_LEDGER     TransactionStatus = 8
+)
+
+type LedgerInfoType uint32
+
+const (
+	LI_BASE         LedgerInfoType = 0
+	LI_TX_NODE      LedgerInfoType = 1
+	LI_AS_NODE      LedgerInfoType = 2
+	LI_TS_CANDIDATE LedgerInfoType = 3
+)
+
+var ledgerInfoTypes = map[LedgerInfoType]string{
+	LI_BASE:         "Base",
+	LI_TX_NODE:      "Transaction Node",
+	LI_AS_NODE:      "Account State Node",
+	LI_TS_CANDIDATE: "Transaction Set Candidate",
+}
+
+func (t LedgerInfoType) String() string {
+	if name, ok := ledgerInfoTypes[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("Unknown(%d)", uint32(t))
+}
+
+type LedgerType uint32
+
+const (
+	LT_ACCEPTED LedgerType = 0
+	LT_CURRENT  LedgerType = 1
+	LT_CLOSED   LedgerType = 2
+)
+
+type ReplyError uint32
+
+const (
+	RE_NO_LEDGER   ReplyError = 1
+	RE_NO_NODE     ReplyError = 2
+	RE_BAD_REQUEST ReplyError = 3
+)
+
+type NodeStatus uint32
+
+const (
+	NS_CONNECTING NodeStatus = 1
+	NS_CONNECTED  NodeStatus = 2
+	NS_MONITORING NodeStatus = 3
+	NS_VALIDATING NodeStatus = 4
+	NS_SHUTTING   NodeStatus = 5
+)
+
+type NodeEvent uint32
+
+const (
+	NE_CLOSING_LEDGER  NodeEvent = 1
+	NE_ACCEPTED_LEDGER NodeEvent = 2
+	NE_SWITCHED_LEDGER NodeEvent = 3
+	NE_LOST_SYNC       NodeEvent = 4
+)
+
+// TMPing is answered by a TMPing of type PT_PONG with the same Seq.
+type TMPing struct {
+	PingType PingType
+	Seq      uint32
+	PingTime uint64
+	NetTime  uint64
+}
+
+func (m *TMPing) Type() MessageType { return MT_PING }
+
+func (m *TMPing) String() string {
+	if m.PingType == PT_PONG {
+		return fmt.Sprintf("Pong: %d", m.Seq)
+	}
+	return fmt.Sprintf("Ping: %d", m.Seq)
+}
+
+func (m *TMPing) marshal() []byte {
+	var e encoder
+	e.uint(1, uint64(m.PingType))
+	e.optionalUint(2, uint64(m.Seq))
+	e.optionalUint(3, m.PingTime)
+	e.optionalUint(4, m.NetTime)
+	return e
+}
+
+func (m *TMPing) unmarshal(b []byte) error {
+	seen := make(map[int]bool)
+	if err := decode(b, func(f *field) error {
+		seen[f.number] = true
+		switch f.number {
+		case 1:
+			m.PingType = PingType(f.value)
+		case 2:
+			m.Seq = f.uint32()
+		case 3:
+			m.PingTime = f.value
+		case 4:
+			m.NetTime = f.value
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	return required("TMPing", seen, 1)
+}
+
+// TMManifests carries serialized validator manifests.
+type TMManifests struct {
+	List    [][]byte
+	History bool
+}
+
+func (m *TMManifests) Type() MessageType { return MT_MANIFESTS }
+
+func (m *TMManifests) String() string {
+	return fmt.Sprintf("Manifests: %d", len(m.List))
+}
+
+func (m *TMManifests) marshal() []byte {
+	var e encoder
+	for _, stobject := range m.List {
+		var manifest encoder
+		manifest.bytes(1, stobject)
+		e.bytes(1, manifest)
+	}
+	e.optionalBool(2, m.History)
+	return e
+}
+
+func (m *TMManifests) unmarshal(b []byte) error {
+	return decode(b, func(f *field) error {
+		switch f.number {
+		case 1:
+			seen := make(map[int]bool)
+			if err := decode(f.data, func(f *field) error {
+				seen[f.number] = true
+				if f.number == 1 {
+					m.List = append(m.List, f.bytes())
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+			return required("TMManifest", seen, 1)
+		case 2:
+			m.History = f.bool()
+		}
+		return nil
+	})
+}
+
+// Manifests decodes each of the serialized manifests.
+func (m *TMManifests) Manifests() ([]*data.Manifest, error) {
+	manifests := make([]*data.Manifest, len(m.List))
+	for i, stobject := range m.List {
+		manifest, err := data.ReadManifest(bytes.NewReader(stobject))
+		if err != nil {
+			return nil, err
+		}
+		manifests[i] = manifest
+	}
+	return manifests, nil
+}
+
+// TMTransaction relays a serialized signed transaction.
+type TMTransaction struct {
+	RawTransaction   []byte
+	Status           TransactionStatus
+	ReceiveTimestamp uint64
+	Deferred         bool
+}
+
+func (m *TMTransaction) Type() MessageType { return MT_TRANSACTION }
+
+func (m *TMTransaction) String() string {
+	return fmt.Sprintf("Transaction: %d bytes status %d", len(m.RawTransaction), m.Status)
+}
+
+func (m *TMTransaction) marshal() []byte {
+	var e encoder
+	e.bytes(1, m.RawTransaction)
+	e.uint(2, uint64(m.Status))
+	e.optionalUint(3, m.ReceiveTimestamp)
+	e.optionalBool(4, m.Deferred)
+	return e
+}
+
+func (m *TMTransaction) unmarshal(b []byte) error {
+	seen := make(map[int]bool)
+	if err := decode(b, func(f *field) error {
+		seen[f.number] = true
+		switch f.number {
+		case 1:
+			m.RawTransaction = f.bytes()
+		case 2:
+			m.Status = TransactionStatus(f.value)
+		case 3:
+			m.ReceiveTimestamp = f.value
+		case 4:
+			m.Deferred = f.bool()
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	return required("TMTransaction", seen, 1, 2)
+}
+
+// Transaction decodes the relayed transaction.
+func (m *TMTransaction) Transaction() (data.Transaction, error) {
+	return data.ReadTransaction(bytes.NewReader(m.RawTransaction))
+}
+
+// TMGetLedger requests the header (LI_BASE) or tree nodes of a ledger,
+// identified by LedgerHash, LedgerSeq or LedgerType. NodeIDs are the
+// 33 byte SHAMap node ids, the 32 byte path followed by the depth.
+type TMGetLedger struct {
+	InfoType      LedgerInfoType
+	LedgerType    *LedgerType
+	LedgerHash    []byte
+	LedgerSeq     uint32
+	NodeIDs       [][]byte
+	RequestCookie uint64
+	QueryType     *uint32
+	QueryDepth    *uint32
+}
+
+func (m *TMGetLedger) Type() MessageType { return MT_GET_LEDGER }
+
+func (m *TMGetLedger) String() string {
+	return fmt.Sprintf("GetLedger: %s %d %X %d nodes", m.InfoType, m.LedgerSeq, m.LedgerHash, len(m.NodeIDs))
+}
+
+func (m *TMGetLedger) marshal() []byte {
+	var e encoder
+	e.uint(1, uint64(m.InfoType))
+	if m.LedgerType != nil {
+		e.uint(2, uint64(*m.LedgerType))
+	}
+	e.optionalBytes(3, m.LedgerHash)
+	e.optionalUint(4, uint64(m.LedgerSeq))
+	for _, id := range m.NodeIDs {
+		e.bytes(5, id)
+	}
+	e.optionalUint(6, m.RequestCookie)
+	if m.QueryType != nil {
+		e.uint(7, uint64(*m.QueryType))
+	}
+	if m.QueryDepth != nil {
+		e.uint(8, uint64(*m.QueryDepth))
+	}
+	return e
+}
+
+func (m *TMGetLedger) unmarshal(b []byte) error {
+	seen := make(map[int]bool)
+	if err := decode(b, func(f *field) error {
+		seen[f.number] = true
+		switch f.number {
+		case 1:
+			m.InfoType = LedgerInfoType(f.value)
+		case 2:
+			t := LedgerType(f.value)
+			m.LedgerType = &t
+		case 3:
+			m.LedgerHash = f.bytes()
+		case 4:
+			m.LedgerSeq = f.uint32()
+		case 5:
+			m.NodeIDs = append(m.NodeIDs, f.bytes())
+		case 6:
+			m.RequestCookie = f.value
+		case 7:
+			v := f.uint32()
+			m.QueryType = &v
+		case 8:
+			v := f.uint32()
+			m.QueryDepth = &v
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	return required("TMGetLedger", seen, 1)
+}
+
+type TMLedgerNode struct {
+	NodeData []byte
+	NodeID   []byte
+}
+
+// TMLedgerData answers a TMGetLedger. For LI_BASE the first node is the
+// ledger header, followed by the state and transaction tree roots.
+type TMLedgerData struct {
+	LedgerHash    []byte
+	LedgerSeq     uint32
+	InfoType      LedgerInfoType
+	Nodes         []TMLedgerNode
+	RequestCookie uint32
+	Error         ReplyError
+}
+
+func (m *TMLedgerData) Type() MessageType { return MT_LEDGER_DATA }
+
+func (m *TMLedgerData) String() string {
+	if m.Error != 0 {
+		return fmt.Sprintf("LedgerData: %s %d %X error %d", m.InfoType, m.LedgerSeq, m.LedgerHash, m.Error)
+	}
+	return fmt.Sprintf("LedgerData: %s %d %X %d nodes", m.InfoType, m.LedgerSeq, m.LedgerHash, len(m.Nodes))
+}
+
+func (m *TMLedgerData) marshal() []byte {
+	var e encoder
+	e.bytes(1, m.LedgerHash)
+	e.uint(2, uint64(m.LedgerSeq))
+	e.uint(3, uint64(m.InfoType))
+	for _, node := range m.Nodes {
+		var n encoder
+		n.bytes(1, node.NodeData)
+		n.optionalBytes(2, node.NodeID)
+		e.bytes(4, n)
+	}
+	e.optionalUint(5, uint64(m.RequestCookie))
+	e.optionalUint(6, uint64(m.Error))
+	return e
+}
+
+func (m *TMLedgerData) unmarshal(b []byte) error {
+	seen := make(map[int]bool)
+	if err := decode(b, func(f *field) error {
+		seen[f.number] = true
+		switch f.number {
+		case 1:
+			m.LedgerHash = f.bytes()
+		case 2:
+			m.LedgerSeq = f.uint32()
+		case 3:
+			m.InfoType = LedgerInfoType(f.value)
+		case 4:
+			var node TMLedgerNode
+			nodeSeen := make(map[int]bool)
+			if err := decode(f.data, func(f *field) error {
+				nodeSeen[f.number] = true
+				switch f.number {
+				case 1:
+					node.NodeData = f.bytes()
+				case 2:
+					node.NodeID = f.bytes()
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+			m.Nodes = append(m.Nodes, node)
+			return required("TMLedgerNode", nodeSeen, 1)
+		case 5:
+			m.RequestCookie = f.uint32()
+		case 6:
+			m.Error = ReplyError(f.value)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	return required("TMLedgerData", seen, 1, 2, 3)
+}
+
+// TMProposeSet is a validator's proposed transaction set for the next
+// ledger during a consensus round.
+type TMProposeSet struct {
+	ProposeSeq          uint32
+	CurrentTxHash       []byte
+	NodePubKey          []byte
+	CloseTime           uint32
+	Signature           []byte
+	PreviousLedger      []byte
+	AddedTransactions   [][]byte
+	RemovedTransactions [][]byte
+	CheckedSignature    bool
+	Hops                uint32
+}
+
+func (m *TMProposeSet) Type() MessageType { return MT_PROPOSE_LEDGER }
+
+func (m *TMProposeSet) String() string {
+	return fmt.Sprintf("ProposeSet: %d %X", m.ProposeSeq, m.CurrentTxHash)
+}
+
+func (m *TMProposeSet) marshal() []byte {
+	var e encoder
+	e.uint(1, uint64(m.ProposeSeq))
+	e.bytes(2, m.CurrentTxHash)
+	e.bytes(3, m.NodePubKey)
+	e.uint(4, uint64(m.CloseTime))
+	e.bytes(5, m.Signature)
+	e.bytes(6, m.PreviousLedger)
+	e.optionalBool(7, m.CheckedSignature)
+	for _, tx := range m.AddedTransactions {
+		e.bytes(10, tx)
+	}
+	for _, tx := range m.RemovedTransactions {
+		e.bytes(11, tx)
+	}
+	e.optionalUint(12, uint64(m.Hops))
+	return e
+}
+
+func (m *TMProposeSet) unmarshal(b []byte) error {
+	seen := make(map[int]bool)
+	if err := decode(b, func(f *field) error {
+		seen[f.number] = true
+		switch f.number {
+		case 1:
+			m.ProposeSeq = f.uint32()
+		case 2:
+			m.CurrentTxHash = f.bytes()
+		case 3:
+			m.NodePubKey = f.bytes()
+		case 4:
+			m.CloseTime = f.uint32()
+		case 5:
+			m.Signature = f.bytes()
+		case 6:
+			m.PreviousLedger = f.bytes()
+		case 7:
+			m.CheckedSignature = f.bool()
+		case 10:
+			m.AddedTransactions = append(m.AddedTransactions, f.bytes())
+		case 11:
+			m.RemovedTransactions = append(m.RemovedTransactions, f.bytes())
+		case 12:
+			m.Hops = f.uint32()
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	return required("TMProposeSet", seen, 1, 2, 3, 4, 5, 6)
+}
+
+// NewTMProposeSet returns the message relaying a signed proposal.
+func NewTMProposeSet(p *data.Proposal) *TMProposeSet {
+	return &TMProposeSet{
+		ProposeSeq:     p.Sequence,
+		CurrentTxHash:  p.LedgerHash.Bytes(),
+		NodePubKey:     p.PublicKey.Bytes(),
+		CloseTime:      p.CloseTime.Uint32(),
+		Signature:      p.Signature.Bytes(),
+		PreviousLedger: p.PreviousLedger.Bytes(),
+	}
+}
+
+// Proposal returns the proposal, whose signature may then be checked
+// with data.CheckSignature.
+func (m *TMProposeSet) Proposal() (*data.Proposal, error) {
+	p := &data.Proposal{
+		Sequence:  m.ProposeSeq,
+		CloseTime: *data.NewRippleTime(m.CloseTime),
+		Signature: data.VariableLength(append([]byte(nil), m.Signature...)),
+	}
+	for _, v := range []struct {
+		name  string
+		dst   []byte
+		value []byte
+	}{
+		{"transaction set hash", p.LedgerHash[:], m.CurrentTxHash},
+		{"previous ledger", p.PreviousLedger[:], m.PreviousLedger},
+		{"public key", p.PublicKey[:], m.NodePubKey},
+	} {
+		if len(v.value) != len(v.dst) {
+			return nil, fmt.Errorf("Proposal has bad %s length: %d", v.name, len(v.value))
+		}
+		copy(v.dst, v.value)
+	}
+	return p, nil
+}
+
+// TMValidation relays a serialized validation.
+type TMValidation struct {
+	Validation       []byte
+	CheckedSignature bool
+	Hops             uint32
+}
+
+func (m *TMValidation) Type() MessageType { return MT_VALIDATION }
+
+func (m *TMValidation) String() string {
+	return fmt.Sprintf("Validation: %d bytes", len(m.Validation))
+}
+
+func (m *TMValidation) marshal() []byte {
+	var e encoder
+	e.bytes(1, m.Validation)
+	e.optionalBool(2, m.CheckedSignature)
+	e.optionalUint(3, uint64(m.Hops))
+	return e
+}
+
+func (m *TMValidation) unmarshal(b []byte) error {
+	seen := make(map[int]bool)
+	if err := decode(b, func(f *field) error {
+		seen[f.number] = true
+		switch f.number {
+		case 1:
+			m.Validation = f.bytes()
+		case 2:
+			m.CheckedSignature = f.bool()
+		case 3:
+			m.Hops = f.uint32()
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	return required("TMValidation", seen, 1)
+}
+
+// Decode returns the relayed validation.
+func (m *TMValidation) Decode() (*data.Validation, error) {
+	return data.ReadValidation(bytes.NewReader(m.Validation))
+}
+
+// TMStatusChange announces a change in a peer's state or ledger.
+type TMStatusChange struct {
+	NewStatus          NodeStatus
+	NewEvent           NodeEvent
+	LedgerSeq          uint32
+	LedgerHash         []byte
+	LedgerHashPrevious []byte
+	NetworkTime        uint64
+	FirstSeq           uint32
+	LastSeq            uint32
+}
+
+func (m *TMStatusChange) Type() MessageType { return MT_STATUS_CHANGE }
+
+func (m *TMStatusChange) String() string {
+	return fmt.Sprintf("StatusChange: %d %d %d %X", m.NewStatus, m.NewEvent, m.LedgerSeq, m.LedgerHash)
+}
+
+func (m *TMStatusChange) marshal() []byte {
+	var e encoder
+	e.optionalUint(1, uint64(m.NewStatus))
+	e.optionalUint(2, uint64(m.NewEvent))
+	e.optionalUint(3, uint64(m.LedgerSeq))
+	e.optionalBytes(4, m.LedgerHash)
+	e.optionalBytes(5, m.LedgerHashPrevious)
+	e.optionalUint(6, m.NetworkTime)
+	e.optionalUint(7, uint64(m.FirstSeq))
+	e.optionalUint(8, uint64(m.LastSeq))
+	return e
+}
+
+func (m *TMStatusChange) unmarshal(b []byte) error {
+	return decode(b, func(f *field) error {
+		switch f.number {
+		case 1:
+			m.NewStatus = NodeStatus(f.value)
+		case 2:
+			m.NewEvent = NodeEvent(f.value)
+		case 3:
+			m.LedgerSeq = f.uint32()
+		case 4:
+			m.LedgerHash = f.bytes()
+		case 5:
+			m.LedgerHashPrevious = f.bytes()
+		case 6:
+			m.NetworkTime = f.value
+		case 7:
+			m.FirstSeq = f.uint32()
+		case 8:
+			m.LastSeq = f.uint32()
+		}
+		return nil
+	})
+}
+
+// TMUnknown holds the payload of a message type which is not decoded.
+type TMUnknown struct {
+	MessageType MessageType
+	Payload     []byte
+}
+
+func (m *TMUnknown) Type() MessageType { return m.MessageType }
+
+func (m *TMUnknown) String() string {
+	return fmt.Sprintf("%s: %d bytes", m.MessageType, len(m.Payload))
+}
+
+func (m *TMUnknown) marshal() []byte          { return m.Payload }
+func (m *TMUnknown) unmarshal(b []byte) error { m.Payload = b; return nil }
@@ -0,0 +1,280 @@
+package shamap
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"github.com/parihaaraka/ripple/data"
+)
+
+// Proof shows that an item is held by a tree with a known root hash,
+// without the rest of the tree. It holds the item, as it is hashed, and
+// the children of each inner node on the path from its leaf to the root.
+type Proof struct {
+	Key  data.Hash256
+	Type data.NodeType // NT_ACCOUNT_NODE or NT_TRANSACTION_NODE
+	Item []byte
+	Path [][16]data.Hash256 // From the parent of the leaf up to the root
+}
+
+func prefix(typ data.NodeType) (data.HashPrefix, error) {
+	switch typ {
+	case data.NT_ACCOUNT_NODE:
+		return data.HP_LEAF_NODE, nil
+	case data.NT_TRANSACTION_NODE:
+		return data.HP_TRANSACTION_NODE, nil
+	default:
+		return 0, fmt.Errorf("Unknown proof type: %d", typ)
+	}
+}
+
+func innerHash(children *[16]data.Hash256) data.Hash256 {
+	var h data.Hash256
+	hasher := sha512.New()
+	binary.Write(hasher, binary.BigEndian, data.HP_INNER_NODE)
+	empty := true
+	for i := range children {
+		hasher.Write(children[i][:])
+		empty = empty && children[i].IsZero()
+	}
+	if !empty {
+		copy(h[:], hasher.Sum(nil))
+	}
+	return h
+}
+
+// Prove returns the proof that the item at key is in the tree.
+func (m *SHAMap) Prove(key data.Hash256) (*Proof, error) {
+	var (
+		path []*inner
+		n    = &m.root
+	)
+	m.Hash()
+	for depth := 0; ; depth++ {
+		path = append(path, n)
+		switch child := n.children[branch(key, depth)].(type) {
+		case *inner:
+			n = child
+			continue
+		case *leaf:
+			if child.key == key {
+				return newProof(child, path)
+			}
+		}
+		return nil, fmt.Errorf("No item at %s", key)
+	}
+}
+
+func newProof(l *leaf, path []*inner) (*Proof, error) {
+	_, item, err := data.Raw(l.item)
+	if err != nil {
+		return nil, err
+	}
+	p := &Proof{Key: l.key, Item: item}
+	switch l.item.Prefix() {
+	case data.HP_LEAF_NODE:
+		p.Type = data.NT_ACCOUNT_NODE
+	case data.HP_TRANSACTION_NODE:
+		p.Type = data.NT_TRANSACTION_NODE
+	default:
+		return nil, fmt.Errorf("Cannot prove %s", l.item.GetType())
+	}
+	for i := len(path) - 1; i >= 0; i-- {
+		var children [16]data.Hash256
+		for j, child := range path[i].children {
+			if child != nil {
+				children[j] = child.hash()
+			}
+		}
+		p.Path = append(p.Path, children)
+	}
+	return p, nil
+}
+
+// Root returns the root hash of the tree which holds the item, after
+// checking that the item is at the key and each hash on the path is
+// held by the node above it.
+func (p *Proof) Root() (data.Hash256, error) {
+	root, err := p.leafHash()
+	if err != nil {
+		return root, err
+	}
+	// Entries and transactions are both followed by their key
+	if len(p.Item) < len(p.Key) || !bytes.Equal(p.Item[len(p.Item)-len(p.Key):], p.Key[:]) {
+		return root, fmt.Errorf("Proof item is not at %s", p.Key)
+	}
+	if len(p.Path) == 0 || len(p.Path) > len(p.Key)*2 {
+		return root, fmt.Errorf("Bad proof length: %d", len(p.Path))
+	}
+	for i := range p.Path {
+		depth := len(p.Path) - 1 - i
+		if p.Path[i][branch(p.Key, depth)] != root {
+			return root, fmt.Errorf("Proof broken at depth %d", depth)
+		}
+		root = innerHash(&p.Path[i])
+	}
+	return root, nil
+}
+
+// Verify checks the proof against the state or transaction
+// hash of a ledger header.
+func (p *Proof) Verify(header *data.LedgerHeader) error {
+	root, err := p.Root()
+	if err != nil {
+		return err
+	}
+	expected := header.StateHash
+	if p.Type == data.NT_TRANSACTION_NODE {
+		expected = header.TransactionHash
+	}
+	if root != expected {
+		return fmt.Errorf("Proof root: %s expected: %s", root, expected)
+	}
+	return nil
+}
+
+// Value decodes the item, which is a LedgerEntry or a
+// TransactionWithMetaData.
+func (p *Proof) Value() (data.Hashable, error) {
+	prefix, err := prefix(p.Type)
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	binary.Write(&b, binary.BigEndian, prefix)
+	b.Write(p.Item)
+	return data.ReadWire(bytes.NewReader(b.Bytes()), p.Type, 0, p.Key)
+}
+
+func (p Proof) String() string {
+	return fmt.Sprintf("%s Proof: %s depth: %d", nodeTypeName(p.Type), p.Key, len(p.Path))
+}
+
+func nodeTypeName(typ data.NodeType) string {
+	if typ == data.NT_TRANSACTION_NODE {
+		return "Transaction"
+	}
+	return "State"
+}
+
+// MarshalBinary encodes the proof compactly. Each inner node is a mask of
+// the branches other than the one on the path which are not empty,
+// followed by their hashes. The hash on the path is recomputed.
+//
+//	type:1 key:32 length:4 item:length depth:1 (mask:2 hashes:32*n)*depth
+func (p *Proof) MarshalBinary() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte(byte(p.Type))
+	b.Write(p.Key[:])
+	binary.Write(&b, binary.BigEndian, uint32(len(p.Item)))
+	b.Write(p.Item)
+	b.WriteByte(byte(len(p.Path)))
+	for i := range p.Path {
+		onPath := branch(p.Key, len(p.Path)-1-i)
+		var mask uint16
+		for j, h := range p.Path[i] {
+			if j != onPath && !h.IsZero() {
+				mask |= 1 << uint(j)
+			}
+		}
+		binary.Write(&b, binary.BigEndian, mask)
+		for j, h := range p.Path[i] {
+			if mask&(1<<uint(j)) != 0 {
+				b.Write(h[:])
+			}
+		}
+	}
+	return b.Bytes(), nil
+}
+
+func (p *Proof) UnmarshalBinary(b []byte) error {
+	r := bytes.NewReader(b)
+	var (
+		typ    uint8
+		length uint32
+		depth  uint8
+	)
+	if err := binary.Read(r, binary.BigEndian, &typ); err != nil {
+		return err
+	}
+	p.Type = data.NodeType(typ)
+	if _, err := io.ReadFull(r, p.Key[:]); err != nil {
+		return err
+	}
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return err
+	}
+	if int64(length) > int64(r.Len()) {
+		return fmt.Errorf("Bad proof item length: %d", length)
+	}
+	p.Item = make([]byte, length)
+	if _, err := io.ReadFull(r, p.Item); err != nil {
+		return err
+	}
+	if err := binary.Read(r, binary.BigEndian, &depth); err != nil {
+		return err
+	}
+	if int(depth) > len(p.Key)*2 {
+		return fmt.Errorf("Bad proof length: %d", depth)
+	}
+	p.Path = make([][16]data.Hash256, depth)
+	for i := range p.Path {
+		var mask uint16
+		if err := binary.Read(r, binary.BigEndian, &mask); err != nil {
+			return err
+		}
+		for j := range p.Path[i] {
+			if mask&(1<<uint(j)) != 0 {
+				if _, err := io.ReadFull(r, p.Path[i][j][:]); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if r.Len() > 0 {
+		return fmt.Errorf("Proof followed by %d bytes", r.Len())
+	}
+	// Fill in the hashes on the path from the bottom up
+	below, err := p.leafHash()
+	if err != nil {
+		return err
+	}
+	for i := range p.Path {
+		p.Path[i][branch(p.Key, int(depth)-1-i)] = below
+		below = innerHash(&p.Path[i])
+	}
+	return nil
+}
+
+func (p *Proof) leafHash() (data.Hash256, error) {
+	var h data.Hash256
+	prefix, err := prefix(p.Type)
+	if err != nil {
+		return h, err
+	}
+	hasher := sha512.New()
+	binary.Write(hasher, binary.BigEndian, prefix)
+	hasher.Write(p.Item)
+	copy(h[:], hasher.Sum(nil))
+	return h, nil
+}
+
+func (p *Proof) MarshalText() ([]byte, error) {
+	b, err := p.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf("%X", b)), nil
+}
+
+func (p *Proof) UnmarshalText(b []byte) error {
+	raw, err := hex.DecodeString(string(bytes.TrimSpace(b)))
+	if err != nil {
+		return err
+	}
+	return p.UnmarshalBinary(raw)
+}
@@ -0,0 +1,276 @@
+// Package shamap implements the radix trees of 16 branches in which a
+// ledger keeps its state and transactions, and whose root hashes are the
+// StateHash and TransactionHash of the ledger header.
+//
+// Each item is a leaf, placed at the shallowest depth at which the nibbles
+// of its key differ from those of every other key. A leaf is hashed with
+// the prefix of its item, and an inner node is the hash of its 16 children,
+// with zero for an empty branch. Hashes are cached, so that after a few
+// items are put or deleted only the inner nodes above them are rehashed.
+package shamap
+
+import (
+	"fmt"
+
+	"github.com/parihaaraka/ripple/data"
+)
+
+type node interface {
+	hash() data.Hash256
+}
+
+type leaf struct {
+	key  data.Hash256
+	item data.Hashable
+	h    data.Hash256
+}
+
+func (l *leaf) hash() data.Hash256 { return l.h }
+
+type inner struct {
+	children [16]node
+	h        data.Hash256
+	dirty    bool
+}
+
+// branch returns the nibble of key which chooses the child at depth.
+func branch(key data.Hash256, depth int) int {
+	b := key[depth/2]
+	if depth%2 == 0 {
+		return int(b >> 4)
+	}
+	return int(b & 0x0F)
+}
+
+func (n *inner) hash() data.Hash256 {
+	if !n.dirty {
+		return n.h
+	}
+	var children [16]data.Hash256
+	for i, child := range n.children {
+		if child != nil {
+			children[i] = child.hash()
+		}
+	}
+	n.h, n.dirty = innerHash(&children), false
+	return n.h
+}
+
+// put returns true when l is a new key, rather than a replacement.
+func (n *inner) put(l *leaf, depth int) bool {
+	n.dirty = true
+	b := branch(l.key, depth)
+	switch child := n.children[b].(type) {
+	case nil:
+		n.children[b] = l
+	case *leaf:
+		if child.key == l.key {
+			n.children[b] = l
+			return false
+		}
+		below := &inner{}
+		below.put(child, depth+1)
+		below.put(l, depth+1)
+		n.children[b] = below
+	case *inner:
+		return child.put(l, depth+1)
+	}
+	return true
+}
+
+func (n *inner) delete(key data.Hash256, depth int) bool {
+	b := branch(key, depth)
+	switch child := n.children[b].(type) {
+	case *leaf:
+		if child.key != key {
+			return false
+		}
+		n.children[b] = nil
+	case *inner:
+		if !child.delete(key, depth+1) {
+			return false
+		}
+		// An inner node is never left holding a lone leaf
+		if l := child.only(); l != nil {
+			n.children[b] = l
+		}
+	default:
+		return false
+	}
+	n.dirty = true
+	return true
+}
+
+// only returns the leaf of an inner node which has no other children.
+func (n *inner) only() *leaf {
+	var only *leaf
+	for _, child := range n.children {
+		switch child := child.(type) {
+		case nil:
+		case *leaf:
+			if only != nil {
+				return nil
+			}
+			only = child
+		default:
+			return nil
+		}
+	}
+	return only
+}
+
+func (n *inner) get(key data.Hash256, depth int) *leaf {
+	switch child := n.children[branch(key, depth)].(type) {
+	case *leaf:
+		if child.key == key {
+			return child
+		}
+	case *inner:
+		return child.get(key, depth+1)
+	}
+	return nil
+}
+
+func (n *inner) each(f func(*leaf) error) error {
+	for _, child := range n.children {
+		switch child := child.(type) {
+		case *leaf:
+			if err := f(child); err != nil {
+				return err
+			}
+		case *inner:
+			if err := child.each(f); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SHAMap is a tree of items keyed by 32 byte hashes. Ledger entries are
+// keyed by their index, and transactions by their hash.
+// A SHAMap is not safe for concurrent use.
+type SHAMap struct {
+	root  inner
+	count int
+}
+
+// New returns an empty tree, whose hash is zero.
+func New() *SHAMap {
+	return &SHAMap{}
+}
+
+// NewState returns the state tree of the entries.
+func NewState(entries data.LedgerEntrySlice) (*SHAMap, error) {
+	m := New()
+	for _, le := range entries {
+		if err := m.PutEntry(le); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// NewTransactions returns the transaction tree of the transactions.
+func NewTransactions(txs data.TransactionSlice) (*SHAMap, error) {
+	m := New()
+	for _, txm := range txs {
+		if err := m.PutTransaction(txm); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Put inserts item at key, or replaces the item already there.
+func (m *SHAMap) Put(key data.Hash256, item data.Hashable) error {
+	h, err := data.NodeId(item)
+	if err != nil {
+		return err
+	}
+	if m.root.put(&leaf{key: key, item: item, h: h}, 0) {
+		m.count++
+	}
+	return nil
+}
+
+// PutEntry puts a ledger entry at its index.
+func (m *SHAMap) PutEntry(le data.LedgerEntry) error {
+	index, err := data.LedgerIndex(le)
+	if err != nil {
+		return err
+	}
+	return m.Put(*index, le)
+}
+
+// PutTransaction puts a transaction, with its metadata, at its hash.
+func (m *SHAMap) PutTransaction(txm *data.TransactionWithMetaData) error {
+	hash, err := data.NodeId(txm.Transaction)
+	if err != nil {
+		return err
+	}
+	return m.Put(hash, txm)
+}
+
+// Delete removes the item at key, returning false if there was none.
+func (m *SHAMap) Delete(key data.Hash256) bool {
+	if !m.root.delete(key, 0) {
+		return false
+	}
+	m.count--
+	return true
+}
+
+// Get returns the item at key.
+func (m *SHAMap) Get(key data.Hash256) (data.Hashable, bool) {
+	if l := m.root.get(key, 0); l != nil {
+		return l.item, true
+	}
+	return nil, false
+}
+
+// Len returns the number of items.
+func (m *SHAMap) Len() int {
+	return m.count
+}
+
+// Each calls f for each item in order of key.
+func (m *SHAMap) Each(f func(key data.Hash256, item data.Hashable) error) error {
+	return m.root.each(func(l *leaf) error {
+		return f(l.key, l.item)
+	})
+}
+
+// Hash returns the root hash, rehashing only what has changed since
+// the last call.
+func (m *SHAMap) Hash() data.Hash256 {
+	return m.root.hash()
+}
+
+// Verify checks the root hash against expected.
+func (m *SHAMap) Verify(expected data.Hash256) error {
+	if hash := m.Hash(); hash != expected {
+		return fmt.Errorf("SHAMap hash mismatch: %s expected: %s", hash, expected)
+	}
+	return nil
+}
+
+// VerifyLedger builds the state and transaction trees of a full ledger
+// and checks them against its header, and the header against its hash.
+func VerifyLedger(ledger *data.Ledger) error {
+	state, err := NewState(ledger.AccountState)
+	if err != nil {
+		return err
+	}
+	if err := state.Verify(ledger.StateHash); err != nil {
+		return fmt.Errorf("State: %s", err)
+	}
+	txs, err := NewTransactions(ledger.Transactions)
+	if err != nil {
+		return err
+	}
+	if err := txs.Verify(ledger.TransactionHash); err != nil {
+		return fmt.Errorf("Transactions: %s", err)
+	}
+	return ledger.VerifyHash()
+}
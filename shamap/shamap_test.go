@@ -0,0 +1,196 @@
+package shamap
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/parihaaraka/ripple/data"
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type SHAMapSuite struct{}
+
+var _ = Suite(&SHAMapSuite{})
+
+func loadLedger(c *C) *data.Ledger {
+	b, err := ioutil.ReadFile("../data/testdata/ledger_6000000.json")
+	c.Assert(err, IsNil)
+	var ledger data.Ledger
+	c.Assert(json.Unmarshal(b, &ledger), IsNil)
+	return &ledger
+}
+
+func (s *SHAMapSuite) TestVerifyLedger(c *C) {
+	ledger := loadLedger(c)
+	c.Assert(ledger.AccountState, HasLen, 261)
+	c.Assert(VerifyLedger(ledger), IsNil)
+
+	ledger.Hash[0] ^= 1
+	c.Check(VerifyLedger(ledger), ErrorMatches, "Ledger 38129 hash: .*")
+	ledger.Hash[0] ^= 1
+
+	ledger.StateHash[0] ^= 1
+	c.Check(VerifyLedger(ledger), ErrorMatches, "State: SHAMap hash mismatch.*")
+}
+
+func (s *SHAMapSuite) TestIncremental(c *C) {
+	ledger := loadLedger(c)
+	state, err := NewState(ledger.AccountState)
+	c.Assert(err, IsNil)
+	c.Check(state.Len(), Equals, 261)
+	expected := state.Hash()
+
+	// Removing and restoring entries, in any order, gives the same tree
+	var keys []data.Hash256
+	for _, le := range ledger.AccountState[:50] {
+		index, err := data.LedgerIndex(le)
+		c.Assert(err, IsNil)
+		c.Assert(state.Delete(*index), Equals, true)
+		c.Check(state.Delete(*index), Equals, false)
+		_, ok := state.Get(*index)
+		c.Check(ok, Equals, false)
+		keys = append(keys, *index)
+	}
+	c.Check(state.Len(), Equals, 211)
+	c.Check(state.Hash(), Not(Equals), expected)
+	partial, err := NewState(ledger.AccountState[50:])
+	c.Assert(err, IsNil)
+	c.Check(state.Hash(), Equals, partial.Hash())
+	for i := len(keys) - 1; i >= 0; i-- {
+		c.Assert(state.PutEntry(ledger.AccountState[i]), IsNil)
+	}
+	c.Check(state.Hash(), Equals, expected)
+
+	// Updating an entry changes the hash, and restoring it changes it back
+	root := ledger.AccountState[0].(*data.AccountRoot)
+	c.Assert(state.PutEntry(root), IsNil)
+	c.Check(state.Len(), Equals, 261)
+	c.Check(state.Hash(), Equals, expected)
+	sequence := *root.Sequence
+	*root.Sequence++
+	c.Assert(state.PutEntry(root), IsNil)
+	c.Check(state.Hash(), Not(Equals), expected)
+	*root.Sequence = sequence
+	c.Assert(state.PutEntry(root), IsNil)
+	c.Check(state.Hash(), Equals, expected)
+
+	item, ok := state.Get(keys[0])
+	c.Check(ok, Equals, true)
+	c.Check(item, Equals, ledger.AccountState[0])
+
+	var previous *data.Hash256
+	c.Assert(state.Each(func(key data.Hash256, item data.Hashable) error {
+		if previous != nil {
+			c.Check(previous.Compare(key) < 0, Equals, true)
+		}
+		previous = &key
+		return nil
+	}), IsNil)
+}
+
+func (s *SHAMapSuite) TestEmpty(c *C) {
+	m := New()
+	c.Check(m.Hash().IsZero(), Equals, true)
+	c.Check(m.Delete(data.Hash256{}), Equals, false)
+}
+
+func (s *SHAMapSuite) TestProof(c *C) {
+	ledger := loadLedger(c)
+	state, err := NewState(ledger.AccountState)
+	c.Assert(err, IsNil)
+	txs, err := NewTransactions(ledger.Transactions)
+	c.Assert(err, IsNil)
+
+	account, err := data.NewAccountFromAddress("r3kmLJN5D28dHuH8vZNUZpMC43pEHpaocV")
+	c.Assert(err, IsNil)
+	index, err := data.GetAccountRootIndex(*account)
+	c.Assert(err, IsNil)
+	txid := *ledger.Transactions[0].GetHash()
+	for _, test := range []struct {
+		m   *SHAMap
+		key data.Hash256
+	}{{state, *index}, {txs, txid}} {
+		proof, err := test.m.Prove(test.key)
+		c.Assert(err, IsNil)
+		c.Check(proof.Verify(&ledger.LedgerHeader), IsNil)
+
+		// The compact encoding leaves out the empty and recomputed hashes
+		b, err := proof.MarshalText()
+		c.Assert(err, IsNil)
+		var decoded Proof
+		c.Assert(decoded.UnmarshalText(b), IsNil)
+		c.Check(decoded, DeepEquals, *proof)
+		c.Check(decoded.Verify(&ledger.LedgerHeader), IsNil)
+		c.Check(len(b) < (len(proof.Item)+len(proof.Path)*16*32)*2, Equals, true)
+
+		v, err := decoded.Value()
+		c.Assert(err, IsNil)
+		item, _ := test.m.Get(test.key)
+		c.Check(v.GetType(), Equals, item.GetType())
+
+		// Any change to the item or path breaks the proof
+		proof.Item[0] ^= 1
+		c.Check(proof.Verify(&ledger.LedgerHeader), NotNil)
+		proof.Item[0] ^= 1
+		proof.Path[len(proof.Path)-1][0][0] ^= 1
+		c.Check(proof.Verify(&ledger.LedgerHeader), NotNil)
+	}
+	root, ok := state.Get(*index)
+	c.Assert(ok, Equals, true)
+	c.Check(root.(*data.AccountRoot).Account.String(), Equals, account.String())
+
+	_, err = state.Prove(txid)
+	c.Check(err, ErrorMatches, "No item at .*")
+}
+
+func (s *SHAMapSuite) TestWireNode(c *C) {
+	ledger := loadLedger(c)
+	state, err := NewState(ledger.AccountState)
+	c.Assert(err, IsNil)
+
+	// Every node read back from the wire hashes to the one it came from
+	leaves, compressed := 0, 0
+	var walk func(id NodeID, expected data.Hash256)
+	walk = func(id NodeID, expected data.Hash256) {
+		b, err := state.WireNode(id)
+		c.Assert(err, IsNil)
+		node, err := ReadWireNode(b, data.NT_ACCOUNT_NODE, ledger.LedgerSequence)
+		c.Assert(err, IsNil)
+		c.Assert(node.Hash, Equals, expected)
+		if node.Children == nil {
+			leaves++
+			return
+		}
+		if b[len(b)-1] == wireCompressedInner {
+			compressed++
+		}
+		for i, child := range node.Children {
+			if !child.IsZero() {
+				walk(id.Child(i), child)
+			}
+		}
+	}
+	walk(NodeID{}, state.Hash())
+	c.Check(leaves, Equals, len(ledger.AccountState))
+	c.Check(compressed > 0, Equals, true)
+
+	id := NodeID{}.Child(10).Child(3)
+	c.Check(id.String(), Equals, "2:A300000000000000000000000000000000000000000000000000000000000000")
+	decoded, err := NewNodeID(id.Bytes())
+	c.Assert(err, IsNil)
+	c.Check(decoded, Equals, id)
+	_, err = NewNodeID(id.Bytes()[1:])
+	c.Check(err, ErrorMatches, "Bad node id length: 32")
+	b := id.Bytes()
+	b[32] = 1
+	_, err = NewNodeID(b)
+	c.Check(err, ErrorMatches, "Bad node id: .*")
+
+	_, err = ReadWireNode([]byte{1, 2, 9}, data.NT_ACCOUNT_NODE, 0)
+	c.Check(err, ErrorMatches, "Unsupported wire node type: 9")
+	_, err = ReadWireNode(append(make([]byte, 5), wireInner), data.NT_ACCOUNT_NODE, 0)
+	c.Check(err, ErrorMatches, "Bad inner node length: 5")
+}
@@ -0,0 +1,205 @@
+package shamap
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/parihaaraka/ripple/data"
+)
+
+// NodeID locates a node by its depth and the nibbles of the keys below
+// it, as in TMGetLedger requests. The root is the zero NodeID.
+type NodeID struct {
+	Path  data.Hash256 // Zero beyond Depth nibbles
+	Depth uint8
+}
+
+const nodeIDSize = 33
+
+// NewNodeID decodes the 33 byte form of a NodeID, the path followed by
+// the depth.
+func NewNodeID(b []byte) (NodeID, error) {
+	var id NodeID
+	if len(b) != nodeIDSize {
+		return id, fmt.Errorf("Bad node id length: %d", len(b))
+	}
+	copy(id.Path[:], b)
+	id.Depth = b[32]
+	if id.Depth > 64 || id.masked() != id.Path {
+		return id, fmt.Errorf("Bad node id: %X", b)
+	}
+	return id, nil
+}
+
+func (id NodeID) masked() data.Hash256 {
+	var path data.Hash256
+	copy(path[:], id.Path[:id.Depth/2])
+	if id.Depth%2 == 1 {
+		path[id.Depth/2] = id.Path[id.Depth/2] & 0xF0
+	}
+	return path
+}
+
+// Child returns the id of the node on branch b.
+func (id NodeID) Child(b int) NodeID {
+	child := NodeID{Path: id.Path, Depth: id.Depth + 1}
+	if id.Depth%2 == 0 {
+		child.Path[id.Depth/2] |= byte(b) << 4
+	} else {
+		child.Path[id.Depth/2] |= byte(b)
+	}
+	return child
+}
+
+func (id NodeID) Bytes() []byte {
+	return append(id.Path[:], id.Depth)
+}
+
+func (id NodeID) String() string {
+	return fmt.Sprintf("%d:%s", id.Depth, id.Path)
+}
+
+// The type which ends each node sent between peers
+const (
+	wireTransaction         = 0
+	wireAccountState        = 1
+	wireInner               = 2
+	wireCompressedInner     = 3
+	wireTransactionWithMeta = 4
+)
+
+// rippled sends inner nodes with fewer children than this compressed
+const compressBelow = 12
+
+// WireNode is a node of a tree as it is sent between peers.
+type WireNode struct {
+	Hash     data.Hash256
+	Children *[16]data.Hash256 // For inner nodes
+	Item     data.Hashable     // For leaves
+}
+
+// ReadWireNode decodes and hashes a node of a tree of type typ, either
+// NT_ACCOUNT_NODE or NT_TRANSACTION_NODE, as sent in TMLedgerData.
+func ReadWireNode(b []byte, typ data.NodeType, ledgerSequence uint32) (*WireNode, error) {
+	if len(b) == 0 {
+		return nil, fmt.Errorf("Empty node")
+	}
+	body := b[:len(b)-1]
+	var (
+		node     WireNode
+		prefixed bytes.Buffer
+		prefix   data.HashPrefix
+	)
+	switch b[len(b)-1] {
+	case wireAccountState:
+		prefix = data.HP_LEAF_NODE
+	case wireTransactionWithMeta:
+		prefix = data.HP_TRANSACTION_NODE
+	case wireInner:
+		if len(body) != 16*32 {
+			return nil, fmt.Errorf("Bad inner node length: %d", len(body))
+		}
+		node.Children = new([16]data.Hash256)
+		for i := range node.Children {
+			copy(node.Children[i][:], body[i*32:])
+		}
+	case wireCompressedInner:
+		if len(body)%33 != 0 {
+			return nil, fmt.Errorf("Bad compressed inner node length: %d", len(body))
+		}
+		node.Children = new([16]data.Hash256)
+		for i := 0; i < len(body); i += 33 {
+			if body[i+32] > 15 {
+				return nil, fmt.Errorf("Bad branch in compressed inner node: %d", body[i+32])
+			}
+			copy(node.Children[body[i+32]][:], body[i:])
+		}
+	default:
+		return nil, fmt.Errorf("Unsupported wire node type: %d", b[len(b)-1])
+	}
+	if node.Children != nil {
+		node.Hash = innerHash(node.Children)
+		// The decoder reads the compressed form of every inner node
+		binary.Write(&prefixed, binary.BigEndian, data.HP_INNER_NODE)
+		for i, child := range node.Children {
+			if !child.IsZero() {
+				prefixed.Write(child[:])
+				prefixed.WriteByte(byte(i))
+			}
+		}
+	} else {
+		binary.Write(&prefixed, binary.BigEndian, prefix)
+		prefixed.Write(body)
+		hash := sha512.Sum512(prefixed.Bytes())
+		copy(node.Hash[:], hash[:32])
+	}
+	if node.Hash.IsZero() {
+		return nil, fmt.Errorf("Empty inner node")
+	}
+	item, err := data.ReadWire(bytes.NewReader(prefixed.Bytes()), typ, ledgerSequence, node.Hash)
+	if err != nil {
+		return nil, err
+	}
+	if node.Children == nil {
+		node.Item = item
+	}
+	return &node, nil
+}
+
+// WireNode returns the node at id as it is sent between peers.
+func (m *SHAMap) WireNode(id NodeID) ([]byte, error) {
+	m.Hash()
+	var n node = &m.root
+	for depth := 0; depth < int(id.Depth); depth++ {
+		parent, ok := n.(*inner)
+		if !ok {
+			return nil, fmt.Errorf("No node at %s", id)
+		}
+		if n = parent.children[branch(id.Path, depth)]; n == nil {
+			return nil, fmt.Errorf("No node at %s", id)
+		}
+	}
+	switch n := n.(type) {
+	case *leaf:
+		_, item, err := data.Raw(n.item)
+		if err != nil {
+			return nil, err
+		}
+		switch n.item.Prefix() {
+		case data.HP_LEAF_NODE:
+			return append(item, wireAccountState), nil
+		case data.HP_TRANSACTION_NODE:
+			return append(item, wireTransactionWithMeta), nil
+		default:
+			return nil, fmt.Errorf("Cannot send %s", n.item.GetType())
+		}
+	default:
+		var b []byte
+		children := n.(*inner).children
+		count := 0
+		for _, child := range children {
+			if child != nil {
+				count++
+			}
+		}
+		for i, child := range children {
+			switch {
+			case child == nil && count < compressBelow:
+			case child == nil:
+				b = append(b, make([]byte, 32)...)
+			case count < compressBelow:
+				h := child.hash()
+				b = append(append(b, h[:]...), byte(i))
+			default:
+				h := child.hash()
+				b = append(b, h[:]...)
+			}
+		}
+		if count < compressBelow {
+			return append(b, wireCompressedInner), nil
+		}
+		return append(b, wireInner), nil
+	}
+}
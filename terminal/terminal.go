@@ -7,6 +7,9 @@ import (
 
 	"github.com/fatih/color"
 	"github.com/parihaaraka/ripple/data"
+	"github.com/parihaaraka/ripple/peers"
+	"github.com/parihaaraka/ripple/shamap"
+	"github.com/parihaaraka/ripple/validators"
 	"github.com/parihaaraka/ripple/websockets"
 )
 
@@ -35,6 +38,7 @@ var (
 	offerStyle      = color.New(color.FgYellow)
 	lineStyle       = color.New(color.FgYellow)
 	infoStyle       = color.New(color.FgRed)
+	peerStyle       = color.New(color.FgCyan)
 )
 
 func defaultUint32(v *uint32) uint32 {
@@ -167,6 +171,13 @@ func newBundle(value interface{}, flag Flag) (*bundle, error) {
 		return newTxBundle(v, "", flag)
 	case data.LedgerEntry:
 		return newLeBundle(v, flag)
+	case peers.Message:
+		return &bundle{
+			color:  peerStyle,
+			format: "%s",
+			values: []interface{}{v},
+			flag:   flag,
+		}, nil
 	}
 	switch v := reflect.Indirect(reflect.ValueOf(value)).Interface().(type) {
 	case websockets.LedgerStreamMsg:
@@ -183,6 +194,17 @@ func newBundle(value interface{}, flag Flag) (*bundle, error) {
 			values: []interface{}{v.Status, v.LoadFactor, v.LoadBase},
 			flag:   flag,
 		}, nil
+	case websockets.ConnectionStateMsg:
+		state := "lost"
+		if v.Connected {
+			state = "restored"
+		}
+		return &bundle{
+			color:  infoStyle,
+			format: "Connection %s after %d failed attempts, last ledger: %d",
+			values: []interface{}{state, v.Attempts, v.LastLedgerSequence},
+			flag:   flag,
+		}, nil
 	case data.Ledger:
 		return &bundle{
 			color:  ledgerStyle,
@@ -190,6 +212,28 @@ func newBundle(value interface{}, flag Flag) (*bundle, error) {
 			values: []interface{}{v.LedgerSequence, v.CloseTime.String()},
 			flag:   flag,
 		}, nil
+	case shamap.Proof:
+		root, err := v.Root()
+		if err != nil {
+			return nil, err
+		}
+		return &bundle{
+			color:  leStyle,
+			format: "%s root: %s",
+			values: []interface{}{v, root},
+			flag:   flag,
+		}, nil
+	case validators.Event:
+		style := infoStyle
+		if v.Type == validators.QuorumReached {
+			style = validationStyle
+		}
+		return &bundle{
+			color:  style,
+			format: "%s",
+			values: []interface{}{v},
+			flag:   flag,
+		}, nil
 	case data.InnerNode:
 		return &bundle{
 			color:  leStyle,
@@ -221,8 +265,8 @@ func newBundle(value interface{}, flag Flag) (*bundle, error) {
 	case data.Balance:
 		return &bundle{
 			color:  balanceStyle,
-			format: "CounterParty: %-34s  Currency: %s Balance: %20s Change: %20s",
-			values: []interface{}{v.CounterParty, v.Currency, v.Balance, v.Change},
+			format: "%s",
+			values: []interface{}{v},
 			flag:   flag,
 		}, nil
 	case data.Path:
@@ -20,7 +20,7 @@ book XRP USD/rvYAfWj5gh67oV6fW32ZzP3Aw4Eubs59B
 Options:`
 
 var (
-	host = flag.String("host", "wss://s1.ripple.com:443", "websockets host")
+	host = flag.String("host", "wss://s1.ripple.com:443", "websockets or JSON-RPC host")
 )
 
 func showUsage() {
@@ -42,7 +42,7 @@ func main() {
 	}
 	flag.CommandLine.Parse(os.Args[3:])
 
-	remote, err := websockets.NewRemote(*host)
+	remote, err := websockets.NewClient(*host)
 	checkErr(err)
 	gets, err := data.NewAsset(os.Args[1])
 	checkErr(err)
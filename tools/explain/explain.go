@@ -13,6 +13,7 @@ import (
 
 	"github.com/golang/glog"
 	"github.com/parihaaraka/ripple/data"
+	"github.com/parihaaraka/ripple/shamap"
 	"github.com/parihaaraka/ripple/terminal"
 	"github.com/parihaaraka/ripple/websockets"
 )
@@ -33,18 +34,22 @@ explain 955A4C0B7C66FC97EA4C72634CDCDBF50BB17AAA647EC6C8C592788E5B95173C
 explain -
 	Explain binary transactions received through stdin
 
+explain - -proof
+	Check and explain hex encoded inclusion proofs received through stdin
+
 Options:`
 
 var argumentRegex = regexp.MustCompile(`(^[0-9a-fA-F]{64}$)|(^\d+$)|(^[r][a-km-zA-HJ-NP-Z0-9]{26,34}$)|(-)`)
 
 var (
 	flags        = flag.CommandLine
-	host         = flags.String("host", "wss://s2.ripple.com:443", "websockets host")
+	host         = flags.String("host", "wss://s2.ripple.com:443", "websockets or JSON-RPC host")
 	trades       = flag.Bool("t", false, "hide trades")
 	balances     = flag.Bool("b", false, "hide balances")
 	paths        = flag.Bool("p", false, "hide paths")
 	transactions = flag.Bool("tx", false, "hide transactions")
 	pageSize     = flag.Int("page_size", 20, "page size for account_tx requests")
+	proofs       = flag.Bool("proof", false, "read inclusion proofs from stdin")
 )
 
 func showUsage() {
@@ -88,13 +93,26 @@ func explain(txm *data.TransactionWithMetaData, flag terminal.Flag) {
 	}
 }
 
+func explainProof(line string) {
+	var proof shamap.Proof
+	checkErr(proof.UnmarshalText([]byte(line)))
+	terminal.Println(proof, terminal.Default)
+	v, err := proof.Value()
+	checkErr(err)
+	if txm, ok := v.(*data.TransactionWithMetaData); ok {
+		explain(txm, terminal.Indent)
+		return
+	}
+	terminal.Println(v, terminal.Indent)
+}
+
 func main() {
 	if len(os.Args) == 1 {
 		showUsage()
 	}
 	flags.Parse(os.Args[2:])
 	matches := argumentRegex.FindStringSubmatch(os.Args[1])
-	r, err := websockets.NewRemote(*host)
+	r, err := websockets.NewClient(*host)
 	checkErr(err)
 	glog.Infoln("Connected to: ", *host)
 	switch {
@@ -127,6 +145,10 @@ func main() {
 		r := bufio.NewReader(os.Stdin)
 		for line, err := r.ReadString('\n'); err == nil; line, err = r.ReadString('\n') {
 			// TODO: Accept nodeid:nodedata format
+			if *proofs {
+				explainProof(line)
+				continue
+			}
 			b, err := hex.DecodeString(line[:len(line)-1])
 			checkErr(err)
 			var nodeid data.Hash256
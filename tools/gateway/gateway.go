@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/parihaaraka/ripple/data"
+	"github.com/parihaaraka/ripple/keystore"
+	"github.com/parihaaraka/ripple/terminal"
+	"github.com/parihaaraka/ripple/websockets"
+)
+
+const usage = `Usage: gateway [issuer address] [options]
+
+Examples:
+
+gateway rMwjYedjc7qqtKYVLiAccJSmCwih4LnE2q -hot ra7JkEzrgeKHdzKgo4EUUVBnxggY4z37kt
+	Show the obligations of an issuer, the balances of its hot wallet, any frozen balances and assets, and any problems with its rippling settings
+
+gateway rMwjYedjc7qqtKYVLiAccJSmCwih4LnE2q -fix
+	Also sign the transactions which fix the problems with the issuer's key from the keystore and submit them
+
+Options:`
+
+var (
+	host  = flag.String("host", "wss://s1.ripple.com:443", "websockets host")
+	hot   = flag.String("hot", "", "comma separated hot wallet addresses")
+	user  = flag.Bool("user", false, "check the settings recommended for a user rather than a gateway")
+	fix   = flag.Bool("fix", false, "sign and submit the suggested transactions, waiting for each to be validated")
+	keys  = flag.String("keystore", keystore.DefaultPath(), "keystore holding the issuer's key")
+	keyId = flag.String("key", "", "name or address of the key in the keystore to sign with, if not the issuer's own")
+)
+
+func showUsage() {
+	fmt.Println(usage)
+	flag.PrintDefaults()
+	os.Exit(1)
+}
+
+func checkErr(err error) {
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+}
+
+// printBalances shows the balances of each account under a heading made
+// from format and the account.
+func printBalances(format string, balances map[data.Account][]websockets.GatewayBalance) {
+	for account, held := range balances {
+		terminal.Println(fmt.Sprintf(format, account), terminal.Default)
+		for _, balance := range held {
+			terminal.Println(fmt.Sprintf("%s %s", balance.Value.String(), balance.Currency), terminal.Indent)
+		}
+	}
+}
+
+func main() {
+	if len(os.Args) == 1 || strings.HasPrefix(os.Args[1], "-") {
+		showUsage()
+	}
+	flag.CommandLine.Parse(os.Args[2:])
+
+	issuer, err := data.NewAccountFromAddress(os.Args[1])
+	checkErr(err)
+	var hotWallets []data.Account
+	if *hot != "" {
+		for _, address := range strings.Split(*hot, ",") {
+			account, err := data.NewAccountFromAddress(strings.TrimSpace(address))
+			checkErr(err)
+			hotWallets = append(hotWallets, *account)
+		}
+	}
+	remote, err := websockets.NewRemote(*host)
+	checkErr(err)
+	defer remote.Close()
+
+	balances, err := remote.GatewayBalances(*issuer, hotWallets, "validated")
+	checkErr(err)
+	terminal.Println("Obligations:", terminal.Default)
+	for currency, value := range balances.Obligations {
+		terminal.Println(fmt.Sprintf("%s %s", value.String(), currency), terminal.Indent)
+	}
+	printBalances("Hot wallet %s:", balances.Balances)
+	printBalances("Frozen balances of %s:", balances.FrozenBalances)
+	printBalances("Assets issued by %s:", balances.Assets)
+
+	check, err := remote.NoRippleCheck(*issuer, !*user, "current")
+	checkErr(err)
+	if len(check.Problems) == 0 {
+		terminal.Println("No problems found", terminal.Default)
+		return
+	}
+	terminal.Println("Problems:", terminal.Default)
+	for _, problem := range check.Problems {
+		terminal.Println(problem, terminal.Indent)
+	}
+	if !*fix {
+		return
+	}
+
+	ref := *keyId
+	if ref == "" {
+		ref = issuer.String()
+	}
+	ks, err := keystore.Open(*keys)
+	checkErr(err)
+	passphrase, err := keystore.ReadPassphrase(keystore.PassphraseEnv, fmt.Sprintf("Passphrase for %s: ", ref))
+	checkErr(err)
+	secret, err := ks.Secret(ref, passphrase)
+	checkErr(err)
+	for _, tx := range check.Transactions {
+		validated, err := websockets.SubmitAndWait(context.Background(), remote, tx, secret.Key(), secret.Sequence())
+		checkErr(err)
+		terminal.Println(validated, terminal.Default)
+	}
+}
@@ -0,0 +1,2 @@
+// Empty test file to ensure gateway tool compiles
+package main
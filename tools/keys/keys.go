@@ -0,0 +1,109 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/parihaaraka/ripple/keystore"
+)
+
+const usage = `Usage: keys [options] command [name or address]
+
+Commands:
+
+add alice
+	Encrypt the seed, secret numbers or mnemonic read from stdin as alice
+list
+	Show the name, account and key type of each key, but never the secret
+export alice
+	Show the secret of alice
+rotate [alice]
+	Encrypt alice, or every key, again with a new passphrase
+remove alice
+	Delete alice
+
+Passphrases are read from $` + keystore.PassphraseEnv + ` and new ones, for add
+and rotate, from $` + keystore.NewPassphraseEnv + ` when set, or else asked for.
+New passphrases are asked for twice.
+
+Options:`
+
+var (
+	path = flag.String("keystore", keystore.DefaultPath(), "keystore file")
+)
+
+func showUsage() {
+	fmt.Println(usage)
+	flag.PrintDefaults()
+	os.Exit(1)
+}
+
+func checkErr(err error) {
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+}
+
+func passphrase(name string) []byte {
+	p, err := keystore.ReadPassphrase(keystore.PassphraseEnv, fmt.Sprintf("Passphrase for %s: ", name))
+	checkErr(err)
+	return p
+}
+
+func main() {
+	flag.Usage = showUsage
+	flag.Parse()
+	args := flag.Args()
+	if len(args) == 0 {
+		showUsage()
+	}
+	ks, err := keystore.Open(*path)
+	checkErr(err)
+	command, args := args[0], args[1:]
+	if len(args) > 1 || (len(args) == 0 && command != "list" && command != "rotate") {
+		showUsage()
+	}
+	switch command {
+	case "add":
+		secret, err := keystore.ReadSecret(fmt.Sprintf("Secret for %s: ", args[0]))
+		checkErr(err)
+		p, err := keystore.ReadNewPassphrase(keystore.NewPassphraseEnv, fmt.Sprintf("Passphrase for %s: ", args[0]))
+		checkErr(err)
+		e, err := ks.Add(args[0], strings.TrimSpace(string(secret)), p)
+		checkErr(err)
+		checkErr(ks.Save())
+		fmt.Println(e)
+	case "list":
+		for _, e := range ks.Keys {
+			fmt.Println(e)
+		}
+	case "export":
+		e, err := ks.Find(args[0])
+		checkErr(err)
+		secret, err := ks.Export(args[0], passphrase(e.Name))
+		checkErr(err)
+		fmt.Println(secret)
+	case "rotate":
+		entries := ks.Keys
+		if len(args) == 1 {
+			e, err := ks.Find(args[0])
+			checkErr(err)
+			entries = []*keystore.Entry{e}
+		}
+		to, err := keystore.ReadNewPassphrase(keystore.NewPassphraseEnv, "New passphrase: ")
+		checkErr(err)
+		for _, e := range entries {
+			checkErr(ks.Rotate(e.Name, passphrase(e.Name), to))
+			fmt.Println(e)
+		}
+		checkErr(ks.Save())
+	case "remove":
+		checkErr(ks.Remove(args[0]))
+		checkErr(ks.Save())
+	default:
+		showUsage()
+	}
+}
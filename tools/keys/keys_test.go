@@ -0,0 +1,2 @@
+// Empty test file to ensure keys tool compiles
+package main
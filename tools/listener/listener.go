@@ -0,0 +1,72 @@
+// Connects to a rippled server with the peer protocol and prints the
+// proposals, validations, manifests and transactions it relays.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/parihaaraka/ripple/peers"
+	"github.com/parihaaraka/ripple/terminal"
+)
+
+func checkErr(err error, quit bool) {
+	if err != nil {
+		terminal.Println(err.Error(), terminal.Default)
+		if quit {
+			os.Exit(1)
+		}
+	}
+}
+
+var (
+	host    = flag.String("host", "s1.ripple.com:51235", "peer protocol address to connect to")
+	network = flag.Uint("network", 0, "network id of the peer, 0 for mainnet")
+	all     = flag.Bool("all", false, "print every message, including pings and status changes")
+)
+
+func main() {
+	flag.Parse()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	p, err := peers.Dial(ctx, *host, &peers.Config{NetworkID: uint32(*network)})
+	cancel()
+	checkErr(err, true)
+	terminal.Println(fmt.Sprintf("Connected to %s with %s", p, p.Protocol), terminal.Default)
+
+	for m := range p.Incoming {
+		switch m := m.(type) {
+		case *peers.TMProposeSet:
+			proposal, err := m.Proposal()
+			checkErr(err, false)
+			if err == nil {
+				terminal.Println(proposal, terminal.Default)
+			}
+		case *peers.TMValidation:
+			validation, err := m.Decode()
+			checkErr(err, false)
+			if err == nil {
+				terminal.Println(validation, terminal.Default)
+			}
+		case *peers.TMManifests:
+			manifests, err := m.Manifests()
+			checkErr(err, false)
+			for _, manifest := range manifests {
+				terminal.Println(manifest, terminal.Default)
+			}
+		case *peers.TMTransaction:
+			tx, err := m.Transaction()
+			checkErr(err, false)
+			if err == nil {
+				terminal.Println(tx, terminal.Default)
+			}
+		default:
+			if *all {
+				terminal.Println(m, terminal.Default)
+			}
+		}
+	}
+	checkErr(p.Err(), true)
+}
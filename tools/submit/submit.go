@@ -1,15 +1,20 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"log"
 	"os"
 
 	"github.com/parihaaraka/ripple/config"
+	"github.com/parihaaraka/ripple/keystore"
 )
 
 var (
-	host = flag.String("host", "wss://s2.ripple.com:443", "websockets host")
+	host = flag.String("host", "wss://s2.ripple.com:443", "websockets or JSON-RPC host")
+	wait = flag.Bool("wait", false, "fill in sequences and fees, and wait for each transaction to be validated")
+	keys = flag.String("keystore", keystore.DefaultPath(), "keystore holding the keys actions refer to by name or address")
 )
 
 func checkErr(err error) {
@@ -22,6 +27,17 @@ func main() {
 	flag.Parse()
 	actions, err := config.Parse(os.Stdin)
 	checkErr(err)
+	ks, err := keystore.Open(*keys)
+	checkErr(err)
+	checkErr(actions.Unlock(ks, func(e *keystore.Entry) ([]byte, error) {
+		return keystore.ReadPassphrase(keystore.PassphraseEnv, fmt.Sprintf("Passphrase for %s: ", e.Name))
+	}))
+	if *wait {
+		checkErr(actions.SubmitAndWait(context.Background(), *host))
+		log.Printf("Validated %d transactions", actions.Count())
+		return
+	}
+	checkErr(actions.Autofill(*host))
 	checkErr(actions.Prepare())
 	checkErr(actions.Submit(*host))
 	log.Printf("Submitted %d transactions", actions.Count())
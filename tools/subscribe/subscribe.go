@@ -22,11 +22,16 @@ func checkErr(err error, quit bool) {
 var (
 	host     = flag.String("host", "wss://s2.ripple.com:443", "websockets host to connect to")
 	proposed = flag.Bool("proposed", false, "include proposed transacions")
+	retry    = flag.Bool("reconnect", false, "reconnect and resubscribe when the connection is lost")
 )
 
 func main() {
 	flag.Parse()
-	r, err := websockets.NewRemote(*host)
+	newRemote := websockets.NewRemote
+	if *retry {
+		newRemote = websockets.NewReconnectingRemote
+	}
+	r, err := newRemote(*host)
 	checkErr(err, true)
 
 	confirmation, err := r.Subscribe(true, !*proposed, *proposed, true)
@@ -60,6 +65,8 @@ func main() {
 			}
 		case *websockets.ServerStreamMsg:
 			terminal.Println(msg, terminal.Default)
+		case *websockets.ConnectionStateMsg:
+			terminal.Println(msg, terminal.Default)
 		}
 	}
 }
@@ -0,0 +1,77 @@
+// Monitors the agreement of trusted validators using the validations
+// stream, and prints quorums, disagreements and lagging validators.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/parihaaraka/ripple/data"
+	"github.com/parihaaraka/ripple/terminal"
+	"github.com/parihaaraka/ripple/validators"
+	"github.com/parihaaraka/ripple/websockets"
+)
+
+func checkErr(err error, quit bool) {
+	if err != nil {
+		terminal.Println(err.Error(), terminal.Default)
+		if quit {
+			os.Exit(1)
+		}
+	}
+}
+
+var (
+	host      = flag.String("host", "wss://s2.ripple.com:443", "websockets host to connect to")
+	list      = flag.String("list", "", "validator list file, as served by the publisher")
+	publisher = flag.String("publisher", "", "master public key of the list publisher, in hex")
+	quorum    = flag.Int("quorum", 0, "validations needed for quorum, 0 for 80% of the list")
+	lag       = flag.Uint("lag", validators.DefaultMaxLag, "ledgers behind before a validator is lagging")
+	verbose   = flag.Bool("verbose", false, "print every validation")
+)
+
+func main() {
+	flag.Parse()
+	if *list == "" || *publisher == "" {
+		fmt.Println("Usage: validations -list validators.json -publisher ED2677...")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+	var key data.PublicKey
+	checkErr(key.UnmarshalText([]byte(*publisher)), true)
+	lists := validators.NewLists(nil, key)
+	_, err := lists.Load(*list)
+	checkErr(err, true)
+	trusted := lists.Trusted(time.Now())
+	monitor := validators.NewMonitor(lists.Manifests, trusted, *quorum)
+	monitor.MaxLag = uint32(*lag)
+	terminal.Println(fmt.Sprintf("Trusting %d validators", len(trusted)), terminal.Default)
+
+	r, err := websockets.NewReconnectingRemote(*host)
+	checkErr(err, true)
+	_, err = r.SubscribeValidations()
+	checkErr(err, true)
+
+	for msg := range r.Incoming {
+		switch msg := msg.(type) {
+		case *websockets.ManifestStreamMsg:
+			_, err := lists.Manifests.ApplyBase64(msg.Manifest)
+			if err != validators.ErrStaleManifest {
+				checkErr(err, false)
+			}
+		case *websockets.ValidationStreamMsg:
+			events, err := monitor.Add(&msg.Validation)
+			checkErr(err, false)
+			if *verbose {
+				terminal.Println(&msg.Validation, terminal.Indent)
+			}
+			for _, event := range events {
+				terminal.Println(event, terminal.Default)
+			}
+		case *websockets.ConnectionStateMsg:
+			terminal.Println(msg, terminal.Default)
+		}
+	}
+}
@@ -0,0 +1,2 @@
+// Empty test file to ensure validations tool compiles
+package main
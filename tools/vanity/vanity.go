@@ -95,7 +95,7 @@ func main() {
 			log.Printf("Tested: %d seeds at %.2f/sec", num, float64(num)/time.Since(start).Seconds())
 			return
 		case trial := <-c:
-			s, err := crypto.NewFamilySeed(trial.Seed)
+			s, err := crypto.EncodeSeed(trial.Seed, *ed25519key)
 			checkErr(err)
 			log.Println(s, trial.Id)
 		}
@@ -0,0 +1,269 @@
+package validators
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/parihaaraka/ripple/crypto"
+	"github.com/parihaaraka/ripple/data"
+)
+
+// ErrStaleList is returned when no list in a publication is newer than
+// those already held for its publisher.
+var ErrStaleList = errors.New("Validator list is not newer than the current one")
+
+// Validator is an entry of a validator list.
+type Validator struct {
+	PublicKey data.PublicKey
+	Manifest  *data.Manifest
+}
+
+// ValidatorList is a verified validator list from a publisher.
+type ValidatorList struct {
+	Publisher  data.PublicKey
+	Sequence   uint32
+	Effective  *data.RippleTime
+	Expiration data.RippleTime
+	Validators []Validator
+}
+
+// Active is true when the list is in effect at t.
+func (l *ValidatorList) Active(t time.Time) bool {
+	return (l.Effective == nil || !t.Before(l.Effective.Time())) && t.Before(l.Expiration.Time())
+}
+
+func (l ValidatorList) String() string {
+	return fmt.Sprintf("Validator list: %s %d validators: %d expires: %s", l.Publisher.NodePublicKey(), l.Sequence, len(l.Validators), l.Expiration.String())
+}
+
+// The JSON served by publishers, and returned by the validator_list
+// command. Version 1 has a single blob, and version 2 any number, of which
+// those not yet effective replace the current one in turn.
+type publication struct {
+	PublicKey data.PublicKey `json:"public_key"`
+	Manifest  string         `json:"manifest"`
+	Blob      string         `json:"blob,omitempty"`
+	Signature string         `json:"signature,omitempty"`
+	Version   uint32         `json:"version"`
+	Blobs     []struct {
+		Blob      string `json:"blob"`
+		Signature string `json:"signature"`
+		Manifest  string `json:"manifest,omitempty"`
+	} `json:"blobs_v2,omitempty"`
+}
+
+type blob struct {
+	Sequence   uint32           `json:"sequence"`
+	Effective  *data.RippleTime `json:"effective,omitempty"`
+	Expiration data.RippleTime  `json:"expiration"`
+	Validators []struct {
+		PublicKey string `json:"validation_public_key"`
+		Manifest  string `json:"manifest,omitempty"`
+	} `json:"validators"`
+}
+
+// Lists holds the validator lists of trusted publishers, and the
+// manifests of their validators. It is safe for concurrent use.
+type Lists struct {
+	Manifests *Manifests
+
+	mu         sync.RWMutex
+	publishers map[data.PublicKey][]*ValidatorList // Ordered by sequence
+}
+
+// NewLists returns Lists which accepts lists signed by the master keys of
+// the publishers. Manifests may be shared with a validations monitor, or
+// nil for new ones.
+func NewLists(manifests *Manifests, publishers ...data.PublicKey) *Lists {
+	if manifests == nil {
+		manifests = NewManifests()
+	}
+	l := &Lists{
+		Manifests:  manifests,
+		publishers: make(map[data.PublicKey][]*ValidatorList),
+	}
+	for _, publisher := range publishers {
+		l.publishers[publisher] = nil
+	}
+	return l
+}
+
+// Load reads a publication from a file, as served by a publisher.
+func (l *Lists) Load(path string) ([]*ValidatorList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return l.Read(f)
+}
+
+// Read reads a publication, as served by a publisher.
+func (l *Lists) Read(r io.Reader) ([]*ValidatorList, error) {
+	var p publication
+	if err := json.NewDecoder(r).Decode(&p); err != nil {
+		return nil, err
+	}
+	return l.apply(&p)
+}
+
+// apply verifies each list in the publication and keeps those newer than
+// the publisher's current lists, which are returned. Expired lists are
+// kept, as there may be nothing newer, but are not Active.
+func (l *Lists) apply(p *publication) ([]*ValidatorList, error) {
+	l.mu.RLock()
+	_, trusted := l.publishers[p.PublicKey]
+	l.mu.RUnlock()
+	if !trusted {
+		return nil, fmt.Errorf("Untrusted publisher: %s", p.PublicKey.NodePublicKey())
+	}
+	type signed struct{ blob, signature, manifest string }
+	var blobs []signed
+	switch p.Version {
+	case 1:
+		blobs = append(blobs, signed{p.Blob, p.Signature, p.Manifest})
+	case 2:
+		for _, b := range p.Blobs {
+			manifest := b.Manifest
+			if manifest == "" {
+				manifest = p.Manifest
+			}
+			blobs = append(blobs, signed{b.Blob, b.Signature, manifest})
+		}
+	default:
+		return nil, fmt.Errorf("Unknown validator list version: %d", p.Version)
+	}
+	var lists []*ValidatorList
+	for _, b := range blobs {
+		list, err := l.verify(p.PublicKey, b.blob, b.signature, b.manifest)
+		if err == ErrRevoked {
+			l.mu.Lock()
+			l.publishers[p.PublicKey] = nil
+			l.mu.Unlock()
+		}
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, list)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	current := l.publishers[p.PublicKey]
+	var last uint32
+	if len(current) > 0 {
+		last = current[len(current)-1].Sequence
+	}
+	var accepted []*ValidatorList
+	for _, list := range lists {
+		if list.Sequence > last {
+			accepted = append(accepted, list)
+		}
+	}
+	if len(accepted) == 0 {
+		return nil, ErrStaleList
+	}
+	current = append(current, accepted...)
+	sort.SliceStable(current, func(i, j int) bool { return current[i].Sequence < current[j].Sequence })
+	l.publishers[p.PublicKey] = current
+	return accepted, nil
+}
+
+// verify checks the publisher's manifest and its signature of the blob,
+// then decodes the blob.
+func (l *Lists) verify(publisher data.PublicKey, encoded, signature, manifest string) (*ValidatorList, error) {
+	m, err := l.Manifests.ApplyBase64(manifest)
+	switch {
+	case m != nil && m.PublicKey != publisher:
+		return nil, fmt.Errorf("Manifest of %s signs list of %s", m.PublicKey.NodePublicKey(), publisher.NodePublicKey())
+	case err != nil && err != ErrStaleManifest:
+		return nil, err
+	}
+	signing, ok := l.Manifests.Signing(publisher)
+	if !ok {
+		return nil, ErrRevoked
+	}
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := hex.DecodeString(signature)
+	if err != nil {
+		return nil, err
+	}
+	if ok, err := crypto.Verify(signing.Bytes(), crypto.Sha512Half(raw), raw, sig); err != nil || !ok {
+		return nil, fmt.Errorf("Bad validator list signature from %s", publisher.NodePublicKey())
+	}
+	var b blob
+	if err := json.Unmarshal(raw, &b); err != nil {
+		return nil, err
+	}
+	list := &ValidatorList{
+		Publisher:  publisher,
+		Sequence:   b.Sequence,
+		Effective:  b.Effective,
+		Expiration: b.Expiration,
+	}
+	for _, v := range b.Validators {
+		var validator Validator
+		if err := validator.PublicKey.UnmarshalText([]byte(v.PublicKey)); err != nil {
+			return nil, err
+		}
+		if v.Manifest != "" {
+			m, err := l.Manifests.ApplyBase64(v.Manifest)
+			switch {
+			case m != nil && m.PublicKey != validator.PublicKey:
+				return nil, fmt.Errorf("Manifest of %s listed for %s", m.PublicKey.NodePublicKey(), validator.PublicKey.NodePublicKey())
+			case err != nil && err != ErrStaleManifest && err != ErrRevoked:
+				return nil, err
+			}
+		}
+		// The validator's own manifest may well be newer than the listed one
+		validator.Manifest, _ = l.Manifests.Get(validator.PublicKey)
+		list.Validators = append(list.Validators, validator)
+	}
+	return list, nil
+}
+
+// Current returns the list of each publisher which is in effect at t.
+func (l *Lists) Current(t time.Time) []*ValidatorList {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	var current []*ValidatorList
+	for _, lists := range l.publishers {
+		for i := len(lists) - 1; i >= 0; i-- {
+			if lists[i].Active(t) {
+				current = append(current, lists[i])
+				break
+			}
+		}
+	}
+	sort.Slice(current, func(i, j int) bool {
+		return current[i].Publisher.String() < current[j].Publisher.String()
+	})
+	return current
+}
+
+// Trusted returns the master keys of the validators on any list in effect
+// at t, less those which have been revoked.
+func (l *Lists) Trusted(t time.Time) []data.PublicKey {
+	seen := make(map[data.PublicKey]bool)
+	var trusted []data.PublicKey
+	for _, list := range l.Current(t) {
+		for _, v := range list.Validators {
+			if !seen[v.PublicKey] && !l.Manifests.Revoked(v.PublicKey) {
+				seen[v.PublicKey] = true
+				trusted = append(trusted, v.PublicKey)
+			}
+		}
+	}
+	sort.Slice(trusted, func(i, j int) bool { return trusted[i].String() < trusted[j].String() })
+	return trusted
+}
@@ -0,0 +1,118 @@
+// Package validators keeps track of the keys of trusted validators: the
+// manifests which bind each validator's ephemeral signing key to its
+// master key, and the signed lists of validators from trusted publishers.
+package validators
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/parihaaraka/ripple/data"
+)
+
+var (
+	// ErrStaleManifest is returned when a manifest is not newer than the
+	// one already held for its master key.
+	ErrStaleManifest = errors.New("Manifest is not newer than the current one")
+	// ErrRevoked is returned for anything signed by a revoked master key.
+	ErrRevoked = errors.New("Master key is revoked")
+)
+
+// Manifests holds the latest manifest of each master key.
+// It is safe for concurrent use.
+type Manifests struct {
+	mu        sync.RWMutex
+	byMaster  map[data.PublicKey]*data.Manifest
+	bySigning map[data.PublicKey]data.PublicKey
+}
+
+// NewManifests returns an empty set of manifests.
+func NewManifests() *Manifests {
+	return &Manifests{
+		byMaster:  make(map[data.PublicKey]*data.Manifest),
+		bySigning: make(map[data.PublicKey]data.PublicKey),
+	}
+}
+
+// Apply verifies the manifest and, if it is newer than the one held for
+// its master key, replaces it. A revocation replaces any manifest, after
+// which every manifest for the master key is refused with ErrRevoked.
+func (m *Manifests) Apply(manifest *data.Manifest) error {
+	if err := manifest.Verify(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	current, ok := m.byMaster[manifest.PublicKey]
+	switch {
+	case ok && current.Revoked():
+		return ErrRevoked
+	case ok && manifest.Sequence <= current.Sequence:
+		return ErrStaleManifest
+	}
+	if master, ok := m.bySigning[manifest.PublicKey]; ok {
+		return fmt.Errorf("Master key %s is an ephemeral key of %s", manifest.PublicKey.NodePublicKey(), master.NodePublicKey())
+	}
+	if !manifest.Revoked() {
+		signing := *manifest.SigningPubKey
+		if _, ok := m.byMaster[signing]; ok {
+			return fmt.Errorf("Ephemeral key %s is a master key", signing.NodePublicKey())
+		}
+		if master, ok := m.bySigning[signing]; ok && master != manifest.PublicKey {
+			return fmt.Errorf("Ephemeral key %s belongs to %s", signing.NodePublicKey(), master.NodePublicKey())
+		}
+	}
+	if ok && current.SigningPubKey != nil {
+		delete(m.bySigning, *current.SigningPubKey)
+	}
+	m.byMaster[manifest.PublicKey] = manifest
+	if !manifest.Revoked() {
+		m.bySigning[*manifest.SigningPubKey] = manifest.PublicKey
+	}
+	return nil
+}
+
+// ApplyBase64 decodes a manifest, as published, and applies it.
+func (m *Manifests) ApplyBase64(s string) (*data.Manifest, error) {
+	manifest, err := data.NewManifestFromBase64(s)
+	if err != nil {
+		return nil, err
+	}
+	return manifest, m.Apply(manifest)
+}
+
+// Get returns the manifest held for the master key.
+func (m *Manifests) Get(master data.PublicKey) (*data.Manifest, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	manifest, ok := m.byMaster[master]
+	return manifest, ok
+}
+
+// Revoked is true when the master key has been revoked.
+func (m *Manifests) Revoked(master data.PublicKey) bool {
+	manifest, ok := m.Get(master)
+	return ok && manifest.Revoked()
+}
+
+// Signing returns the current ephemeral key of the master key, or false
+// when it has none, because it is unknown or revoked.
+func (m *Manifests) Signing(master data.PublicKey) (data.PublicKey, bool) {
+	manifest, ok := m.Get(master)
+	if !ok || manifest.Revoked() {
+		return data.PublicKey{}, false
+	}
+	return *manifest.SigningPubKey, true
+}
+
+// Master returns the master key of an ephemeral key. Validators which
+// have no manifest sign with their master key, which is returned as is.
+func (m *Manifests) Master(signing data.PublicKey) data.PublicKey {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if master, ok := m.bySigning[signing]; ok {
+		return master
+	}
+	return signing
+}
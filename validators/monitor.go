@@ -0,0 +1,271 @@
+package validators
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/parihaaraka/ripple/data"
+)
+
+// EventType says what a Monitor has seen.
+type EventType uint8
+
+const (
+	// A ledger hash was validated by a quorum of trusted validators
+	QuorumReached EventType = iota
+	// A trusted validator validated another hash for a ledger
+	// which reached quorum
+	Disagreement
+	// A trusted validator has validated nothing for MaxLag ledgers
+	Lagging
+	// A lagging validator validated a recent ledger again
+	Recovered
+)
+
+var eventTypes = map[EventType]string{
+	QuorumReached: "QuorumReached",
+	Disagreement:  "Disagreement",
+	Lagging:       "Lagging",
+	Recovered:     "Recovered",
+}
+
+func (t EventType) String() string {
+	return eventTypes[t]
+}
+
+// Event is a change in the agreement of trusted validators.
+type Event struct {
+	Type EventType
+	// The ledger which reached quorum, or which the validator
+	// disagreed with or fell behind
+	LedgerSequence uint32
+	LedgerHash     data.Hash256
+	// The master key of the validator, unless the quorum was reached
+	Validator data.PublicKey
+	// The hash the validator validated instead, when it disagreed
+	Validated data.Hash256
+	// The last ledger the validator validated, when it is lagging
+	Latest uint32
+	// The number of trusted validators which agreed, when the
+	// quorum was reached
+	Validations int
+}
+
+func (e Event) String() string {
+	switch e.Type {
+	case QuorumReached:
+		return fmt.Sprintf("%s: %d %s validations: %d", e.Type, e.LedgerSequence, e.LedgerHash, e.Validations)
+	case Disagreement:
+		return fmt.Sprintf("%s: %s %d %s validated: %s", e.Type, e.Validator.NodePublicKey(), e.LedgerSequence, e.LedgerHash, e.Validated)
+	case Lagging:
+		return fmt.Sprintf("%s: %s %d latest: %d", e.Type, e.Validator.NodePublicKey(), e.LedgerSequence, e.Latest)
+	default:
+		return fmt.Sprintf("%s: %s %d", e.Type, e.Validator.NodePublicKey(), e.LedgerSequence)
+	}
+}
+
+// Quorum returns the number of validators, out of n trusted ones, which
+// must agree for a ledger to be validated: 80%, rounded up.
+func Quorum(n int) int {
+	return (n*4 + 4) / 5
+}
+
+// DefaultMaxLag is the MaxLag of a new Monitor.
+const DefaultMaxLag = 5
+
+// The number of ledgers before the last validated one whose
+// validations are kept, to report late disagreement.
+const keepLedgers = 256
+
+type votes struct {
+	hashes    map[data.PublicKey]data.Hash256
+	validated *data.Hash256
+}
+
+// Monitor tracks the agreement of trusted validators on each ledger,
+// given their validations. It is safe for concurrent use.
+type Monitor struct {
+	// Validators whose latest full validation is more than this many
+	// ledgers behind the last validated ledger are lagging
+	MaxLag uint32
+
+	manifests *Manifests
+
+	mu        sync.Mutex
+	trusted   map[data.PublicKey]bool
+	quorum    int
+	ledgers   map[uint32]*votes
+	latest    map[data.PublicKey]uint32
+	lagging   map[data.PublicKey]bool
+	first     uint32 // The first ledger to reach quorum
+	validated uint32
+	hash      data.Hash256
+}
+
+// NewMonitor returns a Monitor which trusts the master keys, whose current
+// ephemeral keys are found in manifests. A zero quorum uses Quorum.
+func NewMonitor(manifests *Manifests, trusted []data.PublicKey, quorum int) *Monitor {
+	if manifests == nil {
+		manifests = NewManifests()
+	}
+	m := &Monitor{
+		MaxLag:    DefaultMaxLag,
+		manifests: manifests,
+		ledgers:   make(map[uint32]*votes),
+		latest:    make(map[data.PublicKey]uint32),
+		lagging:   make(map[data.PublicKey]bool),
+	}
+	m.SetTrusted(trusted, quorum)
+	return m
+}
+
+// SetTrusted replaces the trusted validators, as when a new
+// validator list takes effect. A zero quorum uses Quorum.
+func (m *Monitor) SetTrusted(trusted []data.PublicKey, quorum int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.trusted = make(map[data.PublicKey]bool)
+	for _, key := range trusted {
+		m.trusted[key] = true
+	}
+	if quorum == 0 {
+		quorum = Quorum(len(trusted))
+	}
+	m.quorum = quorum
+	for key := range m.lagging {
+		if !m.trusted[key] {
+			delete(m.lagging, key)
+		}
+	}
+}
+
+// Validated returns the last ledger to reach quorum.
+func (m *Monitor) Validated() (uint32, data.Hash256) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.validated, m.hash
+}
+
+// Add checks the signature of a validation and, if it is from a trusted
+// validator, counts it, returning any events which follow. Partial
+// validations show only that a validator is not keeping up, and are not
+// counted.
+func (m *Monitor) Add(v *data.Validation) ([]Event, error) {
+	if ok, err := data.CheckSignature(v); err != nil || !ok {
+		return nil, fmt.Errorf("Bad validation signature from %s", v.SigningPubKey.NodePublicKey())
+	}
+	master := m.manifests.Master(v.SigningPubKey)
+	if master == v.SigningPubKey {
+		// A validator with a manifest must sign with its ephemeral key
+		if _, ok := m.manifests.Get(master); ok {
+			return nil, fmt.Errorf("Validation signed by master key %s", master.NodePublicKey())
+		}
+	}
+	if !v.Full() {
+		return nil, nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	seq := v.LedgerSequence
+	if !m.trusted[master] || seq+keepLedgers < m.validated {
+		return nil, nil
+	}
+	ledger, ok := m.ledgers[seq]
+	if !ok {
+		ledger = &votes{hashes: make(map[data.PublicKey]data.Hash256)}
+		m.ledgers[seq] = ledger
+	}
+	if _, ok := ledger.hashes[master]; ok {
+		return nil, nil
+	}
+	ledger.hashes[master] = v.LedgerHash
+	if seq > m.latest[master] {
+		m.latest[master] = seq
+	}
+	var events []Event
+	if m.lagging[master] && seq+m.MaxLag >= m.validated {
+		delete(m.lagging, master)
+		events = append(events, Event{Type: Recovered, LedgerSequence: seq, LedgerHash: v.LedgerHash, Validator: master})
+	}
+	switch {
+	case ledger.validated != nil && *ledger.validated != v.LedgerHash:
+		events = append(events, m.disagreement(master, seq, ledger))
+	case ledger.validated == nil && m.count(ledger, v.LedgerHash) >= m.quorum:
+		events = append(events, m.reached(seq, v.LedgerHash, ledger)...)
+	}
+	return events, nil
+}
+
+func (m *Monitor) count(ledger *votes, hash data.Hash256) int {
+	var n int
+	for key, h := range ledger.hashes {
+		if h == hash && m.trusted[key] {
+			n++
+		}
+	}
+	return n
+}
+
+func (m *Monitor) disagreement(key data.PublicKey, seq uint32, ledger *votes) Event {
+	return Event{
+		Type:           Disagreement,
+		LedgerSequence: seq,
+		LedgerHash:     *ledger.validated,
+		Validator:      key,
+		Validated:      ledger.hashes[key],
+	}
+}
+
+// sorted returns the keys in a stable order, for repeatable events.
+func sorted(keys map[data.PublicKey]bool) []data.PublicKey {
+	var s []data.PublicKey
+	for key, ok := range keys {
+		if ok {
+			s = append(s, key)
+		}
+	}
+	sort.Slice(s, func(i, j int) bool { return s[i].String() < s[j].String() })
+	return s
+}
+
+// reached records a ledger reaching quorum, and reports the validators
+// which disagreed with it or are lagging behind it.
+func (m *Monitor) reached(seq uint32, hash data.Hash256, ledger *votes) []Event {
+	ledger.validated = &hash
+	events := []Event{{
+		Type:           QuorumReached,
+		LedgerSequence: seq,
+		LedgerHash:     hash,
+		Validations:    m.count(ledger, hash),
+	}}
+	for _, key := range sorted(m.trusted) {
+		if h, ok := ledger.hashes[key]; ok && h != hash {
+			events = append(events, m.disagreement(key, seq, ledger))
+		}
+	}
+	if seq <= m.validated {
+		return events
+	}
+	if m.first == 0 {
+		m.first = seq
+	}
+	m.validated, m.hash = seq, hash
+	for _, key := range sorted(m.trusted) {
+		latest, ok := m.latest[key]
+		if !ok {
+			// Not yet seen, which is no surprise on starting
+			latest = m.first
+		}
+		if latest+m.MaxLag < seq && !m.lagging[key] {
+			m.lagging[key] = true
+			events = append(events, Event{Type: Lagging, LedgerSequence: seq, LedgerHash: hash, Validator: key, Latest: m.latest[key]})
+		}
+	}
+	for s := range m.ledgers {
+		if s+keepLedgers < seq {
+			delete(m.ledgers, s)
+		}
+	}
+	return events
+}
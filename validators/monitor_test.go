@@ -0,0 +1,135 @@
+package validators
+
+import (
+	"fmt"
+
+	"github.com/parihaaraka/ripple/crypto"
+	"github.com/parihaaraka/ripple/data"
+	. "gopkg.in/check.v1"
+)
+
+type monitorFixture struct {
+	masters, signing []crypto.Key
+	manifests        *Manifests
+	monitor          *Monitor
+}
+
+func newMonitorFixture(c *C, n int) *monitorFixture {
+	f := &monitorFixture{manifests: NewManifests()}
+	var trusted []data.PublicKey
+	for i := 0; i < n; i++ {
+		master := key(c, fmt.Sprintf("monitor %d", i), i%2 == 0)
+		signing := master
+		// Odd validators sign with an ephemeral key
+		if i%2 == 1 {
+			signing = key(c, fmt.Sprintf("monitor %d ephemeral", i), true)
+			c.Assert(f.manifests.Apply(manifest(c, master, signing, 1)), IsNil)
+		}
+		f.masters = append(f.masters, master)
+		f.signing = append(f.signing, signing)
+		trusted = append(trusted, publicKey(master))
+	}
+	f.monitor = NewMonitor(f.manifests, trusted, 0)
+	return f
+}
+
+func (f *monitorFixture) validation(c *C, i int, seq uint32, hash data.Hash256, full bool) *data.Validation {
+	v := &data.Validation{
+		LedgerHash:     hash,
+		LedgerSequence: seq,
+		SigningTime:    *data.NewRippleTime(seq),
+	}
+	if full {
+		v.Flags = data.VF_FULL_VALIDATION
+	}
+	c.Assert(data.Sign(v, f.signing[i], nil), IsNil)
+	return v
+}
+
+func (f *monitorFixture) add(c *C, i int, seq uint32, hash data.Hash256) []Event {
+	events, err := f.monitor.Add(f.validation(c, i, seq, hash, true))
+	c.Assert(err, IsNil)
+	return events
+}
+
+func (s *ValidatorsSuite) TestQuorum(c *C) {
+	for n, quorum := range []int{0, 1, 2, 3, 4, 4, 5, 6, 7, 8, 8} {
+		c.Check(Quorum(n), Equals, quorum)
+	}
+}
+
+func (s *ValidatorsSuite) TestMonitor(c *C) {
+	f := newMonitorFixture(c, 5)
+	good, bad := data.Hash256{1}, data.Hash256{2}
+
+	// Quorum of 4 out of 5
+	c.Check(f.add(c, 0, 100, good), HasLen, 0)
+	c.Check(f.add(c, 1, 100, bad), HasLen, 0)
+	c.Check(f.add(c, 2, 100, good), HasLen, 0)
+	c.Check(f.add(c, 2, 100, good), HasLen, 0)
+	c.Check(f.add(c, 3, 100, good), HasLen, 0)
+	events := f.add(c, 4, 100, good)
+	c.Assert(events, HasLen, 2)
+	c.Check(events[0], DeepEquals, Event{Type: QuorumReached, LedgerSequence: 100, LedgerHash: good, Validations: 4})
+	c.Check(events[0].String(), Matches, "QuorumReached: 100 0100.* validations: 4")
+	c.Check(events[1], DeepEquals, Event{Type: Disagreement, LedgerSequence: 100, LedgerHash: good, Validator: publicKey(f.masters[1]), Validated: bad})
+	c.Check(events[1].String(), Matches, "Disagreement: n.* 100 0100.* validated: 0200.*")
+	seq, hash := f.monitor.Validated()
+	c.Check(seq, Equals, uint32(100))
+	c.Check(hash, Equals, good)
+
+	// Validator 4 stops, and falls behind
+	for seq := uint32(101); seq <= 106; seq++ {
+		for i := 0; i < 3; i++ {
+			c.Check(f.add(c, i, seq, good), HasLen, 0)
+		}
+		events = f.add(c, 3, seq, good)
+		c.Check(events[0].Type, Equals, QuorumReached)
+		if seq < 106 {
+			c.Check(events, HasLen, 1)
+		}
+	}
+	c.Assert(events, HasLen, 2)
+	c.Check(events[1], DeepEquals, Event{Type: Lagging, LedgerSequence: 106, LedgerHash: good, Validator: publicKey(f.masters[4]), Latest: 100})
+	c.Check(events[1].String(), Matches, "Lagging: n.* 106 latest: 100")
+	events = f.add(c, 4, 103, good)
+	c.Assert(events, HasLen, 1)
+	c.Check(events[0], DeepEquals, Event{Type: Recovered, LedgerSequence: 103, LedgerHash: good, Validator: publicKey(f.masters[4])})
+	c.Check(f.add(c, 4, 106, good), HasLen, 0)
+
+	// Late disagreement with a validated ledger
+	events = f.add(c, 4, 104, bad)
+	c.Assert(events, HasLen, 1)
+	c.Check(events[0].Type, Equals, Disagreement)
+
+	// Partial and untrusted validations are not counted
+	events, err := f.monitor.Add(f.validation(c, 0, 107, good, false))
+	c.Check(err, IsNil)
+	c.Check(events, HasLen, 0)
+	f.monitor.SetTrusted([]data.PublicKey{publicKey(f.masters[0])}, 0)
+	c.Check(f.add(c, 1, 107, good), HasLen, 0)
+	events = f.add(c, 0, 107, good)
+	c.Assert(events, HasLen, 1)
+	c.Check(events[0].Validations, Equals, 1)
+}
+
+func (s *ValidatorsSuite) TestMonitorRefusals(c *C) {
+	f := newMonitorFixture(c, 2)
+
+	v := f.validation(c, 0, 100, data.Hash256{1}, true)
+	v.LedgerSequence++
+	_, err := f.monitor.Add(v)
+	c.Check(err, ErrorMatches, "Bad validation signature from .*")
+
+	// Validator 1 has a manifest, so must not sign with its master key
+	f.signing[1] = f.masters[1]
+	_, err = f.monitor.Add(f.validation(c, 1, 100, data.Hash256{1}, true))
+	c.Check(err, ErrorMatches, "Validation signed by master key .*")
+
+	// Once revoked its validations are no longer counted
+	f.signing[1] = key(c, "monitor 1 ephemeral", true)
+	c.Assert(f.manifests.Apply(manifest(c, f.masters[1], nil, data.RevokedSequence)), IsNil)
+	_, err = f.monitor.Add(f.validation(c, 1, 100, data.Hash256{1}, true))
+	c.Check(err, IsNil)
+	c.Check(f.add(c, 0, 100, data.Hash256{1}), HasLen, 0)
+}
@@ -0,0 +1,280 @@
+package validators
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/parihaaraka/ripple/crypto"
+	"github.com/parihaaraka/ripple/data"
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type ValidatorsSuite struct{}
+
+var _ = Suite(&ValidatorsSuite{})
+
+func key(c *C, seed string, ed25519 bool) crypto.Key {
+	if ed25519 {
+		k, err := crypto.NewEd25519Key([]byte(seed))
+		c.Assert(err, IsNil)
+		return k
+	}
+	k, err := crypto.NewECDSAKey([]byte(seed))
+	c.Assert(err, IsNil)
+	return k
+}
+
+func publicKey(k crypto.Key) data.PublicKey {
+	var pub data.PublicKey
+	copy(pub[:], k.Public(nil))
+	return pub
+}
+
+func manifest(c *C, master, ephemeral crypto.Key, sequence uint32) *data.Manifest {
+	m := &data.Manifest{Sequence: sequence}
+	c.Assert(data.SignManifest(m, master, ephemeral), IsNil)
+	return m
+}
+
+func encode(c *C, m *data.Manifest) string {
+	s, err := m.Base64()
+	c.Assert(err, IsNil)
+	return s
+}
+
+// The keys used to make the publications in testdata
+type keys struct {
+	master, ephemeral       crypto.Key
+	validators, validatorsE []crypto.Key
+}
+
+func newKeys(c *C) *keys {
+	k := &keys{
+		master:    key(c, "publisher master", true),
+		ephemeral: key(c, "publisher ephemeral", false),
+	}
+	for i := 0; i < 4; i++ {
+		k.validators = append(k.validators, key(c, fmt.Sprintf("validator %d", i), i%2 == 0))
+		k.validatorsE = append(k.validatorsE, key(c, fmt.Sprintf("validator %d ephemeral", i), i%2 == 1))
+	}
+	return k
+}
+
+func (k *keys) blob(c *C, sequence uint32, effective, expiration uint32, validators int) (string, string) {
+	b := map[string]interface{}{
+		"sequence":   sequence,
+		"expiration": expiration,
+	}
+	if effective != 0 {
+		b["effective"] = effective
+	}
+	var list []map[string]string
+	for i := 0; i < validators; i++ {
+		list = append(list, map[string]string{
+			"validation_public_key": publicKey(k.validators[i]).String(),
+			"manifest":              encode(c, manifest(c, k.validators[i], k.validatorsE[i], 1)),
+		})
+	}
+	b["validators"] = list
+	raw, err := json.Marshal(b)
+	c.Assert(err, IsNil)
+	sig, err := crypto.Sign(k.ephemeral.Private(nil), crypto.Sha512Half(raw), raw)
+	c.Assert(err, IsNil)
+	return base64.StdEncoding.EncodeToString(raw), fmt.Sprintf("%X", sig)
+}
+
+func (k *keys) publication(c *C, version int, m *data.Manifest, blobs ...[2]string) []byte {
+	p := map[string]interface{}{
+		"public_key": publicKey(k.master).String(),
+		"manifest":   encode(c, m),
+		"version":    version,
+	}
+	if version == 1 {
+		p["blob"], p["signature"] = blobs[0][0], blobs[0][1]
+	} else {
+		var v2 []map[string]string
+		for _, b := range blobs {
+			v2 = append(v2, map[string]string{"blob": b[0], "signature": b[1]})
+		}
+		p["blobs_v2"] = v2
+	}
+	b, err := json.MarshalIndent(p, "", "  ")
+	c.Assert(err, IsNil)
+	return b
+}
+
+func at(t uint32) time.Time {
+	return data.NewRippleTime(t).Time()
+}
+
+func (s *ValidatorsSuite) TestManifest(c *C) {
+	master, ephemeral := key(c, "master", true), key(c, "ephemeral", false)
+	m := manifest(c, master, ephemeral, 3)
+	c.Assert(m.Verify(), IsNil)
+	decoded, err := data.NewManifestFromBase64(encode(c, m))
+	c.Assert(err, IsNil)
+	c.Check(decoded, DeepEquals, m)
+	c.Check(decoded.Verify(), IsNil)
+	c.Check(decoded.String(), Matches, "Manifest: n.* 3 n.*")
+
+	domain := data.VariableLength("example.com")
+	decoded.Domain = &domain
+	c.Check(decoded.Verify(), ErrorMatches, "Bad master signature for .*")
+	decoded.Domain = nil
+	(*decoded.Signature)[10] ^= 1
+	c.Check(decoded.Verify(), ErrorMatches, "Bad ephemeral signature for .*")
+
+	// Ed25519 ephemeral keys and domains are signed too
+	m = &data.Manifest{Sequence: 1, Domain: &domain}
+	c.Assert(data.SignManifest(m, key(c, "master", false), key(c, "ephemeral", true)), IsNil)
+	c.Check(m.Verify(), IsNil)
+
+	revocation := manifest(c, master, nil, data.RevokedSequence)
+	c.Check(revocation.Verify(), IsNil)
+	c.Check(revocation.Revoked(), Equals, true)
+	c.Check(revocation.String(), Matches, "Manifest: n.* revoked")
+	incomplete := manifest(c, master, nil, 4)
+	c.Check(incomplete.Verify(), ErrorMatches, "No ephemeral key for .*")
+}
+
+func (s *ValidatorsSuite) TestManifests(c *C) {
+	master, master2 := key(c, "master", true), key(c, "master 2", false)
+	ephemeral1, ephemeral2 := key(c, "ephemeral 1", false), key(c, "ephemeral 2", true)
+	manifests := NewManifests()
+
+	c.Check(manifests.Master(publicKey(ephemeral1)), Equals, publicKey(ephemeral1))
+	c.Assert(manifests.Apply(manifest(c, master, ephemeral1, 1)), IsNil)
+	c.Check(manifests.Master(publicKey(ephemeral1)), Equals, publicKey(master))
+	signing, ok := manifests.Signing(publicKey(master))
+	c.Check(ok, Equals, true)
+	c.Check(signing, Equals, publicKey(ephemeral1))
+
+	c.Check(manifests.Apply(manifest(c, master, ephemeral2, 1)), Equals, ErrStaleManifest)
+	c.Check(manifests.Apply(manifest(c, master2, ephemeral1, 1)), ErrorMatches, "Ephemeral key .* belongs to .*")
+	c.Check(manifests.Apply(manifest(c, master2, master, 1)), ErrorMatches, "Ephemeral key .* is a master key")
+	c.Check(manifests.Apply(manifest(c, ephemeral1, ephemeral2, 1)), ErrorMatches, "Master key .* is an ephemeral key of .*")
+
+	c.Assert(manifests.Apply(manifest(c, master, ephemeral2, 2)), IsNil)
+	c.Check(manifests.Master(publicKey(ephemeral1)), Equals, publicKey(ephemeral1))
+	c.Check(manifests.Master(publicKey(ephemeral2)), Equals, publicKey(master))
+
+	c.Assert(manifests.Apply(manifest(c, master, nil, data.RevokedSequence)), IsNil)
+	c.Check(manifests.Revoked(publicKey(master)), Equals, true)
+	_, ok = manifests.Signing(publicKey(master))
+	c.Check(ok, Equals, false)
+	c.Check(manifests.Master(publicKey(ephemeral2)), Equals, publicKey(ephemeral2))
+	c.Check(manifests.Apply(manifest(c, master, nil, data.RevokedSequence)), Equals, ErrRevoked)
+}
+
+func (s *ValidatorsSuite) TestLoad(c *C) {
+	k := newKeys(c)
+	for _, file := range []string{"testdata/validator_list_v1.json", "testdata/validator_list_v2.json"} {
+		lists := NewLists(nil, publicKey(k.master))
+		loaded, err := lists.Load(file)
+		c.Assert(err, IsNil, Commentf(file))
+		c.Assert(loaded, Not(HasLen), 0)
+		c.Check(loaded[0].Publisher, Equals, publicKey(k.master))
+		c.Check(loaded[0].Sequence, Equals, uint32(1))
+		c.Check(loaded[0].Validators, HasLen, 3)
+		c.Check(loaded[0].String(), Matches, "Validator list: n.* 1 validators: 3 expires: .*")
+		trusted := lists.Trusted(at(1000))
+		c.Assert(trusted, HasLen, 3)
+		for _, v := range loaded[0].Validators {
+			c.Assert(v.Manifest, NotNil)
+			c.Check(lists.Manifests.Master(*v.Manifest.SigningPubKey), Equals, v.PublicKey)
+		}
+		c.Check(lists.Trusted(at(1000000)), HasLen, 0)
+
+		_, err = lists.Load(file)
+		c.Check(err, Equals, ErrStaleList)
+		_, err = NewLists(nil, publicKey(k.validators[0])).Load(file)
+		c.Check(err, ErrorMatches, "Untrusted publisher: .*")
+	}
+}
+
+// The publications in testdata/published_*.json were signed outside this
+// package, following rippled's manifest and validator list formats.
+func (s *ValidatorsSuite) TestPublished(c *C) {
+	var publisher data.PublicKey
+	c.Assert(publisher.UnmarshalText([]byte("ED8E9D804C3D4F5A13A879057F7ADD693E65A650CC4C0CB1AA9E5BF0467B28B014")), IsNil)
+	for _, test := range []struct {
+		file         string
+		lists, later int
+	}{
+		{"testdata/published_v1.json", 1, 2},
+		{"testdata/published_v2.json", 2, 3},
+	} {
+		msg := Commentf(test.file)
+		b, err := ioutil.ReadFile(test.file)
+		c.Assert(err, IsNil, msg)
+		var p struct{ Manifest string }
+		c.Assert(json.Unmarshal(b, &p), IsNil, msg)
+		m, err := data.NewManifestFromBase64(p.Manifest)
+		c.Assert(err, IsNil, msg)
+		c.Check(m.Verify(), IsNil, msg)
+		c.Check(m.PublicKey, Equals, publisher, msg)
+		c.Check(m.SigningPubKey.String(), Equals, "02FB99DFBCA6C890F87314C0C59D86B39D83B4C5E18E32B9AA62853F7D1CC83076", msg)
+
+		lists := NewLists(nil, publisher)
+		loaded, err := lists.Read(bytes.NewReader(b))
+		c.Assert(err, IsNil, msg)
+		c.Assert(loaded, HasLen, test.lists, msg)
+		validator := loaded[0].Validators[0]
+		c.Assert(validator.Manifest, NotNil, msg)
+		c.Check(validator.Manifest.Verify(), IsNil, msg)
+		c.Check(string(*validator.Manifest.Domain), Equals, "example.com", msg)
+		c.Check(lists.Manifests.Master(*validator.Manifest.SigningPubKey), Equals, validator.PublicKey, msg)
+		c.Check(lists.Trusted(at(800000000)), HasLen, 2, msg)
+		c.Check(lists.Trusted(at(950000000)), HasLen, test.later, msg)
+		c.Check(lists.Trusted(at(1100000000)), HasLen, 0, msg)
+	}
+}
+
+func (s *ValidatorsSuite) TestLists(c *C) {
+	k := newKeys(c)
+	lists := NewLists(nil, publicKey(k.master))
+	m := manifest(c, k.master, k.ephemeral, 1)
+
+	// Version 2 lists take effect in turn
+	b1, s1 := k.blob(c, 1, 0, 1000, 2)
+	b2, s2 := k.blob(c, 2, 500, 2000, 4)
+	loaded, err := lists.Read(bytes.NewReader(k.publication(c, 2, m, [2]string{b1, s1}, [2]string{b2, s2})))
+	c.Assert(err, IsNil)
+	c.Check(loaded, HasLen, 2)
+	c.Check(lists.Trusted(at(100)), HasLen, 2)
+	c.Check(lists.Trusted(at(600)), HasLen, 4)
+	c.Check(lists.Current(at(600))[0].Sequence, Equals, uint32(2))
+	c.Check(lists.Trusted(at(2000)), HasLen, 0)
+
+	// A bad signature, or a blob signed by another key, is refused
+	b3, s3 := k.blob(c, 3, 0, 3000, 1)
+	raw, _ := base64.StdEncoding.DecodeString(b3)
+	_, err = lists.Read(bytes.NewReader(k.publication(c, 1, m, [2]string{base64.StdEncoding.EncodeToString(append(raw, ' ')), s3})))
+	c.Check(err, ErrorMatches, "Bad validator list signature from .*")
+	other := manifest(c, k.master, key(c, "other ephemeral", true), 2)
+	_, err = lists.Read(bytes.NewReader(k.publication(c, 1, other, [2]string{b3, s3})))
+	c.Check(err, ErrorMatches, "Bad validator list signature from .*")
+	_, err = lists.Read(bytes.NewReader(k.publication(c, 1, manifest(c, k.validators[1], k.ephemeral, 5), [2]string{b3, s3})))
+	c.Check(err, ErrorMatches, "Manifest of .* signs list of .*")
+
+	// The publisher's new ephemeral key replaced the one which signed b3
+	_, err = lists.Read(bytes.NewReader(k.publication(c, 1, m, [2]string{b3, s3})))
+	c.Check(err, ErrorMatches, "Bad validator list signature from .*")
+
+	// Revoked validators are no longer trusted
+	c.Assert(lists.Manifests.Apply(manifest(c, k.validators[0], nil, data.RevokedSequence)), IsNil)
+	c.Check(lists.Trusted(at(600)), HasLen, 3)
+
+	// Nor is any list from a revoked publisher
+	revocation := manifest(c, k.master, nil, data.RevokedSequence)
+	_, err = lists.Read(bytes.NewReader(k.publication(c, 1, revocation, [2]string{b3, s3})))
+	c.Check(err, Equals, ErrRevoked)
+	c.Check(lists.Trusted(at(600)), HasLen, 0)
+}
@@ -0,0 +1,120 @@
+package websockets
+
+import (
+	"context"
+	"math"
+	"reflect"
+
+	"github.com/parihaaraka/ripple/data"
+)
+
+// Networks with ids above this must have NetworkID set in every transaction.
+const legacyNetworkID = 1024
+
+// AutofillOptions control the values chosen by Autofill.
+type AutofillOptions struct {
+	// Applied to the open ledger fee, to give room for fee rises.
+	FeeMultiplier float64
+	// The highest fee in drops which will be set. Zero for no limit.
+	MaxFee int64
+	// Ledgers after the current one in which the transaction may be validated.
+	LedgerOffset uint32
+}
+
+// DefaultAutofillOptions are used when Autofill is given no options.
+var DefaultAutofillOptions = AutofillOptions{
+	FeeMultiplier: 1,
+	MaxFee:        1000000,
+	LedgerOffset:  20,
+}
+
+// ticketSequence returns the TicketSequence of tx, for the
+// transaction types which have one.
+func ticketSequence(tx data.Transaction) *uint32 {
+	f := reflect.Indirect(reflect.ValueOf(tx)).FieldByName("TicketSequence")
+	if !f.IsValid() || f.IsNil() {
+		return nil
+	}
+	return f.Interface().(*uint32)
+}
+
+// Autofill sets the fields of tx needed for submission, where they are not
+// already set. The Sequence is the account's next sequence, or zero when a
+// TicketSequence is used. The Fee is the open ledger fee scaled and capped
+// by opts, and LastLedgerSequence allows opts.LedgerOffset ledgers for
+// validation. NetworkID is set for networks which require it.
+// Nil opts uses DefaultAutofillOptions.
+func Autofill(client Client, tx data.Transaction, opts *AutofillOptions) error {
+	return AutofillContext(context.Background(), client, tx, opts)
+}
+
+// AutofillContext is like Autofill, but gives up when ctx is done.
+func AutofillContext(ctx context.Context, client Client, tx data.Transaction, opts *AutofillOptions) error {
+	if opts == nil {
+		opts = &DefaultAutofillOptions
+	}
+	base := tx.GetBase()
+	tickets := ticketSequence(tx) != nil
+	if tickets {
+		base.Sequence = 0
+	}
+	if (base.Sequence == 0 && !tickets) || base.LastLedgerSequence == nil {
+		info, err := client.AccountInfoContext(ctx, base.Account, "current")
+		if err != nil {
+			return err
+		}
+		if base.Sequence == 0 && !tickets && info.AccountData.Sequence != nil {
+			base.Sequence = *info.AccountData.Sequence
+		}
+		if base.LastLedgerSequence == nil {
+			last := info.LedgerSequence + opts.LedgerOffset
+			base.LastLedgerSequence = &last
+		}
+	}
+	var server *ServerInfoResult
+	if base.NetworkID == nil {
+		var err error
+		if server, err = client.ServerInfoContext(ctx); err != nil {
+			return err
+		}
+		if id := server.Info.NetworkID; id > legacyNetworkID {
+			base.NetworkID = &id
+		}
+	}
+	if base.Fee.IsZero() {
+		drops, err := openLedgerFee(ctx, client, server)
+		if err != nil {
+			return err
+		}
+		drops = math.Ceil(drops * opts.FeeMultiplier)
+		if opts.MaxFee > 0 && drops > float64(opts.MaxFee) {
+			drops = float64(opts.MaxFee)
+		}
+		fee, err := data.NewNativeValue(int64(drops))
+		if err != nil {
+			return err
+		}
+		base.Fee = *fee
+	}
+	return nil
+}
+
+// openLedgerFee returns the fee in drops for inclusion in the open ledger.
+// Servers which do not support the fee command are asked for their load
+// factor instead.
+func openLedgerFee(ctx context.Context, client Client, server *ServerInfoResult) (float64, error) {
+	fee, err := client.FeeContext(ctx)
+	if err == nil {
+		drops, _ := fee.Drops.OpenLedgerFee.Rat().Float64()
+		return drops, nil
+	}
+	if _, ok := err.(*CommandError); !ok {
+		return 0, err
+	}
+	if server == nil {
+		if server, err = client.ServerInfoContext(ctx); err != nil {
+			return 0, err
+		}
+	}
+	return server.Info.ValidatedLedger.BaseFeeXrp * 1e6 * math.Max(server.Info.LoadFactor, 1), nil
+}
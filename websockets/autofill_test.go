@@ -0,0 +1,85 @@
+package websockets
+
+import (
+	"github.com/parihaaraka/ripple/data"
+	. "gopkg.in/check.v1"
+)
+
+type AutofillSuite struct{}
+
+var _ = Suite(&AutofillSuite{})
+
+// autofillServer is on the given network, and supports the fee command if fee is set.
+func autofillServer(networkID int, fee string) *fakeServer {
+	return newFakeServer(func(request map[string]interface{}) interface{} {
+		switch request["command"] {
+		case "account_info":
+			return response(request, map[string]interface{}{
+				"ledger_current_index": 100,
+				"account_data":         map[string]interface{}{"LedgerEntryType": "AccountRoot", "Sequence": 5},
+			})
+		case "server_info":
+			return response(request, map[string]interface{}{"info": map[string]interface{}{
+				"network_id":       networkID,
+				"load_factor":      1.5,
+				"validated_ledger": map[string]interface{}{"base_fee_xrp": 0.00001},
+			}})
+		case "fee":
+			if fee == "" {
+				return errorResponse(request, "unknownCmd", 32)
+			}
+			return response(request, map[string]interface{}{"drops": map[string]interface{}{"open_ledger_fee": fee}})
+		}
+		return nil
+	})
+}
+
+func (s *AutofillSuite) TestAutofill(c *C) {
+	server := autofillServer(0, "12")
+	defer server.Close()
+	r, err := NewRemote(server.Endpoint())
+	c.Assert(err, IsNil)
+	defer r.Close()
+
+	tx := &data.Payment{}
+	c.Assert(Autofill(r, tx, &AutofillOptions{FeeMultiplier: 1.5, LedgerOffset: 4}), IsNil)
+	c.Check(tx.Sequence, Equals, uint32(5))
+	c.Check(*tx.LastLedgerSequence, Equals, uint32(104))
+	c.Check(tx.Fee.String(), Equals, "0.000018")
+	c.Check(tx.NetworkID, IsNil)
+
+	// Capped fee
+	tx = &data.Payment{}
+	c.Assert(Autofill(r, tx, &AutofillOptions{FeeMultiplier: 10, MaxFee: 100}), IsNil)
+	c.Check(tx.Fee.String(), Equals, "0.0001")
+
+	// Existing fields are kept, except Sequence with a ticket
+	last, ticket := uint32(7), uint32(9)
+	tx = &data.Payment{TicketSequence: &ticket}
+	tx.Sequence, tx.LastLedgerSequence, tx.Fee = 3, &last, *mustValue(c, "15")
+	c.Assert(Autofill(r, tx, nil), IsNil)
+	c.Check(tx.Sequence, Equals, uint32(0))
+	c.Check(*tx.LastLedgerSequence, Equals, uint32(7))
+	c.Check(tx.Fee.String(), Equals, "0.000015")
+}
+
+func (s *AutofillSuite) TestAutofillSidechain(c *C) {
+	server := autofillServer(21338, "")
+	defer server.Close()
+	r, err := NewRemote(server.Endpoint())
+	c.Assert(err, IsNil)
+	defer r.Close()
+
+	tx := &data.AccountSet{}
+	c.Assert(Autofill(r, tx, nil), IsNil)
+	c.Assert(tx.NetworkID, NotNil)
+	c.Check(*tx.NetworkID, Equals, uint32(21338))
+	// From the load factor, without the fee command
+	c.Check(tx.Fee.String(), Equals, "0.000015")
+}
+
+func mustValue(c *C, s string) *data.Value {
+	v, err := data.NewValue(s, true)
+	c.Assert(err, IsNil)
+	return v
+}
@@ -0,0 +1,77 @@
+package websockets
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/parihaaraka/ripple/data"
+)
+
+// Client is the set of request/response commands available over both
+// websockets, using Remote, and JSON-RPC over HTTP, using HTTPRemote.
+type Client interface {
+	Tx(hash data.Hash256) (*TxResult, error)
+	TxContext(ctx context.Context, hash data.Hash256) (*TxResult, error)
+	AccountTx(account data.Account, pageSize int, minLedger, maxLedger int64) chan *data.TransactionWithMetaData
+	AccountTxContext(ctx context.Context, account data.Account, pageSize int, minLedger, maxLedger int64) chan *data.TransactionWithMetaData
+	Submit(tx data.Transaction) (*SubmitResult, error)
+	SubmitContext(ctx context.Context, tx data.Transaction) (*SubmitResult, error)
+	LedgerData(ledger interface{}, marker *data.Hash256) (*LedgerDataResult, error)
+	LedgerDataContext(ctx context.Context, ledger interface{}, marker *data.Hash256) (*LedgerDataResult, error)
+	StreamLedgerData(ledger interface{}) chan data.LedgerEntrySlice
+	StreamLedgerDataContext(ctx context.Context, ledger interface{}) chan data.LedgerEntrySlice
+	Ledger(ledger interface{}, transactions bool) (*LedgerResult, error)
+	LedgerContext(ctx context.Context, ledger interface{}, transactions bool) (*LedgerResult, error)
+	LedgerHeader(ledger interface{}) (*LedgerHeaderResult, error)
+	LedgerHeaderContext(ctx context.Context, ledger interface{}) (*LedgerHeaderResult, error)
+	RipplePathFind(src, dest data.Account, amount data.Amount, srcCurr *[]data.Currency) (*RipplePathFindResult, error)
+	RipplePathFindContext(ctx context.Context, src, dest data.Account, amount data.Amount, srcCurr *[]data.Currency) (*RipplePathFindResult, error)
+	AccountInfo(a data.Account, ledger interface{}) (*AccountInfoResult, error)
+	AccountInfoContext(ctx context.Context, a data.Account, ledger interface{}) (*AccountInfoResult, error)
+	ServerInfo() (*ServerInfoResult, error)
+	ServerInfoContext(ctx context.Context) (*ServerInfoResult, error)
+	DepositAuthorized(source, dest data.Account, ledger interface{}) (*DepositAuthorizedResult, error)
+	DepositAuthorizedContext(ctx context.Context, source, dest data.Account, ledger interface{}) (*DepositAuthorizedResult, error)
+	AccountLines(account data.Account, ledgerIndex interface{}, peer string) (*AccountLinesResult, error)
+	AccountLinesContext(ctx context.Context, account data.Account, ledgerIndex interface{}, peer string) (*AccountLinesResult, error)
+	AccountOffers(account data.Account, ledgerIndex interface{}) (*AccountOffersResult, error)
+	AccountOffersContext(ctx context.Context, account data.Account, ledgerIndex interface{}) (*AccountOffersResult, error)
+	BookOffers(taker data.Account, ledgerIndex interface{}, pays, gets data.Asset) (*BookOffersResult, error)
+	BookOffersContext(ctx context.Context, taker data.Account, ledgerIndex interface{}, pays, gets data.Asset) (*BookOffersResult, error)
+	Fee() (*FeeResult, error)
+	FeeContext(ctx context.Context) (*FeeResult, error)
+	Close()
+}
+
+var (
+	_ Client = (*Remote)(nil)
+	_ Client = (*HTTPRemote)(nil)
+)
+
+// NewClient returns a Remote for ws:// and wss:// endpoints
+// and an HTTPRemote for http:// and https:// endpoints.
+func NewClient(endpoint string) (Client, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "ws", "wss":
+		return NewRemote(endpoint)
+	case "http", "https":
+		return NewHTTPRemote(endpoint)
+	default:
+		return nil, fmt.Errorf("Unsupported scheme: %s", u.Scheme)
+	}
+}
+
+// transport sends a command and fills in its result or error.
+type transport interface {
+	send(ctx context.Context, cmd Syncer, c *Command) error
+}
+
+// commands implements the Client methods over a transport.
+type commands struct {
+	transport
+}
@@ -1,7 +1,9 @@
 package websockets
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sync/atomic"
 
@@ -36,11 +38,7 @@ func (c *Command) Done() {
 }
 
 func (c *Command) Fail(message string) {
-	c.CommandError = &CommandError{
-		Name:    "Client Error",
-		Code:    -1,
-		Message: message,
-	}
+	c.CommandError = clientError(message)
 	c.Ready <- struct{}{}
 }
 
@@ -52,11 +50,45 @@ func (e *CommandError) Error() string {
 	return fmt.Sprintf("%s %d %s %s", e.Name, e.Code, e.Message, e.Exception)
 }
 
+func clientError(message string) *CommandError {
+	return &CommandError{
+		Name:    "Client Error",
+		Code:    -1,
+		Message: message,
+	}
+}
+
+// ContextError is returned by the Context variants of the Remote methods
+// when the context is cancelled or its deadline passes before the server
+// has responded. The command is withdrawn from the pending set, so a late
+// response is discarded.
+type ContextError struct {
+	Name string
+	Id   uint64
+	Err  error
+}
+
+func (e *ContextError) Error() string {
+	return fmt.Sprintf("%s %d: %s", e.Name, e.Id, e.Err)
+}
+
+func (e *ContextError) Unwrap() error {
+	return e.Err
+}
+
+// Timeout reports whether the context deadline passed,
+// as opposed to the context being cancelled.
+func (e *ContextError) Timeout() bool {
+	return errors.Is(e.Err, context.DeadlineExceeded)
+}
+
 func newCommand(command string) *Command {
 	return &Command{
-		Id:    atomic.AddUint64(&counter, 1),
-		Name:  command,
-		Ready: make(chan struct{}),
+		Id:   atomic.AddUint64(&counter, 1),
+		Name: command,
+		// Buffered so that the run loop never blocks on a caller
+		// which has already given up on the response.
+		Ready: make(chan struct{}, 1),
 	}
 }
 
@@ -318,9 +350,10 @@ type ServerInfoResult struct {
 			ConvergeTimeS float64 `json:"converge_time_s"`
 			Proposers     int     `json:"proposers"`
 		} `json:"last_close"`
-		LoadFactor      int    `json:"load_factor"`
-		PubkeyNode      string `json:"pubkey_node"`
-		PublishedLedger string `json:"published_ledger"`
+		LoadFactor      float64 `json:"load_factor"`
+		NetworkID       uint32  `json:"network_id"`
+		PubkeyNode      string  `json:"pubkey_node"`
+		PublishedLedger string  `json:"published_ledger"`
 		Reporting       struct {
 			EtlSources []struct {
 				Connected              bool   `json:"connected"`
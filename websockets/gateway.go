@@ -0,0 +1,143 @@
+package websockets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/parihaaraka/ripple/data"
+)
+
+type GatewayBalancesCommand struct {
+	*Command
+	Account     data.Account           `json:"account"`
+	HotWallets  []data.Account         `json:"hotwallet,omitempty"`
+	LedgerIndex interface{}            `json:"ledger_index,omitempty"`
+	Strict      bool                   `json:"strict"`
+	Result      *GatewayBalancesResult `json:"result,omitempty"`
+}
+
+type GatewayBalance struct {
+	Currency data.Currency       `json:"currency"`
+	Value    data.NonNativeValue `json:"value"`
+}
+
+// GatewayBalancesResult is what an issuer owes and holds. Balances are
+// those of the hot wallets, which are left out of the obligations, and
+// assets are what others have issued to the issuer.
+type GatewayBalancesResult struct {
+	Account        data.Account                          `json:"account"`
+	LedgerSequence *uint32                               `json:"ledger_index,omitempty"`
+	LedgerCurrent  *uint32                               `json:"ledger_current_index,omitempty"`
+	Obligations    map[data.Currency]data.NonNativeValue `json:"obligations,omitempty"`
+	Balances       map[data.Account][]GatewayBalance     `json:"balances,omitempty"`
+	FrozenBalances map[data.Account][]GatewayBalance     `json:"frozen_balances,omitempty"`
+	Assets         map[data.Account][]GatewayBalance     `json:"assets,omitempty"`
+	Locked         map[data.Currency]data.NonNativeValue `json:"locked,omitempty"`
+}
+
+// GatewayBalances returns the obligations of the issuer account, leaving
+// out the balances of its hotWallets, which are listed separately.
+func (r *commands) GatewayBalances(account data.Account, hotWallets []data.Account, ledgerIndex interface{}) (*GatewayBalancesResult, error) {
+	return r.GatewayBalancesContext(context.Background(), account, hotWallets, ledgerIndex)
+}
+
+// GatewayBalancesContext is like GatewayBalances, but gives up when ctx is
+// done.
+func (r *commands) GatewayBalancesContext(ctx context.Context, account data.Account, hotWallets []data.Account, ledgerIndex interface{}) (*GatewayBalancesResult, error) {
+	cmd := &GatewayBalancesCommand{
+		Command:     newCommand("gateway_balances"),
+		Account:     account,
+		HotWallets:  hotWallets,
+		LedgerIndex: ledgerIndex,
+		Strict:      true,
+	}
+	if err := r.send(ctx, cmd, cmd.Command); err != nil {
+		return nil, err
+	}
+	return cmd.Result, nil
+}
+
+type NoRippleCheckCommand struct {
+	*Command
+	Account      data.Account         `json:"account"`
+	Role         string               `json:"role"`
+	Transactions bool                 `json:"transactions"`
+	Limit        uint32               `json:"limit,omitempty"`
+	LedgerIndex  interface{}          `json:"ledger_index,omitempty"`
+	Result       *NoRippleCheckResult `json:"result,omitempty"`
+}
+
+// NoRippleCheckResult has the problems with the rippling settings of an
+// account and the AccountSet and TrustSet transactions which fix them,
+// with sequences following on from the account's, ready to be signed.
+type NoRippleCheckResult struct {
+	LedgerSequence *uint32               `json:"ledger_index,omitempty"`
+	LedgerCurrent  *uint32               `json:"ledger_current_index,omitempty"`
+	Problems       []string              `json:"problems"`
+	Transactions   SuggestedTransactions `json:"transactions,omitempty"`
+}
+
+// SuggestedTransactions are transactions in JSON without metadata, whose
+// Fee may be a number rather than a string of drops.
+type SuggestedTransactions []data.Transaction
+
+func (s *SuggestedTransactions) UnmarshalJSON(b []byte) error {
+	var raw []map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	for _, fields := range raw {
+		var txType string
+		if err := json.Unmarshal(fields["TransactionType"], &txType); err != nil {
+			return fmt.Errorf("Bad TransactionType: %s", err)
+		}
+		tx := data.GetTxFactoryByType(txType)()
+		if tx.GetTransactionType().String() != txType {
+			return fmt.Errorf("Unknown TransactionType: %s", txType)
+		}
+		if fee, ok := fields["Fee"]; ok && len(fee) > 0 && fee[0] != '"' {
+			fields["Fee"] = json.RawMessage(strconv.Quote(string(fee)))
+		}
+		b, err := json.Marshal(fields)
+		if err != nil {
+			return err
+		}
+		if b, err = data.ExpandXAddresses(b); err != nil {
+			return err
+		}
+		if err := json.Unmarshal(b, tx); err != nil {
+			return err
+		}
+		*s = append(*s, tx)
+	}
+	return nil
+}
+
+// NoRippleCheck compares the rippling settings of account with those
+// recommended for a gateway, which should enable DefaultRipple and allow
+// rippling on its trust lines, or for a user, which should not.
+func (r *commands) NoRippleCheck(account data.Account, gateway bool, ledgerIndex interface{}) (*NoRippleCheckResult, error) {
+	return r.NoRippleCheckContext(context.Background(), account, gateway, ledgerIndex)
+}
+
+// NoRippleCheckContext is like NoRippleCheck, but gives up when ctx is
+// done.
+func (r *commands) NoRippleCheckContext(ctx context.Context, account data.Account, gateway bool, ledgerIndex interface{}) (*NoRippleCheckResult, error) {
+	cmd := &NoRippleCheckCommand{
+		Command:      newCommand("noripple_check"),
+		Account:      account,
+		Role:         "user",
+		Transactions: true,
+		Limit:        pageSize,
+		LedgerIndex:  ledgerIndex,
+	}
+	if gateway {
+		cmd.Role = "gateway"
+	}
+	if err := r.send(ctx, cmd, cmd.Command); err != nil {
+		return nil, err
+	}
+	return cmd.Result, nil
+}
@@ -0,0 +1,49 @@
+package websockets
+
+import (
+	"github.com/parihaaraka/ripple/crypto"
+	"github.com/parihaaraka/ripple/data"
+	. "gopkg.in/check.v1"
+)
+
+func (s *MessagesSuite) TestGatewayBalancesResponse(c *C) {
+	msg := &GatewayBalancesCommand{}
+	readResponseFile(c, msg, "testdata/gateway_balances.json")
+
+	c.Assert(msg.Result.Account.String(), Equals, "rMwjYedjc7qqtKYVLiAccJSmCwih4LnE2q")
+	c.Assert(*msg.Result.LedgerSequence, Equals, uint32(14483195))
+	usd, err := data.NewCurrency("USD")
+	c.Assert(err, IsNil)
+	c.Assert(msg.Result.Obligations, HasLen, 4)
+	c.Assert(msg.Result.Obligations[usd].String(), Equals, "1997134.20229482")
+	hot, err := data.NewAccountFromAddress("ra7JkEzrgeKHdzKgo4EUUVBnxggY4z37kt")
+	c.Assert(err, IsNil)
+	c.Assert(msg.Result.Balances[*hot], HasLen, 1)
+	c.Assert(msg.Result.Balances[*hot][0].Currency, Equals, usd)
+	c.Assert(msg.Result.Balances[*hot][0].Value.String(), Equals, "13857.70416")
+	c.Assert(msg.Result.Assets, HasLen, 1)
+}
+
+func (s *MessagesSuite) TestNoRippleCheckResponse(c *C) {
+	msg := &NoRippleCheckCommand{}
+	readResponseFile(c, msg, "testdata/noripple_check.json")
+
+	c.Assert(msg.Result.Problems, HasLen, 3)
+	c.Assert(msg.Result.Transactions, HasLen, 2)
+	set := msg.Result.Transactions[0].(*data.AccountSet)
+	c.Assert(*set.SetFlag, Equals, uint32(8))
+	c.Assert(set.Sequence, Equals, uint32(1406))
+	c.Assert(set.Fee.String(), Equals, "0.01")
+	trust := msg.Result.Transactions[1].(*data.TrustSet)
+	c.Assert(trust.LimitAmount.String(), Equals, "0/XAU/r3vi7mWxru9rJCxETCyA1CHvzL96eZWx5z")
+	c.Assert(trust.Sequence, Equals, uint32(1407))
+
+	// The fixes are ready to be signed
+	key, err := crypto.NewECDSAKey(make([]byte, 16))
+	c.Assert(err, IsNil)
+	var sequence uint32
+	c.Assert(data.Sign(trust, key, &sequence), IsNil)
+	ok, err := data.CheckSignature(trust)
+	c.Assert(err, IsNil)
+	c.Assert(ok, Equals, true)
+}
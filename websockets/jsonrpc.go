@@ -0,0 +1,111 @@
+package websockets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// HTTPRemote sends commands to a server using JSON-RPC over HTTP.
+// It shares the command and result types of Remote, but has no
+// streams, so cannot subscribe or find paths continuously.
+type HTTPRemote struct {
+	commands
+	endpoint string
+	client   *http.Client
+}
+
+// NewHTTPRemote returns a client for the JSON-RPC endpoint URI.
+// To release idle connections, use Close().
+func NewHTTPRemote(endpoint string) (*HTTPRemote, error) {
+	if _, err := url.Parse(endpoint); err != nil {
+		return nil, err
+	}
+	h := &HTTPRemote{
+		endpoint: endpoint,
+		client:   &http.Client{},
+	}
+	h.commands.transport = h
+	return h, nil
+}
+
+func (h *HTTPRemote) Close() {
+	h.client.CloseIdleConnections()
+}
+
+type rpcRequest struct {
+	Method string        `json:"method"`
+	Params []interface{} `json:"params"`
+}
+
+// rpcParams returns the fields of a command, other than those identifying
+// it over websockets, as the single parameter of a JSON-RPC request.
+func rpcParams(cmd Syncer) (map[string]json.RawMessage, error) {
+	b, err := json.Marshal(cmd)
+	if err != nil {
+		return nil, err
+	}
+	var params map[string]json.RawMessage
+	if err := json.Unmarshal(b, &params); err != nil {
+		return nil, err
+	}
+	for _, field := range []string{"id", "command", "result", "Result"} {
+		delete(params, field)
+	}
+	return params, nil
+}
+
+// send posts a command and fills in its result. rippled reports errors
+// within the result, rather than alongside it as over websockets.
+func (h *HTTPRemote) send(ctx context.Context, cmd Syncer, c *Command) error {
+	params, err := rpcParams(cmd)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(rpcRequest{Method: c.Name, Params: []interface{}{params}})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := h.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return &ContextError{Name: c.Name, Id: c.Id, Err: ctx.Err()}
+		}
+		return clientError(err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return clientError(fmt.Sprintf("%s: %s", c.Name, resp.Status))
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return clientError(err.Error())
+	}
+	var response struct {
+		Result struct {
+			*CommandError
+			Status string `json:"status"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(b, &response); err != nil {
+		return clientError(fmt.Sprintf("%s ---- %s", err.Error(), string(b)))
+	}
+	c.Status = response.Result.Status
+	if e := response.Result.CommandError; e != nil && e.Name != "" {
+		c.CommandError = e
+		return e
+	}
+	if err := json.Unmarshal(b, cmd); err != nil {
+		return clientError(fmt.Sprintf("%s ---- %s", err.Error(), string(b)))
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package websockets
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/parihaaraka/ripple/data"
+	. "gopkg.in/check.v1"
+)
+
+type JSONRPCSuite struct{}
+
+var _ = Suite(&JSONRPCSuite{})
+
+// rpcServer checks the envelope of each request and replies with the file.
+func rpcServer(c *C, method, path string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Method string                   `json:"method"`
+			Params []map[string]interface{} `json:"params"`
+		}
+		c.Check(r.Method, Equals, http.MethodPost)
+		c.Check(json.NewDecoder(r.Body).Decode(&request), IsNil)
+		c.Check(request.Method, Equals, method)
+		c.Check(request.Params, HasLen, 1)
+		c.Check(request.Params[0]["account"], Equals, "rvYAfWj5gh67oV6fW32ZzP3Aw4Eubs59B")
+		c.Check(request.Params[0]["id"], IsNil)
+		c.Check(request.Params[0]["command"], IsNil)
+		b, err := ioutil.ReadFile(path)
+		c.Check(err, IsNil)
+		w.Write(b)
+	}))
+}
+
+func (s *JSONRPCSuite) TestAccountInfo(c *C) {
+	server := rpcServer(c, "account_info", "testdata/account_info.json")
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	c.Assert(err, IsNil)
+	defer client.Close()
+	c.Assert(client, FitsTypeOf, &HTTPRemote{})
+
+	account, err := data.NewAccountFromAddress("rvYAfWj5gh67oV6fW32ZzP3Aw4Eubs59B")
+	c.Assert(err, IsNil)
+	result, err := client.AccountInfo(*account, "validated")
+	c.Assert(err, IsNil)
+	c.Check(result.LedgerSequence, Equals, uint32(7636529))
+	c.Check(*result.AccountData.Sequence, Equals, uint32(546))
+}
+
+func (s *JSONRPCSuite) TestError(c *C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"result":{"error":"actNotFound","error_code":19,"error_message":"Account not found.","status":"error"}}`))
+	}))
+	defer server.Close()
+	client, err := NewHTTPRemote(server.URL)
+	c.Assert(err, IsNil)
+
+	_, err = client.AccountInfo(data.Account{}, "validated")
+	c.Assert(err, FitsTypeOf, &CommandError{})
+	c.Check(err.(*CommandError).Name, Equals, "actNotFound")
+	c.Check(err.(*CommandError).Code, Equals, 19)
+}
@@ -0,0 +1,510 @@
+package websockets
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/parihaaraka/ripple/data"
+)
+
+// The page size asked for by the iterators
+const pageSize = 400
+
+// The type filters of account_objects for the entries an account can own
+var accountObjectTypes = map[data.LedgerEntryType]string{
+	data.AMM_LT:                "amm",
+	data.BRIDGE:                "bridge",
+	data.CHECK:                 "check",
+	data.CREDENTIAL:            "credential",
+	data.DEPOSIT_PRE_AUTH:      "deposit_preauth",
+	data.DID:                   "did",
+	data.ESCROW:                "escrow",
+	data.MPTOKEN:               "mptoken",
+	data.MPTOKEN_ISSUANCE:      "mpt_issuance",
+	data.NFTOKEN_OFFER:         "nft_offer",
+	data.NFTOKEN_PAGE:          "nft_page",
+	data.OFFER:                 "offer",
+	data.ORACLE:                "oracle",
+	data.PAY_CHANNEL:           "payment_channel",
+	data.RIPPLE_STATE:          "state",
+	data.SIGNER_LIST:           "signer_list",
+	data.TICKET:                "ticket",
+	data.XCHAIN_OWNED_CLAIM_ID: "xchain_owned_claim_id",
+	data.XCHAIN_OWNED_CREATE_ACCOUNT_CLAIM_ID: "xchain_owned_create_account_claim_id",
+}
+
+type AccountObjectsCommand struct {
+	*Command
+	Account     data.Account          `json:"account"`
+	Type        string                `json:"type,omitempty"`
+	LedgerIndex interface{}           `json:"ledger_index,omitempty"`
+	Limit       uint32                `json:"limit,omitempty"`
+	Marker      interface{}           `json:"marker,omitempty"`
+	Result      *AccountObjectsResult `json:"result,omitempty"`
+}
+
+type AccountObjectsResult struct {
+	Account        data.Account          `json:"account"`
+	LedgerSequence *uint32               `json:"ledger_index,omitempty"`
+	LedgerCurrent  *uint32               `json:"ledger_current_index,omitempty"`
+	Objects        data.LedgerEntrySlice `json:"account_objects"`
+	Marker         interface{}           `json:"marker,omitempty"`
+}
+
+type AccountChannelsCommand struct {
+	*Command
+	Account     data.Account           `json:"account"`
+	Destination *data.Account          `json:"destination_account,omitempty"`
+	LedgerIndex interface{}            `json:"ledger_index,omitempty"`
+	Limit       uint32                 `json:"limit,omitempty"`
+	Marker      interface{}            `json:"marker,omitempty"`
+	Result      *AccountChannelsResult `json:"result,omitempty"`
+}
+
+type AccountChannel struct {
+	ChannelId      data.Hash256     `json:"channel_id"`
+	Account        data.Account     `json:"account"`
+	Destination    data.Account     `json:"destination_account"`
+	Amount         data.Amount      `json:"amount"`
+	Balance        data.Amount      `json:"balance"`
+	SettleDelay    uint32           `json:"settle_delay"`
+	PublicKey      string           `json:"public_key,omitempty"`
+	PublicKeyHex   string           `json:"public_key_hex,omitempty"`
+	Expiration     *data.RippleTime `json:"expiration,omitempty"`
+	CancelAfter    *data.RippleTime `json:"cancel_after,omitempty"`
+	SourceTag      *uint32          `json:"source_tag,omitempty"`
+	DestinationTag *uint32          `json:"destination_tag,omitempty"`
+}
+
+type AccountChannelsResult struct {
+	Account        data.Account     `json:"account"`
+	LedgerSequence *uint32          `json:"ledger_index,omitempty"`
+	LedgerCurrent  *uint32          `json:"ledger_current_index,omitempty"`
+	Channels       []AccountChannel `json:"channels"`
+	Marker         interface{}      `json:"marker,omitempty"`
+}
+
+type AccountNFTsCommand struct {
+	*Command
+	Account     data.Account       `json:"account"`
+	LedgerIndex interface{}        `json:"ledger_index,omitempty"`
+	Limit       uint32             `json:"limit,omitempty"`
+	Marker      interface{}        `json:"marker,omitempty"`
+	Result      *AccountNFTsResult `json:"result,omitempty"`
+}
+
+type AccountNFT struct {
+	Flags        uint32               `json:"Flags"`
+	Issuer       data.Account         `json:"Issuer"`
+	NFTokenID    data.Hash256         `json:"NFTokenID"`
+	NFTokenTaxon uint32               `json:"NFTokenTaxon"`
+	URI          *data.VariableLength `json:"URI,omitempty"`
+	TransferFee  uint16               `json:"TransferFee,omitempty"`
+	Serial       uint32               `json:"nft_serial"`
+}
+
+type AccountNFTsResult struct {
+	Account        data.Account `json:"account"`
+	LedgerSequence *uint32      `json:"ledger_index,omitempty"`
+	LedgerCurrent  *uint32      `json:"ledger_current_index,omitempty"`
+	NFTs           []AccountNFT `json:"account_nfts"`
+	Marker         interface{}  `json:"marker,omitempty"`
+}
+
+type AccountCurrenciesCommand struct {
+	*Command
+	Account     data.Account             `json:"account"`
+	LedgerIndex interface{}              `json:"ledger_index,omitempty"`
+	Result      *AccountCurrenciesResult `json:"result,omitempty"`
+}
+
+type AccountCurrenciesResult struct {
+	LedgerSequence *uint32         `json:"ledger_index,omitempty"`
+	LedgerCurrent  *uint32         `json:"ledger_current_index,omitempty"`
+	Receive        []data.Currency `json:"receive_currencies"`
+	Send           []data.Currency `json:"send_currencies"`
+}
+
+type LedgerEntryCommand struct {
+	*Command
+	Index       data.Hash256       `json:"index"`
+	LedgerIndex interface{}        `json:"ledger_index,omitempty"`
+	Binary      bool               `json:"binary"`
+	Result      *LedgerEntryResult `json:"result,omitempty"`
+}
+
+type LedgerEntryResult struct {
+	Index          data.Hash256     `json:"index"`
+	LedgerSequence *uint32          `json:"ledger_index,omitempty"`
+	LedgerCurrent  *uint32          `json:"ledger_current_index,omitempty"`
+	Validated      bool             `json:"validated"`
+	NodeBinary     string           `json:"node_binary"`
+	Entry          data.LedgerEntry `json:"-"`
+}
+
+// The ledger a page came from, so that later pages are from the same one
+func pinnedLedger(validated, current *uint32) interface{} {
+	switch {
+	case validated != nil:
+		return *validated
+	case current != nil:
+		return *current
+	default:
+		return nil
+	}
+}
+
+// pager fetches the pages of a command which takes a marker one at a time,
+// each from the ledger the first came from.
+type pager struct {
+	ctx     context.Context
+	client  *commands
+	ledger  interface{}
+	marker  interface{}
+	started bool
+	err     error
+}
+
+// fetch gets the next page with get unless there are no more or an error
+// has occurred. get returns the marker of the next page and the ledger.
+func (p *pager) fetch(get func() (interface{}, interface{}, error)) bool {
+	if p.err != nil || (p.started && p.marker == nil) {
+		return false
+	}
+	p.started = true
+	marker, ledger, err := get()
+	if err != nil {
+		p.err = err
+		return false
+	}
+	p.marker = marker
+	if ledger != nil {
+		p.ledger = ledger
+	}
+	return true
+}
+
+// Err returns the error which ended the iteration, if any.
+func (p *pager) Err() error {
+	return p.err
+}
+
+// Ledger returns the sequence of the ledger the pages come from, which is
+// known once the first has been fetched unless a sequence was asked for.
+// It is zero otherwise.
+func (p *pager) Ledger() uint32 {
+	sequence, _ := p.ledger.(uint32)
+	return sequence
+}
+
+// AccountObjectsIterator pages through the ledger entries owned by an
+// account. Call Next before each Object.
+type AccountObjectsIterator struct {
+	pager
+	account data.Account
+	filter  string
+	page    data.LedgerEntrySlice
+	object  data.LedgerEntry
+}
+
+func (it *AccountObjectsIterator) Next() bool {
+	for len(it.page) == 0 {
+		if !it.fetch(it.get) {
+			return false
+		}
+	}
+	it.object, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+func (it *AccountObjectsIterator) Object() data.LedgerEntry {
+	return it.object
+}
+
+func (it *AccountObjectsIterator) get() (interface{}, interface{}, error) {
+	cmd := &AccountObjectsCommand{
+		Command:     newCommand("account_objects"),
+		Account:     it.account,
+		Type:        it.filter,
+		LedgerIndex: it.ledger,
+		Limit:       pageSize,
+		Marker:      it.marker,
+	}
+	if err := it.client.send(it.ctx, cmd, cmd.Command); err != nil {
+		return nil, nil, err
+	}
+	it.page = cmd.Result.Objects
+	return cmd.Result.Marker, pinnedLedger(cmd.Result.LedgerSequence, cmd.Result.LedgerCurrent), nil
+}
+
+// AccountObjects iterates over the ledger entries owned by account, or
+// only those of entryType unless it is zero. An entryType which cannot be
+// filtered by is the iterator's Err.
+func (r *commands) AccountObjects(account data.Account, ledgerIndex interface{}, entryType data.LedgerEntryType) *AccountObjectsIterator {
+	return r.AccountObjectsContext(context.Background(), account, ledgerIndex, entryType)
+}
+
+// AccountObjectsContext is like AccountObjects, but the iterator gives up
+// when ctx is done.
+func (r *commands) AccountObjectsContext(ctx context.Context, account data.Account, ledgerIndex interface{}, entryType data.LedgerEntryType) *AccountObjectsIterator {
+	it := &AccountObjectsIterator{
+		pager:   pager{ctx: ctx, client: r, ledger: ledgerIndex},
+		account: account,
+	}
+	if entryType != 0 {
+		filter, ok := accountObjectTypes[entryType]
+		if !ok {
+			it.err = fmt.Errorf("Cannot filter account objects by %s", entryType)
+		}
+		it.filter = filter
+	}
+	return it
+}
+
+// AccountChannelsIterator pages through the payment channels of an account.
+type AccountChannelsIterator struct {
+	pager
+	account     data.Account
+	destination *data.Account
+	page        []AccountChannel
+	channel     *AccountChannel
+}
+
+func (it *AccountChannelsIterator) Next() bool {
+	for len(it.page) == 0 {
+		if !it.fetch(it.get) {
+			return false
+		}
+	}
+	it.channel, it.page = &it.page[0], it.page[1:]
+	return true
+}
+
+func (it *AccountChannelsIterator) Channel() *AccountChannel {
+	return it.channel
+}
+
+func (it *AccountChannelsIterator) get() (interface{}, interface{}, error) {
+	cmd := &AccountChannelsCommand{
+		Command:     newCommand("account_channels"),
+		Account:     it.account,
+		Destination: it.destination,
+		LedgerIndex: it.ledger,
+		Limit:       pageSize,
+		Marker:      it.marker,
+	}
+	if err := it.client.send(it.ctx, cmd, cmd.Command); err != nil {
+		return nil, nil, err
+	}
+	it.page = cmd.Result.Channels
+	return cmd.Result.Marker, pinnedLedger(cmd.Result.LedgerSequence, cmd.Result.LedgerCurrent), nil
+}
+
+// AccountChannels iterates over the payment channels from account, or
+// only those to destination unless it is nil.
+func (r *commands) AccountChannels(account data.Account, destination *data.Account, ledgerIndex interface{}) *AccountChannelsIterator {
+	return r.AccountChannelsContext(context.Background(), account, destination, ledgerIndex)
+}
+
+// AccountChannelsContext is like AccountChannels, but the iterator gives
+// up when ctx is done.
+func (r *commands) AccountChannelsContext(ctx context.Context, account data.Account, destination *data.Account, ledgerIndex interface{}) *AccountChannelsIterator {
+	return &AccountChannelsIterator{
+		pager:       pager{ctx: ctx, client: r, ledger: ledgerIndex},
+		account:     account,
+		destination: destination,
+	}
+}
+
+// AccountNFTsIterator pages through the NFTs held by an account.
+type AccountNFTsIterator struct {
+	pager
+	account data.Account
+	page    []AccountNFT
+	nft     *AccountNFT
+}
+
+func (it *AccountNFTsIterator) Next() bool {
+	for len(it.page) == 0 {
+		if !it.fetch(it.get) {
+			return false
+		}
+	}
+	it.nft, it.page = &it.page[0], it.page[1:]
+	return true
+}
+
+func (it *AccountNFTsIterator) NFT() *AccountNFT {
+	return it.nft
+}
+
+func (it *AccountNFTsIterator) get() (interface{}, interface{}, error) {
+	cmd := &AccountNFTsCommand{
+		Command:     newCommand("account_nfts"),
+		Account:     it.account,
+		LedgerIndex: it.ledger,
+		Limit:       pageSize,
+		Marker:      it.marker,
+	}
+	if err := it.client.send(it.ctx, cmd, cmd.Command); err != nil {
+		return nil, nil, err
+	}
+	it.page = cmd.Result.NFTs
+	return cmd.Result.Marker, pinnedLedger(cmd.Result.LedgerSequence, cmd.Result.LedgerCurrent), nil
+}
+
+// AccountNFTs iterates over the NFTs held by account.
+func (r *commands) AccountNFTs(account data.Account, ledgerIndex interface{}) *AccountNFTsIterator {
+	return r.AccountNFTsContext(context.Background(), account, ledgerIndex)
+}
+
+// AccountNFTsContext is like AccountNFTs, but the iterator gives up when
+// ctx is done.
+func (r *commands) AccountNFTsContext(ctx context.Context, account data.Account, ledgerIndex interface{}) *AccountNFTsIterator {
+	return &AccountNFTsIterator{
+		pager:   pager{ctx: ctx, client: r, ledger: ledgerIndex},
+		account: account,
+	}
+}
+
+// AccountCurrencies returns the currencies account can send and receive
+// according to its trust lines.
+func (r *commands) AccountCurrencies(account data.Account, ledgerIndex interface{}) (*AccountCurrenciesResult, error) {
+	return r.AccountCurrenciesContext(context.Background(), account, ledgerIndex)
+}
+
+// AccountCurrenciesContext is like AccountCurrencies, but gives up when
+// ctx is done.
+func (r *commands) AccountCurrenciesContext(ctx context.Context, account data.Account, ledgerIndex interface{}) (*AccountCurrenciesResult, error) {
+	cmd := &AccountCurrenciesCommand{
+		Command:     newCommand("account_currencies"),
+		Account:     account,
+		LedgerIndex: ledgerIndex,
+	}
+	if err := r.send(ctx, cmd, cmd.Command); err != nil {
+		return nil, err
+	}
+	return cmd.Result, nil
+}
+
+// LedgerKey identifies a ledger entry by the fields its index is derived
+// from.
+type LedgerKey interface {
+	Index() (*data.Hash256, error)
+}
+
+type OfferKey struct {
+	Account  data.Account
+	Sequence uint32
+}
+
+func (k OfferKey) Index() (*data.Hash256, error) {
+	return data.GetOfferIndex(k.Account, k.Sequence)
+}
+
+// RippleStateKey is the trust line between two accounts in either order.
+type RippleStateKey struct {
+	Account, Peer data.Account
+	Currency      data.Currency
+}
+
+func (k RippleStateKey) Index() (*data.Hash256, error) {
+	return data.GetRippleStateIndex(k.Account, k.Peer, k.Currency)
+}
+
+type EscrowKey struct {
+	Owner    data.Account
+	Sequence uint32
+}
+
+func (k EscrowKey) Index() (*data.Hash256, error) {
+	return data.GetEscrowIndex(k.Owner, k.Sequence)
+}
+
+type CheckKey struct {
+	Account  data.Account
+	Sequence uint32
+}
+
+func (k CheckKey) Index() (*data.Hash256, error) {
+	return data.GetCheckIndex(k.Account, k.Sequence)
+}
+
+// DirectoryKey is a page of the owner directory of Owner, or of the
+// directory with the root Root if it is not nil. Page 0 is the root.
+type DirectoryKey struct {
+	Owner data.Account
+	Root  *data.Hash256
+	Page  data.NodeIndex
+}
+
+func (k DirectoryKey) Index() (*data.Hash256, error) {
+	root := k.Root
+	if root == nil {
+		var err error
+		if root, err = data.GetOwnerDirectoryIndex(k.Owner); err != nil {
+			return nil, err
+		}
+	}
+	if k.Page == 0 {
+		return root, nil
+	}
+	return data.GetDirectoryNodeIndex(*root, &k.Page)
+}
+
+type AMMKey struct {
+	Asset, Asset2 data.Issue
+}
+
+func (k AMMKey) Index() (*data.Hash256, error) {
+	return data.GetAMMIndex(k.Asset, k.Asset2)
+}
+
+// LedgerEntry returns the ledger entry with index, which is decoded from
+// its binary form.
+func (r *commands) LedgerEntry(index data.Hash256, ledgerIndex interface{}) (*LedgerEntryResult, error) {
+	return r.LedgerEntryContext(context.Background(), index, ledgerIndex)
+}
+
+// LedgerEntryContext is like LedgerEntry, but gives up when ctx is done.
+func (r *commands) LedgerEntryContext(ctx context.Context, index data.Hash256, ledgerIndex interface{}) (*LedgerEntryResult, error) {
+	cmd := &LedgerEntryCommand{
+		Command:     newCommand("ledger_entry"),
+		Index:       index,
+		LedgerIndex: ledgerIndex,
+		Binary:      true,
+	}
+	if err := r.send(ctx, cmd, cmd.Command); err != nil {
+		return nil, err
+	}
+	if cmd.Result.Index != index {
+		return nil, fmt.Errorf("Asked for ledger entry %s but got %s", index, cmd.Result.Index)
+	}
+	b, err := hex.DecodeString(cmd.Result.NodeBinary)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := data.ReadLedgerEntry(bytes.NewReader(append(b, index[:]...)), data.Hash256{})
+	if err != nil {
+		return nil, err
+	}
+	cmd.Result.Entry = entry
+	return cmd.Result, nil
+}
+
+// LedgerEntryByKey is like LedgerEntry, but finds the entry by its key.
+func (r *commands) LedgerEntryByKey(key LedgerKey, ledgerIndex interface{}) (*LedgerEntryResult, error) {
+	return r.LedgerEntryByKeyContext(context.Background(), key, ledgerIndex)
+}
+
+// LedgerEntryByKeyContext is like LedgerEntryByKey, but gives up when ctx
+// is done.
+func (r *commands) LedgerEntryByKeyContext(ctx context.Context, key LedgerKey, ledgerIndex interface{}) (*LedgerEntryResult, error) {
+	index, err := key.Index()
+	if err != nil {
+		return nil, err
+	}
+	return r.LedgerEntryContext(ctx, *index, ledgerIndex)
+}
@@ -0,0 +1,166 @@
+package websockets
+
+import (
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/parihaaraka/ripple/data"
+	. "gopkg.in/check.v1"
+)
+
+type ObjectsSuite struct{}
+
+var _ = Suite(&ObjectsSuite{})
+
+const (
+	testAccount = "rGWrZyQqhTp9Xu7G5Pkayo7bXjH4k4QYpf"
+	testIssuer  = "rvYAfWj5gh67oV6fW32ZzP3Aw4Eubs59B"
+	testHash    = "E23869F043A46C2735BCA40781A674C5F24460BAC26C6B7475550493A9180200"
+)
+
+func testOffer(c *C, sequence uint32) (*data.Offer, map[string]interface{}) {
+	account, err := data.NewAccountFromAddress(testAccount)
+	c.Assert(err, IsNil)
+	index, err := data.GetOfferIndex(*account, sequence)
+	c.Assert(err, IsNil)
+	fields := map[string]interface{}{
+		"LedgerEntryType":   "Offer",
+		"Flags":             0,
+		"Account":           testAccount,
+		"Sequence":          sequence,
+		"TakerPays":         "1000000",
+		"TakerGets":         map[string]interface{}{"currency": "USD", "issuer": testIssuer, "value": "1"},
+		"BookDirectory":     testHash,
+		"BookNode":          "0",
+		"OwnerNode":         "0",
+		"PreviousTxnID":     testHash,
+		"PreviousTxnLgrSeq": 7,
+		"index":             index.String(),
+	}
+	b, err := json.Marshal(fields)
+	c.Assert(err, IsNil)
+	var offer data.Offer
+	c.Assert(json.Unmarshal(b, &offer), IsNil)
+	return &offer, fields
+}
+
+func (s *ObjectsSuite) TestAccountObjects(c *C) {
+	requests := make(chan map[string]interface{}, 10)
+	server := newFakeServer(func(request map[string]interface{}) interface{} {
+		requests <- request
+		_, first := testOffer(c, 1)
+		_, second := testOffer(c, 2)
+		if request["marker"] == nil {
+			return response(request, map[string]interface{}{
+				"account":         testAccount,
+				"account_objects": []interface{}{first},
+				"ledger_index":    70,
+				"marker":          "next",
+			})
+		}
+		return response(request, map[string]interface{}{
+			"account":         testAccount,
+			"account_objects": []interface{}{second},
+			"ledger_index":    70,
+		})
+	})
+	defer server.Close()
+	r, err := NewRemote(server.Endpoint())
+	c.Assert(err, IsNil)
+	defer r.Close()
+
+	account, err := data.NewAccountFromAddress(testAccount)
+	c.Assert(err, IsNil)
+	it := r.AccountObjects(*account, "validated", data.LEDGER_HASHES)
+	c.Check(it.Next(), Equals, false)
+	c.Check(it.Err(), ErrorMatches, "Cannot filter account objects by LedgerHashes")
+
+	it = r.AccountObjects(*account, "validated", data.OFFER)
+	var sequences []uint32
+	for it.Next() {
+		sequences = append(sequences, *it.Object().(*data.Offer).Sequence)
+	}
+	c.Assert(it.Err(), IsNil)
+	c.Check(sequences, DeepEquals, []uint32{1, 2})
+
+	first, second := <-requests, <-requests
+	c.Check(first["type"], Equals, "offer")
+	c.Check(first["ledger_index"], Equals, "validated")
+	c.Check(second["marker"], Equals, "next")
+	c.Check(second["ledger_index"], Equals, float64(70))
+	c.Check(it.Ledger(), Equals, uint32(70))
+	c.Check(it.Next(), Equals, false)
+}
+
+func (s *ObjectsSuite) TestAccountNFTs(c *C) {
+	server := newFakeServer(func(request map[string]interface{}) interface{} {
+		if request["command"] != "account_nfts" {
+			return response(request, map[string]interface{}{"error": "unknownCmd"})
+		}
+		return response(request, map[string]interface{}{
+			"account": testAccount,
+			"account_nfts": []interface{}{map[string]interface{}{
+				"Flags":        8,
+				"Issuer":       testIssuer,
+				"NFTokenID":    testHash,
+				"NFTokenTaxon": 3,
+				"URI":          "697066733A2F2F",
+				"nft_serial":   12,
+			}},
+			"ledger_current_index": 71,
+		})
+	})
+	defer server.Close()
+	r, err := NewRemote(server.Endpoint())
+	c.Assert(err, IsNil)
+	defer r.Close()
+
+	account, err := data.NewAccountFromAddress(testAccount)
+	c.Assert(err, IsNil)
+	it := r.AccountNFTs(*account, nil)
+	c.Assert(it.Next(), Equals, true)
+	c.Check(it.NFT().Issuer.String(), Equals, testIssuer)
+	c.Check(it.NFT().NFTokenID.String(), Equals, testHash)
+	c.Check(it.NFT().Serial, Equals, uint32(12))
+	c.Check(it.Next(), Equals, false)
+	c.Check(it.Err(), IsNil)
+}
+
+func (s *ObjectsSuite) TestLedgerEntryByKey(c *C) {
+	offer, _ := testOffer(c, 5)
+	// Raw suffixes the index, which node_binary does not have
+	_, raw, err := data.Raw(offer)
+	c.Assert(err, IsNil)
+	raw = raw[:len(raw)-32]
+	server := newFakeServer(func(request map[string]interface{}) interface{} {
+		return response(request, map[string]interface{}{
+			"index":        request["index"],
+			"ledger_index": 70,
+			"validated":    true,
+			"node_binary":  hex.EncodeToString(raw),
+		})
+	})
+	defer server.Close()
+	r, err := NewRemote(server.Endpoint())
+	c.Assert(err, IsNil)
+	defer r.Close()
+
+	account, err := data.NewAccountFromAddress(testAccount)
+	c.Assert(err, IsNil)
+	result, err := r.LedgerEntryByKey(OfferKey{*account, 5}, "validated")
+	c.Assert(err, IsNil)
+	c.Check(result.Validated, Equals, true)
+	found, ok := result.Entry.(*data.Offer)
+	c.Assert(ok, Equals, true)
+	c.Check(*found.Sequence, Equals, uint32(5))
+	c.Check(found.TakerGets.String(), Equals, offer.TakerGets.String())
+	index, err := data.LedgerIndex(found)
+	c.Assert(err, IsNil)
+	c.Check(*index, Equals, result.Index)
+
+	root, err := data.GetOwnerDirectoryIndex(*account)
+	c.Assert(err, IsNil)
+	page, err := DirectoryKey{Owner: *account}.Index()
+	c.Assert(err, IsNil)
+	c.Check(*page, Equals, *root)
+}
@@ -1,6 +1,9 @@
 package websockets
 
 import (
+	"context"
+	"reflect"
+
 	"github.com/parihaaraka/ripple/data"
 )
 
@@ -30,6 +33,17 @@ type PathFindCreateCommand struct {
 
 	// All commands have Result in their struct?
 	Result *PathFindCreateResult
+
+	session *PathFindSession
+}
+
+// PathFindCommand is the status or close subcommand of path_find.
+type PathFindCommand struct {
+	*Command
+	Subcommand string                `json:"subcommand"`
+	Result     *PathFindCreateResult `json:"result,omitempty"`
+
+	session *PathFindSession
 }
 
 type SourceCurrency struct {
@@ -37,6 +51,11 @@ type SourceCurrency struct {
 }
 
 func (r *Remote) PathFindCreate(src, dest data.Account, amt data.Amount, sendMax *data.Amount, sourceCurrencies *[]SourceCurrency) (*PathFindCreateResult, error) {
+	return r.PathFindCreateContext(context.Background(), src, dest, amt, sendMax, sourceCurrencies)
+}
+
+// PathFindCreateContext is like PathFindCreate, but gives up when ctx is done.
+func (r *Remote) PathFindCreateContext(ctx context.Context, src, dest data.Account, amt data.Amount, sendMax *data.Amount, sourceCurrencies *[]SourceCurrency) (*PathFindCreateResult, error) {
 	cmd := &PathFindCreateCommand{
 		Command:            newCommand("path_find"),
 		Subcommand:         "create",
@@ -46,10 +65,8 @@ func (r *Remote) PathFindCreate(src, dest data.Account, amt data.Amount, sendMax
 		SendMax:            sendMax,
 		SourceCurrencies:   sourceCurrencies,
 	}
-	r.outgoing <- cmd
-	<-cmd.Ready
-	if cmd.CommandError != nil {
-		return nil, cmd.CommandError
+	if err := r.send(ctx, cmd, cmd.Command); err != nil {
+		return nil, err
 	}
 	return cmd.Result, nil
 }
@@ -93,14 +110,130 @@ func (r *Remote) PathFindCreate(src, dest data.Account, amt data.Amount, sendMax
 */
 
 type PathFindAlternative struct {
-	// TODO paths_computed
-	SourceAmount data.Amount `json:"source_amount"`
+	SourceAmount      data.Amount  `json:"source_amount"`
+	PathsComputed     data.PathSet `json:"paths_computed,omitempty"`
+	DestinationAmount *data.Amount `json:"destination_amount,omitempty"` // When delivering less than asked for
 }
 
 type PathFindCreateResult struct {
+	Id                 uint64       `json:"id"` // Of the create command
 	SourceAccount      data.Account `json:"source_account"`
 	DestinationAccount data.Account `json:"destination_account"`
 	DestinationAmount  data.Amount  `json:"destination_amount"`
-	// TODO
-	Alternatives []PathFindAlternative
+	FullReply          bool         `json:"full_reply"` // Whether the search has finished
+	Closed             bool         `json:"closed,omitempty"`
+	Alternatives       []PathFindAlternative
+}
+
+// PathFindSession follows a path_find request, which rippled keeps
+// searching for better paths for as the ledger changes. The first response
+// and every update after it are sent on Updates, which holds the last
+// pathFindBuffer of them, dropping the oldest to make room for another.
+// Updates is closed when the session is closed, replaced by another
+// session or the connection is lost.
+type PathFindSession struct {
+	Updates chan *PathFindCreateResult
+	remote  *Remote
+	id      uint64 // Of the current request, only used by the run loop
+}
+
+// How many updates a PathFindSession holds for its consumer
+const pathFindBuffer = 16
+
+// PathFind starts a path_find session, replacing any other on r.
+func (r *Remote) PathFind(src, dest data.Account, amt data.Amount, sendMax *data.Amount, sourceCurrencies *[]SourceCurrency) (*PathFindSession, error) {
+	return r.PathFindContext(context.Background(), src, dest, amt, sendMax, sourceCurrencies)
+}
+
+// PathFindContext is like PathFind, but gives up when ctx is done.
+func (r *Remote) PathFindContext(ctx context.Context, src, dest data.Account, amt data.Amount, sendMax *data.Amount, sourceCurrencies *[]SourceCurrency) (*PathFindSession, error) {
+	s := &PathFindSession{
+		Updates: make(chan *PathFindCreateResult, pathFindBuffer),
+		remote:  r,
+	}
+	if err := s.ReplaceContext(ctx, src, dest, amt, sendMax, sourceCurrencies); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Replace searches for paths for another payment instead. Updates for
+// the previous one which arrive afterwards are not sent on Updates, but
+// on Incoming, or Streams.PathFind, like those of no session.
+func (s *PathFindSession) Replace(src, dest data.Account, amt data.Amount, sendMax *data.Amount, sourceCurrencies *[]SourceCurrency) error {
+	return s.ReplaceContext(context.Background(), src, dest, amt, sendMax, sourceCurrencies)
+}
+
+// ReplaceContext is like Replace, but gives up when ctx is done.
+func (s *PathFindSession) ReplaceContext(ctx context.Context, src, dest data.Account, amt data.Amount, sendMax *data.Amount, sourceCurrencies *[]SourceCurrency) error {
+	cmd := &PathFindCreateCommand{
+		Command:            newCommand("path_find"),
+		Subcommand:         "create",
+		SourceAccount:      src,
+		DestinationAccount: dest,
+		DestinationAmount:  amt,
+		SendMax:            sendMax,
+		SourceCurrencies:   sourceCurrencies,
+		session:            s,
+	}
+	return s.remote.send(ctx, cmd, cmd.Command)
+}
+
+// Status returns the latest alternatives without waiting for an update.
+func (s *PathFindSession) Status() (*PathFindCreateResult, error) {
+	return s.StatusContext(context.Background())
+}
+
+// StatusContext is like Status, but gives up when ctx is done.
+func (s *PathFindSession) StatusContext(ctx context.Context) (*PathFindCreateResult, error) {
+	cmd := &PathFindCommand{
+		Command:    newCommand("path_find"),
+		Subcommand: "status",
+	}
+	if err := s.remote.send(ctx, cmd, cmd.Command); err != nil {
+		return nil, err
+	}
+	return cmd.Result, nil
+}
+
+// Close stops the search and closes Updates.
+func (s *PathFindSession) Close() error {
+	return s.CloseContext(context.Background())
+}
+
+// CloseContext is like Close, but gives up when ctx is done.
+func (s *PathFindSession) CloseContext(ctx context.Context) error {
+	cmd := &PathFindCommand{
+		Command:    newCommand("path_find"),
+		Subcommand: "close",
+		session:    s,
+	}
+	return s.remote.send(ctx, cmd, cmd.Command)
+}
+
+// started makes s the session of r once rippled has accepted its request,
+// ending any other. Only called by the run loop.
+func (r *Remote) started(s *PathFindSession, result *PathFindCreateResult, id uint64) {
+	if r.pathFind != s {
+		r.endPathFind()
+		r.pathFind = s
+	}
+	s.id = id
+	if result != nil {
+		result.Id = id
+		s.update(result)
+	}
+}
+
+// endPathFind closes the current session, if any. Only called by the run
+// loop.
+func (r *Remote) endPathFind() {
+	if r.pathFind != nil {
+		close(r.pathFind.Updates)
+		r.pathFind = nil
+	}
+}
+
+func (s *PathFindSession) update(result *PathFindCreateResult) {
+	s.remote.dropOldest(reflect.ValueOf(s.Updates), reflect.ValueOf(result))
 }
@@ -0,0 +1,673 @@
+package websockets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/parihaaraka/ripple/data"
+)
+
+const (
+	// How often the servers in a RemotePool are probed with server_info
+	// and disconnected servers are redialled.
+	probeInterval = 10 * time.Second
+
+	// Time allowed for a probe or a subscription migration.
+	probeTimeout = 5 * time.Second
+)
+
+// ErrNoServer is returned by a RemotePool when no connected server
+// has the requested ledger.
+var ErrNoServer = errors.New("No server available")
+
+// ServerHealth is the state of a RemotePool server at its last probe.
+type ServerHealth struct {
+	Endpoint        string
+	Connected       bool
+	ServerState     string
+	CompleteLedgers []data.LedgerRange
+	ValidatedLedger uint32
+	LoadFactor      float64
+	Checked         time.Time
+	Err             error
+}
+
+// Servers which are ranked lower are preferred. Any other
+// state, such as "disconnected", is ranked last.
+var serverStateRank = map[string]int{
+	"full":       0,
+	"proposing":  0,
+	"validating": 0,
+	"tracking":   1,
+	"syncing":    2,
+	"connected":  3,
+}
+
+func (h *ServerHealth) rank() int {
+	if rank, ok := serverStateRank[h.ServerState]; ok {
+		return rank
+	}
+	return len(serverStateRank)
+}
+
+// HasLedger reports whether the server had the ledger when last probed.
+func (h *ServerHealth) HasLedger(sequence uint32) bool {
+	for _, r := range h.CompleteLedgers {
+		if sequence >= r.Start && sequence <= r.End {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *ServerHealth) update(info *ServerInfoResult) error {
+	ranges, err := parseLedgerRanges(info.Info.CompleteLedgers)
+	if err != nil {
+		return err
+	}
+	h.ServerState = info.Info.ServerState
+	h.CompleteLedgers = ranges
+	h.ValidatedLedger = uint32(info.Info.ValidatedLedger.Seq)
+	h.LoadFactor = info.Info.LoadFactor
+	return nil
+}
+
+// parseLedgerRanges parses complete_ledgers, eg. "32570-6000000,6000002-6000010"
+func parseLedgerRanges(s string) ([]data.LedgerRange, error) {
+	var ranges []data.LedgerRange
+	if s == "" || s == "empty" {
+		return ranges, nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		bounds := strings.SplitN(part, "-", 2)
+		start, err := strconv.ParseUint(bounds[0], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("Bad ledger range: %s", part)
+		}
+		end := start
+		if len(bounds) == 2 {
+			if end, err = strconv.ParseUint(bounds[1], 10, 32); err != nil {
+				return nil, fmt.Errorf("Bad ledger range: %s", part)
+			}
+		}
+		ranges = append(ranges, data.LedgerRange{Start: uint32(start), End: uint32(end)})
+	}
+	return ranges, nil
+}
+
+// ledgerSequence returns the sequence of a ledger argument, if it is
+// specified by number rather than by hash or as "validated" etc.
+func ledgerSequence(ledger interface{}) (uint32, bool) {
+	switch v := ledger.(type) {
+	case uint32:
+		return v, true
+	case int:
+		return uint32(v), v >= 0
+	case int64:
+		return uint32(v), v >= 0
+	case uint64:
+		return uint32(v), true
+	}
+	return 0, false
+}
+
+type poolMember struct {
+	health ServerHealth
+	remote *Remote
+}
+
+type subscription func(context.Context, *Remote) (*SubscribeResult, error)
+
+// RemotePool spreads commands across several servers. Each command is
+// sent to the healthiest connected server which has the requested ledger,
+// and is retried on the next healthiest if the connection is lost first.
+// Servers are probed with server_info and redialled in the background.
+//
+// Subscriptions are all made on a single primary server. If it is lost,
+// they are migrated to the next healthiest server, which is announced
+// by a pair of ConnectionStateMsg on Incoming.
+type RemotePool struct {
+	Incoming chan interface{}
+
+	mu         sync.Mutex // Guards the members, primary and lastLedger
+	members    []*poolMember
+	primary    *poolMember
+	lastLedger uint32
+
+	subMu         sync.Mutex // Serialises changes to the subscriptions
+	subscriptions []subscription
+
+	closing chan struct{}
+	prober  sync.WaitGroup // The run loop, which dials the members
+	wg      sync.WaitGroup // The forwarders of the members' streams
+}
+
+// NewRemotePool connects to each of the endpoints and returns a pool
+// of those which are available. Only one of them need be connected.
+// To close all the connections, use Close().
+func NewRemotePool(endpoints ...string) (*RemotePool, error) {
+	p := &RemotePool{
+		Incoming: make(chan interface{}, 1000),
+		closing:  make(chan struct{}),
+	}
+	for _, endpoint := range endpoints {
+		p.members = append(p.members, &poolMember{health: ServerHealth{Endpoint: endpoint}})
+	}
+	p.probe()
+	if len(p.candidates(nil)) == 0 {
+		p.Close()
+		return nil, ErrNoServer
+	}
+	p.prober.Add(1)
+	go p.run()
+	return p, nil
+}
+
+// Close shuts down all the connections and blocks until all internal
+// goroutines have been cleaned up.
+func (p *RemotePool) Close() {
+	// Stop probing first, so that no member is dialled after the
+	// remotes to close have been collected.
+	close(p.closing)
+	p.prober.Wait()
+	p.mu.Lock()
+	var remotes []*Remote
+	for _, m := range p.members {
+		if m.remote != nil {
+			remotes = append(remotes, m.remote)
+		}
+	}
+	p.mu.Unlock()
+	for _, r := range remotes {
+		r.Close()
+	}
+	p.wg.Wait()
+	close(p.Incoming)
+}
+
+// Health returns the state of each of the servers at its last probe.
+func (p *RemotePool) Health() []ServerHealth {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	health := make([]ServerHealth, len(p.members))
+	for i, m := range p.members {
+		health[i] = m.health
+	}
+	return health
+}
+
+func (p *RemotePool) run() {
+	defer p.prober.Done()
+	ticker := time.NewTicker(probeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.closing:
+			return
+		case <-ticker.C:
+			p.probe()
+			p.mu.Lock()
+			lost := p.primary == nil
+			p.mu.Unlock()
+			if lost {
+				p.migrate()
+			}
+		}
+	}
+}
+
+// probe redials any disconnected servers and updates the health of all.
+func (p *RemotePool) probe() {
+	var wg sync.WaitGroup
+	for _, m := range p.members {
+		wg.Add(1)
+		go func(m *poolMember) {
+			defer wg.Done()
+			p.probeMember(m)
+		}(m)
+	}
+	wg.Wait()
+}
+
+func (p *RemotePool) probeMember(m *poolMember) {
+	p.mu.Lock()
+	r := m.remote
+	p.mu.Unlock()
+	if r == nil {
+		var err error
+		if r, err = NewRemote(m.health.Endpoint); err != nil {
+			p.mu.Lock()
+			m.health.Checked, m.health.Err = time.Now(), err
+			p.mu.Unlock()
+			return
+		}
+		p.mu.Lock()
+		select {
+		case <-p.closing:
+			p.mu.Unlock()
+			r.Close()
+			return
+		default:
+		}
+		m.remote = r
+		m.health.Connected = true
+		p.wg.Add(1)
+		p.mu.Unlock()
+		go p.forward(m, r)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+	info, err := r.ServerInfoContext(ctx)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	m.health.Checked, m.health.Err = time.Now(), err
+	if err == nil {
+		m.health.Err = m.health.update(info)
+	}
+}
+
+// forward passes on the messages from one connection until it is lost.
+func (p *RemotePool) forward(m *poolMember, r *Remote) {
+	defer p.wg.Done()
+	for msg := range r.Incoming {
+		if ledger, ok := msg.(*LedgerStreamMsg); ok {
+			p.mu.Lock()
+			if p.primary == m {
+				p.lastLedger = ledger.LedgerSequence
+			}
+			p.mu.Unlock()
+		}
+		select {
+		case p.Incoming <- msg:
+		case <-p.closing:
+		}
+	}
+	p.mu.Lock()
+	m.remote = nil
+	m.health.Connected = false
+	wasPrimary := p.primary == m
+	if wasPrimary {
+		p.primary = nil
+	}
+	lastLedger := p.lastLedger
+	p.mu.Unlock()
+	select {
+	case <-p.closing:
+		return
+	default:
+	}
+	glog.Errorf("Lost connection to %s", m.health.Endpoint)
+	if wasPrimary {
+		select {
+		case p.Incoming <- &ConnectionStateMsg{Endpoint: m.health.Endpoint, LastLedgerSequence: lastLedger}:
+		case <-p.closing:
+			return
+		}
+		p.migrate()
+	}
+}
+
+// candidates returns the connected servers having the ledger, healthiest first.
+func (p *RemotePool) candidates(ledger interface{}) []*poolMember {
+	sequence, specific := ledgerSequence(ledger)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var members []*poolMember
+	for _, m := range p.members {
+		if m.remote == nil || m.health.Err != nil {
+			continue
+		}
+		if specific && !m.health.HasLedger(sequence) {
+			continue
+		}
+		members = append(members, m)
+	}
+	sort.SliceStable(members, func(i, j int) bool {
+		a, b := &members[i].health, &members[j].health
+		switch {
+		case a.rank() != b.rank():
+			return a.rank() < b.rank()
+		case a.LoadFactor != b.LoadFactor:
+			return a.LoadFactor < b.LoadFactor
+		default:
+			return a.ValidatedLedger > b.ValidatedLedger
+		}
+	})
+	return members
+}
+
+func (p *RemotePool) remoteOf(m *poolMember) *Remote {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return m.remote
+}
+
+// isConnectionClosed reports whether err is due to a lost connection
+// rather than a response from the server.
+func isConnectionClosed(err error) bool {
+	e, ok := err.(*CommandError)
+	return ok && e.Name == "Client Error" && e.Message == "Connection Closed"
+}
+
+// Do calls f with the healthiest connected server having the ledger, moving
+// on to the next healthiest each time f fails due to a lost connection.
+func (p *RemotePool) Do(ctx context.Context, ledger interface{}, f func(*Remote) error) error {
+	for _, m := range p.candidates(ledger) {
+		r := p.remoteOf(m)
+		if r == nil {
+			continue
+		}
+		if err := f(r); !isConnectionClosed(err) {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+	return ErrNoServer
+}
+
+// subscribe makes a subscription on the primary server, choosing one if
+// there is none, and records it so that it can be migrated.
+func (p *RemotePool) subscribe(ctx context.Context, s subscription) (*SubscribeResult, error) {
+	p.subMu.Lock()
+	defer p.subMu.Unlock()
+	p.mu.Lock()
+	var r *Remote
+	if p.primary != nil {
+		r = p.primary.remote
+	}
+	p.mu.Unlock()
+	if r != nil {
+		result, err := s(ctx, r)
+		if !isConnectionClosed(err) {
+			if err == nil {
+				p.subscriptions = append(p.subscriptions, s)
+			}
+			return result, err
+		}
+	}
+	p.subscriptions = append(p.subscriptions, s)
+	result, err := p.replay(ctx)
+	if err != nil {
+		p.subscriptions = p.subscriptions[:len(p.subscriptions)-1]
+	}
+	return result, err
+}
+
+// migrate moves the subscriptions to the healthiest server after
+// the primary has been lost.
+func (p *RemotePool) migrate() {
+	p.subMu.Lock()
+	defer p.subMu.Unlock()
+	if len(p.subscriptions) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+	if _, err := p.replay(ctx); err != nil {
+		glog.Errorln(err)
+		return
+	}
+	p.mu.Lock()
+	if p.primary == nil {
+		p.mu.Unlock()
+		return
+	}
+	msg := &ConnectionStateMsg{
+		Connected:          true,
+		Endpoint:           p.primary.health.Endpoint,
+		LastLedgerSequence: p.lastLedger,
+	}
+	p.mu.Unlock()
+	select {
+	case p.Incoming <- msg:
+	case <-p.closing:
+	}
+}
+
+// replay makes all the subscriptions on the healthiest server which accepts
+// them, and makes it the primary. Returns the result of the last one.
+// The caller must hold subMu.
+func (p *RemotePool) replay(ctx context.Context) (*SubscribeResult, error) {
+	var err error
+	for _, m := range p.candidates(nil) {
+		r := p.remoteOf(m)
+		if r == nil {
+			continue
+		}
+		var result *SubscribeResult
+		for _, s := range p.subscriptions {
+			if result, err = s(ctx, r); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			p.mu.Lock()
+			p.primary = m
+			p.mu.Unlock()
+			return result, nil
+		}
+		if !isConnectionClosed(err) {
+			return nil, err
+		}
+	}
+	return nil, ErrNoServer
+}
+
+func (p *RemotePool) Tx(hash data.Hash256) (*TxResult, error) {
+	return p.TxContext(context.Background(), hash)
+}
+
+// TxContext is like Tx, but gives up when ctx is done.
+func (p *RemotePool) TxContext(ctx context.Context, hash data.Hash256) (*TxResult, error) {
+	var result *TxResult
+	err := p.Do(ctx, nil, func(r *Remote) (err error) {
+		result, err = r.TxContext(ctx, hash)
+		return err
+	})
+	return result, err
+}
+
+// Submit is like Remote.Submit. If the connection is lost before the
+// result arrives, the same signed transaction is submitted to another server.
+func (p *RemotePool) Submit(tx data.Transaction) (*SubmitResult, error) {
+	return p.SubmitContext(context.Background(), tx)
+}
+
+// SubmitContext is like Submit, but gives up when ctx is done.
+func (p *RemotePool) SubmitContext(ctx context.Context, tx data.Transaction) (*SubmitResult, error) {
+	var result *SubmitResult
+	err := p.Do(ctx, nil, func(r *Remote) (err error) {
+		result, err = r.SubmitContext(ctx, tx)
+		return err
+	})
+	return result, err
+}
+
+func (p *RemotePool) LedgerData(ledger interface{}, marker *data.Hash256) (*LedgerDataResult, error) {
+	return p.LedgerDataContext(context.Background(), ledger, marker)
+}
+
+// LedgerDataContext is like LedgerData, but gives up when ctx is done.
+func (p *RemotePool) LedgerDataContext(ctx context.Context, ledger interface{}, marker *data.Hash256) (*LedgerDataResult, error) {
+	var result *LedgerDataResult
+	err := p.Do(ctx, ledger, func(r *Remote) (err error) {
+		result, err = r.LedgerDataContext(ctx, ledger, marker)
+		return err
+	})
+	return result, err
+}
+
+func (p *RemotePool) Ledger(ledger interface{}, transactions bool) (*LedgerResult, error) {
+	return p.LedgerContext(context.Background(), ledger, transactions)
+}
+
+// LedgerContext is like Ledger, but gives up when ctx is done.
+func (p *RemotePool) LedgerContext(ctx context.Context, ledger interface{}, transactions bool) (*LedgerResult, error) {
+	var result *LedgerResult
+	err := p.Do(ctx, ledger, func(r *Remote) (err error) {
+		result, err = r.LedgerContext(ctx, ledger, transactions)
+		return err
+	})
+	return result, err
+}
+
+func (p *RemotePool) LedgerHeader(ledger interface{}) (*LedgerHeaderResult, error) {
+	return p.LedgerHeaderContext(context.Background(), ledger)
+}
+
+// LedgerHeaderContext is like LedgerHeader, but gives up when ctx is done.
+func (p *RemotePool) LedgerHeaderContext(ctx context.Context, ledger interface{}) (*LedgerHeaderResult, error) {
+	var result *LedgerHeaderResult
+	err := p.Do(ctx, ledger, func(r *Remote) (err error) {
+		result, err = r.LedgerHeaderContext(ctx, ledger)
+		return err
+	})
+	return result, err
+}
+
+func (p *RemotePool) RipplePathFind(src, dest data.Account, amount data.Amount, srcCurr *[]data.Currency) (*RipplePathFindResult, error) {
+	return p.RipplePathFindContext(context.Background(), src, dest, amount, srcCurr)
+}
+
+// RipplePathFindContext is like RipplePathFind, but gives up when ctx is done.
+func (p *RemotePool) RipplePathFindContext(ctx context.Context, src, dest data.Account, amount data.Amount, srcCurr *[]data.Currency) (*RipplePathFindResult, error) {
+	var result *RipplePathFindResult
+	err := p.Do(ctx, nil, func(r *Remote) (err error) {
+		result, err = r.RipplePathFindContext(ctx, src, dest, amount, srcCurr)
+		return err
+	})
+	return result, err
+}
+
+func (p *RemotePool) AccountInfo(a data.Account, ledger interface{}) (*AccountInfoResult, error) {
+	return p.AccountInfoContext(context.Background(), a, ledger)
+}
+
+// AccountInfoContext is like AccountInfo, but gives up when ctx is done.
+func (p *RemotePool) AccountInfoContext(ctx context.Context, a data.Account, ledger interface{}) (*AccountInfoResult, error) {
+	var result *AccountInfoResult
+	err := p.Do(ctx, ledger, func(r *Remote) (err error) {
+		result, err = r.AccountInfoContext(ctx, a, ledger)
+		return err
+	})
+	return result, err
+}
+
+func (p *RemotePool) ServerInfo() (*ServerInfoResult, error) {
+	return p.ServerInfoContext(context.Background())
+}
+
+// ServerInfoContext is like ServerInfo, but gives up when ctx is done.
+func (p *RemotePool) ServerInfoContext(ctx context.Context) (*ServerInfoResult, error) {
+	var result *ServerInfoResult
+	err := p.Do(ctx, nil, func(r *Remote) (err error) {
+		result, err = r.ServerInfoContext(ctx)
+		return err
+	})
+	return result, err
+}
+
+func (p *RemotePool) DepositAuthorized(source, dest data.Account, ledger interface{}) (*DepositAuthorizedResult, error) {
+	return p.DepositAuthorizedContext(context.Background(), source, dest, ledger)
+}
+
+// DepositAuthorizedContext is like DepositAuthorized, but gives up when ctx is done.
+func (p *RemotePool) DepositAuthorizedContext(ctx context.Context, source, dest data.Account, ledger interface{}) (*DepositAuthorizedResult, error) {
+	var result *DepositAuthorizedResult
+	err := p.Do(ctx, ledger, func(r *Remote) (err error) {
+		result, err = r.DepositAuthorizedContext(ctx, source, dest, ledger)
+		return err
+	})
+	return result, err
+}
+
+func (p *RemotePool) AccountLines(account data.Account, ledgerIndex interface{}, peer string) (*AccountLinesResult, error) {
+	return p.AccountLinesContext(context.Background(), account, ledgerIndex, peer)
+}
+
+// AccountLinesContext is like AccountLines, but gives up when ctx is done.
+func (p *RemotePool) AccountLinesContext(ctx context.Context, account data.Account, ledgerIndex interface{}, peer string) (*AccountLinesResult, error) {
+	var result *AccountLinesResult
+	err := p.Do(ctx, ledgerIndex, func(r *Remote) (err error) {
+		result, err = r.AccountLinesContext(ctx, account, ledgerIndex, peer)
+		return err
+	})
+	return result, err
+}
+
+func (p *RemotePool) AccountOffers(account data.Account, ledgerIndex interface{}) (*AccountOffersResult, error) {
+	return p.AccountOffersContext(context.Background(), account, ledgerIndex)
+}
+
+// AccountOffersContext is like AccountOffers, but gives up when ctx is done.
+func (p *RemotePool) AccountOffersContext(ctx context.Context, account data.Account, ledgerIndex interface{}) (*AccountOffersResult, error) {
+	var result *AccountOffersResult
+	err := p.Do(ctx, ledgerIndex, func(r *Remote) (err error) {
+		result, err = r.AccountOffersContext(ctx, account, ledgerIndex)
+		return err
+	})
+	return result, err
+}
+
+func (p *RemotePool) BookOffers(taker data.Account, ledgerIndex interface{}, pays, gets data.Asset) (*BookOffersResult, error) {
+	return p.BookOffersContext(context.Background(), taker, ledgerIndex, pays, gets)
+}
+
+// BookOffersContext is like BookOffers, but gives up when ctx is done.
+func (p *RemotePool) BookOffersContext(ctx context.Context, taker data.Account, ledgerIndex interface{}, pays, gets data.Asset) (*BookOffersResult, error) {
+	var result *BookOffersResult
+	err := p.Do(ctx, ledgerIndex, func(r *Remote) (err error) {
+		result, err = r.BookOffersContext(ctx, taker, ledgerIndex, pays, gets)
+		return err
+	})
+	return result, err
+}
+
+func (p *RemotePool) Fee() (*FeeResult, error) {
+	return p.FeeContext(context.Background())
+}
+
+// FeeContext is like Fee, but gives up when ctx is done.
+func (p *RemotePool) FeeContext(ctx context.Context) (*FeeResult, error) {
+	var result *FeeResult
+	err := p.Do(ctx, nil, func(r *Remote) (err error) {
+		result, err = r.FeeContext(ctx)
+		return err
+	})
+	return result, err
+}
+
+// Subscribe is like Remote.Subscribe, with the stream messages from
+// the primary server received over the pool's Incoming channel.
+func (p *RemotePool) Subscribe(ledger, transactions, transactionsProposed, server bool) (*SubscribeResult, error) {
+	return p.SubscribeContext(context.Background(), ledger, transactions, transactionsProposed, server)
+}
+
+// SubscribeContext is like Subscribe, but gives up waiting for the
+// confirmation when ctx is done.
+func (p *RemotePool) SubscribeContext(ctx context.Context, ledger, transactions, transactionsProposed, server bool) (*SubscribeResult, error) {
+	return p.subscribe(ctx, func(ctx context.Context, r *Remote) (*SubscribeResult, error) {
+		return r.SubscribeContext(ctx, ledger, transactions, transactionsProposed, server)
+	})
+}
+
+func (p *RemotePool) SubscribeOrderBooks(books []OrderBookSubscription) (*SubscribeResult, error) {
+	return p.SubscribeOrderBooksContext(context.Background(), books)
+}
+
+// SubscribeOrderBooksContext is like SubscribeOrderBooks, but gives up
+// waiting for the confirmation when ctx is done.
+func (p *RemotePool) SubscribeOrderBooksContext(ctx context.Context, books []OrderBookSubscription) (*SubscribeResult, error) {
+	return p.subscribe(ctx, func(ctx context.Context, r *Remote) (*SubscribeResult, error) {
+		return r.SubscribeOrderBooksContext(ctx, books)
+	})
+}
@@ -0,0 +1,92 @@
+package websockets
+
+import (
+	"time"
+
+	"github.com/parihaaraka/ripple/data"
+	. "gopkg.in/check.v1"
+)
+
+type PoolSuite struct{}
+
+var _ = Suite(&PoolSuite{})
+
+// poolServer answers server_info with the given state and reports
+// the other commands it receives.
+func poolServer(complete string, load int, commands chan<- string) *fakeServer {
+	return newFakeServer(func(request map[string]interface{}) interface{} {
+		command := request["command"].(string)
+		if command == "server_info" {
+			return response(request, map[string]interface{}{
+				"info": map[string]interface{}{
+					"server_state":     "full",
+					"complete_ledgers": complete,
+					"load_factor":      load,
+				},
+			})
+		}
+		commands <- command
+		if command == "subscribe" {
+			return response(request, map[string]interface{}{"ledger_index": 7})
+		}
+		return response(request, map[string]interface{}{})
+	})
+}
+
+func (s *PoolSuite) TestParseLedgerRanges(c *C) {
+	ranges, err := parseLedgerRanges("32570-6000000,6000002")
+	c.Assert(err, IsNil)
+	c.Check(ranges, DeepEquals, []data.LedgerRange{{Start: 32570, End: 6000000}, {Start: 6000002, End: 6000002}})
+	ranges, err = parseLedgerRanges("empty")
+	c.Assert(err, IsNil)
+	c.Check(ranges, HasLen, 0)
+	_, err = parseLedgerRanges("1-x")
+	c.Check(err, NotNil)
+}
+
+func (s *PoolSuite) TestRouting(c *C) {
+	a, b := make(chan string, 10), make(chan string, 10)
+	serverA, serverB := poolServer("1-100", 1, a), poolServer("50-200", 2, b)
+	defer serverB.Close()
+	p, err := NewRemotePool(serverA.Endpoint(), serverB.Endpoint())
+	c.Assert(err, IsNil)
+	defer p.Close()
+
+	// Only B has the ledger
+	_, err = p.AccountInfo(data.Account{}, uint32(150))
+	c.Assert(err, IsNil)
+	c.Check(<-b, Equals, "account_info")
+
+	// Both have it, A is less loaded
+	_, err = p.AccountInfo(data.Account{}, 60)
+	c.Assert(err, IsNil)
+	c.Check(<-a, Equals, "account_info")
+
+	// Neither has it
+	_, err = p.AccountInfo(data.Account{}, 300)
+	c.Check(err, Equals, ErrNoServer)
+
+	// Subscriptions go to A and are migrated to B when it goes away
+	_, err = p.Subscribe(true, false, false, false)
+	c.Assert(err, IsNil)
+	c.Check(<-a, Equals, "subscribe")
+	serverA.Drop()
+	serverA.Close()
+
+	msg := (<-p.Incoming).(*ConnectionStateMsg)
+	c.Check(msg.Connected, Equals, false)
+	c.Check(msg.Endpoint, Equals, serverA.Endpoint())
+	msg = (<-p.Incoming).(*ConnectionStateMsg)
+	c.Check(msg.Connected, Equals, true)
+	c.Check(msg.Endpoint, Equals, serverB.Endpoint())
+	c.Check(<-b, Equals, "subscribe")
+
+	_, err = p.AccountInfo(data.Account{}, 60)
+	c.Assert(err, IsNil)
+	select {
+	case command := <-b:
+		c.Check(command, Equals, "account_info")
+	case <-time.After(time.Second):
+		c.Fatal("account_info not failed over")
+	}
+}
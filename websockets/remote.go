@@ -2,6 +2,7 @@ package websockets
 
 import (
 	"bytes"
+	"context"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -30,22 +31,108 @@ const (
 
 	// Time allowed to connect to server.
 	dialTimeout = 5 * time.Second
+
+	// Time waited before the first redial of a reconnecting Remote.
+	// Doubles with each failed attempt up to maxReconnectWait.
+	minReconnectWait = 100 * time.Millisecond
+	maxReconnectWait = 30 * time.Second
 )
 
+// Commands which change state on the server, and so are failed rather
+// than re-sent when a reconnecting Remote loses its connection.
+var notIdempotent = map[string]bool{
+	"submit":    true,
+	"path_find": true,
+}
+
 type Remote struct {
-	Incoming chan interface{}
-	outgoing chan Syncer
-	ws       *websocket.Conn
+	commands
+	Incoming  chan interface{}
+	Streams   *Streams // Only with RemoteOptions.TypedStreams
+	routes    map[string]reflect.Value
+	overflow  OverflowPolicy
+	dropped   map[string]*uint64
+	pathFind  *PathFindSession // Only used by the run loop
+	outgoing  chan Syncer
+	cancelled chan uint64
+	closing   chan struct{}
+	closed    chan struct{}
+	endpoint  *url.URL
+	reconnect bool
+	ws        *websocket.Conn
+}
+
+// ConnectionStateMsg is sent on Incoming by a reconnecting Remote when
+// the connection to the server is lost and again when it is restored.
+// Stream messages after LastLedgerSequence may have been missed, and
+// should be backfilled by the consumer if they matter.
+type ConnectionStateMsg struct {
+	Connected          bool
+	Endpoint           string
+	Attempts           int    // Failed dials before connecting
+	LastLedgerSequence uint32 // From the last ledgerClosed stream message
 }
 
 // NewRemote returns a new remote session connected to the specified
 // server endpoint URI. To close the connection, use Close().
 func NewRemote(endpoint string) (*Remote, error) {
+	return NewRemoteWithOptions(endpoint, RemoteOptions{})
+}
+
+// NewReconnectingRemote is like NewRemote, except that a lost connection
+// is redialled with exponential backoff rather than closing the Remote.
+// Once reconnected, the subscriptions are replayed and any commands which
+// are safe to repeat are re-sent. A ConnectionStateMsg is sent on Incoming
+// on disconnection and reconnection. Only the first dial must succeed.
+func NewReconnectingRemote(endpoint string) (*Remote, error) {
+	return NewRemoteWithOptions(endpoint, RemoteOptions{Reconnect: true})
+}
+
+// NewRemoteWithOptions is like NewRemote, but can also reconnect and
+// deliver stream messages on typed channels with an overflow policy, so
+// that a slow consumer of one stream need not stall the others or the
+// responses to commands.
+func NewRemoteWithOptions(endpoint string, opts RemoteOptions) (*Remote, error) {
 	glog.Infoln(endpoint)
 	u, err := url.Parse(endpoint)
 	if err != nil {
 		return nil, err
 	}
+	ws, err := dial(u)
+	if err != nil {
+		return nil, err
+	}
+	buffer := opts.Buffer
+	if buffer <= 0 {
+		buffer = defaultBuffer
+	}
+	r := &Remote{
+		Incoming: make(chan interface{}, buffer),
+		overflow: opts.Overflow,
+		dropped:  make(map[string]*uint64),
+		// Unbuffered, so that a command is always pending in the run loop
+		// before its caller can ask for it to be cancelled.
+		outgoing:  make(chan Syncer),
+		cancelled: make(chan uint64),
+		closing:   make(chan struct{}),
+		closed:    make(chan struct{}),
+		endpoint:  u,
+		reconnect: opts.Reconnect,
+		ws:        ws,
+	}
+	r.commands.transport = r
+	for msgType := range streamMessageFactory {
+		r.dropped[msgType] = new(uint64)
+	}
+	if opts.TypedStreams {
+		r.Streams, r.routes = newStreams(buffer)
+	}
+
+	go r.run()
+	return r, nil
+}
+
+func dial(u *url.URL) (*websocket.Conn, error) {
 	dialHost := u.Host
 	if u.Port() == "" {
 		if u.Scheme == "wss" {
@@ -60,44 +147,148 @@ func NewRemote(endpoint string) (*Remote, error) {
 	}
 	ws, _, err := websocket.NewClient(c, u, nil, 1024, 1024)
 	if err != nil {
+		c.Close()
 		return nil, err
 	}
-	r := &Remote{
-		Incoming: make(chan interface{}, 1000),
-		outgoing: make(chan Syncer, 10),
-		ws:       ws,
-	}
-
-	go r.run()
-	return r, nil
+	return ws, nil
 }
 
 // Close shuts down the Remote session and blocks until all internal
 // goroutines have been cleaned up.
 // Any commands that are pending a response will return with an error.
 func (r *Remote) Close() {
-	close(r.outgoing)
+	// outgoing is never closed, so that a command sent concurrently
+	// fails with an error once the run loop has exited rather than
+	// panicking.
+	close(r.closing)
 
-	// Drain the Incoming channel and block until it is closed,
-	// indicating that this Remote is fully cleaned up.
+	// Drain the stream channels, which the run loop may be blocked on,
+	// and block until Incoming is closed after them, indicating that
+	// this Remote is fully cleaned up.
+	for _, ch := range r.routes {
+		go func(ch reflect.Value) {
+			for {
+				if _, ok := ch.Recv(); !ok {
+					return
+				}
+			}
+		}(ch)
+	}
 	for range r.Incoming {
 	}
 }
 
-// run spawns the read/write pumps and then runs until Close() is called.
+// run serves the connection until Close() is called, redialling
+// it first if this is a reconnecting Remote.
 func (r *Remote) run() {
-	outbound := make(chan interface{})
-	inbound := make(chan []byte)
 	pending := make(map[uint64]Syncer)
+	var (
+		subscriptions []Subscription
+		lastLedger    uint32
+	)
 
 	defer func() {
-		close(outbound) // Shuts down the writePump
+		r.endPathFind()
+		close(r.closed)
+		for _, ch := range r.routes {
+			ch.Close()
+		}
 		close(r.Incoming)
 
 		// Cancel all pending commands with an error
 		for _, c := range pending {
 			c.Fail("Connection Closed")
 		}
+	}()
+
+	for {
+		if r.serve(pending, &subscriptions, &lastLedger) || !r.reconnect {
+			return
+		}
+		// The server forgets a path_find request with the connection
+		r.endPathFind()
+		for id, c := range pending {
+			if notIdempotent[commandName(c)] {
+				delete(pending, id)
+				c.Fail("Connection Closed")
+			}
+		}
+		if !r.notify(&ConnectionStateMsg{
+			Endpoint:           r.endpoint.String(),
+			LastLedgerSequence: lastLedger,
+		}) {
+			return
+		}
+		attempts, ok := r.redial(pending)
+		if !ok {
+			return
+		}
+		if !r.notify(&ConnectionStateMsg{
+			Connected:          true,
+			Endpoint:           r.endpoint.String(),
+			Attempts:           attempts,
+			LastLedgerSequence: lastLedger,
+		}) {
+			return
+		}
+
+		// The replayed subscriptions are pending like any other command,
+		// but nobody waits for their responses.
+		for _, s := range subscriptions {
+			replay := &SubscribeCommand{
+				Command:      newCommand("subscribe"),
+				Subscription: s,
+			}
+			pending[replay.Id] = replay
+		}
+	}
+}
+
+// redial connects to the endpoint again, backing off exponentially
+// between attempts. Commands issued meanwhile are added to pending.
+// Returns false if Close() is called first.
+func (r *Remote) redial(pending map[uint64]Syncer) (int, bool) {
+	wait := minReconnectWait
+	for attempts := 0; ; attempts++ {
+		timer := time.NewTimer(wait)
+		for waiting := true; waiting; {
+			select {
+			case <-r.closing:
+				timer.Stop()
+				return attempts, false
+
+			case command := <-r.outgoing:
+				pending[commandId(command)] = command
+
+			case id := <-r.cancelled:
+				delete(pending, id)
+
+			case <-timer.C:
+				waiting = false
+			}
+		}
+		ws, err := dial(r.endpoint)
+		if err == nil {
+			r.ws = ws
+			return attempts, true
+		}
+		glog.Errorln(err)
+		if wait *= 2; wait > maxReconnectWait {
+			wait = maxReconnectWait
+		}
+	}
+}
+
+// serve spawns the read/write pumps for the current connection, sends
+// any commands already pending and then runs until either Close() is
+// called, in which case it returns true, or the connection is lost.
+func (r *Remote) serve(pending map[uint64]Syncer, subscriptions *[]Subscription, lastLedger *uint32) bool {
+	outbound := make(chan interface{})
+	inbound := make(chan []byte)
+	writing := make(chan struct{})
+
+	defer func() {
+		close(outbound) // Shuts down the writePump
 
 		// Drain the inbound channel and block until it is closed,
 		// indicating that the readPump has returned.
@@ -106,31 +297,46 @@ func (r *Remote) run() {
 	}()
 
 	// Spawn read/write goroutines
-	go func() {
-		defer r.ws.Close()
-		r.writePump(outbound)
-	}()
-	go func() {
+	go func(ws *websocket.Conn) {
+		defer close(writing)
+		defer ws.Close()
+		r.writePump(ws, outbound)
+	}(r.ws)
+	go func(ws *websocket.Conn) {
 		defer close(inbound)
-		r.readPump(inbound)
-	}()
+		r.readPump(ws, inbound)
+	}(r.ws)
+
+	// write gives up once the writePump has failed, after which
+	// the readPump soon fails too.
+	write := func(command Syncer) {
+		select {
+		case outbound <- command:
+		case <-writing:
+		}
+	}
+	for _, command := range pending {
+		write(command)
+	}
 
 	// Main run loop
 	var response Command
 	for {
 		select {
-		case command, ok := <-r.outgoing:
-			if !ok {
-				return
-			}
-			outbound <- command
-			id := reflect.ValueOf(command).Elem().FieldByName("Id").Uint()
-			pending[id] = command
+		case <-r.closing:
+			return true
+
+		case command := <-r.outgoing:
+			write(command)
+			pending[commandId(command)] = command
+
+		case id := <-r.cancelled:
+			delete(pending, id)
 
 		case in, ok := <-inbound:
 			if !ok {
 				glog.Errorln("Connection closed by server")
-				return
+				return false
 			}
 
 			if err := json.Unmarshal(in, &response); err != nil {
@@ -145,14 +351,20 @@ func (r *Remote) run() {
 					glog.Errorln(err.Error(), string(in))
 					continue
 				}
-				r.Incoming <- cmd
+				if ledger, ok := cmd.(*LedgerStreamMsg); ok {
+					*lastLedger = ledger.LedgerSequence
+				}
+				if !r.deliver(response.Type, cmd) {
+					glog.Errorf("Disconnecting as the channel for %s messages is full", response.Type)
+					return false
+				}
 				continue
 			}
 
 			// Command response message
 			cmd, ok := pending[response.Id]
 			if !ok {
-				glog.Errorf("Unexpected message: %+v", response)
+				glog.Errorf("Unexpected message: %s", in)
 				continue
 			}
 			delete(pending, response.Id)
@@ -162,37 +374,145 @@ func (r *Remote) run() {
 				cmd.Fail(msg)
 				continue
 			}
+			switch c := cmd.(type) {
+			case *SubscribeCommand:
+				if c.CommandError == nil {
+					*subscriptions = addSubscription(*subscriptions, c.Subscription)
+				}
+			case *UnsubscribeCommand:
+				if c.CommandError == nil {
+					*subscriptions = removeSubscription(*subscriptions, c.Subscription)
+				}
+			case *PathFindCreateCommand:
+				switch {
+				case c.CommandError != nil:
+				case c.session != nil:
+					r.started(c.session, c.Result, c.Id)
+				default:
+					// The server has replaced the session's request
+					r.endPathFind()
+				}
+			case *PathFindCommand:
+				if c.CommandError == nil && c.session != nil && c.session == r.pathFind {
+					r.endPathFind()
+				}
+			}
 			cmd.Done()
 		}
 	}
 }
 
+// addSubscription records a successful subscription for replay after
+// reconnection, unless an identical one is already recorded.
+func addSubscription(subscriptions []Subscription, s Subscription) []Subscription {
+	for _, existing := range subscriptions {
+		if reflect.DeepEqual(existing, s) {
+			return subscriptions
+		}
+	}
+	return append(subscriptions, s)
+}
+
+// removeSubscription takes what has been unsubscribed from out of the
+// recorded subscriptions, dropping those with nothing left.
+func removeSubscription(subscriptions []Subscription, u Subscription) []Subscription {
+	var remaining []Subscription
+	for _, s := range subscriptions {
+		if s = s.Without(u); !s.IsEmpty() {
+			remaining = append(remaining, s)
+		}
+	}
+	return remaining
+}
+
+func commandId(command Syncer) uint64 {
+	return reflect.ValueOf(command).Elem().FieldByName("Id").Uint()
+}
+
+func commandName(command Syncer) string {
+	return reflect.ValueOf(command).Elem().FieldByName("Name").String()
+}
+
+// enqueue hands a command to the run loop, giving up if ctx is done first.
+// A done ctx is checked before anything else, as select would choose at
+// random between it and a ready run loop.
+func (r *Remote) enqueue(ctx context.Context, cmd Syncer, c *Command) error {
+	if err := ctx.Err(); err != nil {
+		return &ContextError{Name: c.Name, Id: c.Id, Err: err}
+	}
+	select {
+	case r.outgoing <- cmd:
+		return nil
+	case <-ctx.Done():
+		return &ContextError{Name: c.Name, Id: c.Id, Err: ctx.Err()}
+	case <-r.closed:
+		return clientError("Connection Closed")
+	}
+}
+
+// wait blocks until the response to an enqueued command arrives.
+// If ctx is done first, the command is withdrawn from the run loop.
+func (r *Remote) wait(ctx context.Context, c *Command) error {
+	select {
+	case <-c.Ready:
+	case <-ctx.Done():
+		select {
+		case r.cancelled <- c.Id:
+		case <-r.closed:
+		}
+		return &ContextError{Name: c.Name, Id: c.Id, Err: ctx.Err()}
+	case <-r.closed:
+		select {
+		case <-c.Ready:
+		default:
+			return clientError("Connection Closed")
+		}
+	}
+	if c.CommandError != nil {
+		return c.CommandError
+	}
+	return nil
+}
+
+// send enqueues a command and waits for its response.
+func (r *Remote) send(ctx context.Context, cmd Syncer, c *Command) error {
+	if err := r.enqueue(ctx, cmd, c); err != nil {
+		return err
+	}
+	return r.wait(ctx, c)
+}
+
 // Synchronously get a single transaction
-func (r *Remote) Tx(hash data.Hash256) (*TxResult, error) {
+func (r *commands) Tx(hash data.Hash256) (*TxResult, error) {
+	return r.TxContext(context.Background(), hash)
+}
+
+// TxContext is like Tx, but gives up when ctx is done.
+func (r *commands) TxContext(ctx context.Context, hash data.Hash256) (*TxResult, error) {
 	cmd := &TxCommand{
 		Command:     newCommand("tx"),
 		Transaction: hash,
 	}
-	r.outgoing <- cmd
-	<-cmd.Ready
-	if cmd.CommandError != nil {
-		return nil, cmd.CommandError
+	if err := r.send(ctx, cmd, cmd.Command); err != nil {
+		return nil, err
 	}
 	return cmd.Result, nil
 }
 
-func (r *Remote) accountTx(account data.Account, c chan *data.TransactionWithMetaData, pageSize int, minLedger, maxLedger int64) {
+func (r *commands) accountTx(ctx context.Context, account data.Account, c chan *data.TransactionWithMetaData, pageSize int, minLedger, maxLedger int64) {
 	defer close(c)
 	cmd := newAccountTxCommand(account, pageSize, nil, minLedger, maxLedger)
 	for ; ; cmd = newAccountTxCommand(account, pageSize, cmd.Result.Marker, minLedger, maxLedger) {
-		r.outgoing <- cmd
-		<-cmd.Ready
-		if cmd.CommandError != nil {
-			glog.Errorln(cmd.Error())
+		if err := r.send(ctx, cmd, cmd.Command); err != nil {
+			glog.Errorln(err.Error())
 			return
 		}
 		for _, tx := range cmd.Result.Transactions {
-			c <- tx
+			select {
+			case c <- tx:
+			case <-ctx.Done():
+				return
+			}
 		}
 		if cmd.Result.Marker == nil {
 			return
@@ -208,14 +528,26 @@ func (r *Remote) accountTx(account data.Account, c chan *data.TransactionWithMet
 //
 // Use minLedger -1 for the earliest ledger available.
 // Use maxLedger -1 for the most recent validated ledger.
-func (r *Remote) AccountTx(account data.Account, pageSize int, minLedger, maxLedger int64) chan *data.TransactionWithMetaData {
+func (r *commands) AccountTx(account data.Account, pageSize int, minLedger, maxLedger int64) chan *data.TransactionWithMetaData {
+	return r.AccountTxContext(context.Background(), account, pageSize, minLedger, maxLedger)
+}
+
+// AccountTxContext is like AccountTx, but stops paging and closes the
+// returned channel when ctx is done.
+func (r *commands) AccountTxContext(ctx context.Context, account data.Account, pageSize int, minLedger, maxLedger int64) chan *data.TransactionWithMetaData {
 	c := make(chan *data.TransactionWithMetaData)
-	go r.accountTx(account, c, pageSize, minLedger, maxLedger)
+	go r.accountTx(ctx, account, c, pageSize, minLedger, maxLedger)
 	return c
 }
 
 // Synchronously submit a single transaction
-func (r *Remote) Submit(tx data.Transaction) (*SubmitResult, error) {
+func (r *commands) Submit(tx data.Transaction) (*SubmitResult, error) {
+	return r.SubmitContext(context.Background(), tx)
+}
+
+// SubmitContext is like Submit, but gives up when ctx is done.
+// The transaction may still be applied by the server.
+func (r *commands) SubmitContext(ctx context.Context, tx data.Transaction) (*SubmitResult, error) {
 	_, raw, err := data.Raw(tx)
 	if err != nil {
 		return nil, err
@@ -224,53 +556,83 @@ func (r *Remote) Submit(tx data.Transaction) (*SubmitResult, error) {
 		Command: newCommand("submit"),
 		TxBlob:  fmt.Sprintf("%X", raw),
 	}
-	r.outgoing <- cmd
-	<-cmd.Ready
-	if cmd.CommandError != nil {
-		return nil, cmd.CommandError
+	if err := r.send(ctx, cmd, cmd.Command); err != nil {
+		return nil, err
 	}
 	return cmd.Result, nil
 }
 
 // Synchronously submit multiple transactions
 func (r *Remote) SubmitBatch(txs []data.Transaction) ([]*SubmitResult, error) {
+	return r.SubmitBatchContext(context.Background(), txs)
+}
+
+// SubmitBatchContext is like SubmitBatch, but gives up on all the
+// outstanding submissions when ctx is done. Nothing is submitted if any of
+// txs cannot be encoded, but those already sent may still be applied by
+// the server.
+func (r *Remote) SubmitBatchContext(ctx context.Context, txs []data.Transaction) ([]*SubmitResult, error) {
 	commands := make([]*SubmitCommand, len(txs))
-	results := make([]*SubmitResult, len(txs))
 	for i := range txs {
 		_, raw, err := data.Raw(txs[i])
 		if err != nil {
 			return nil, err
 		}
-		cmd := &SubmitCommand{
+		commands[i] = &SubmitCommand{
 			Command: newCommand("submit"),
 			TxBlob:  fmt.Sprintf("%X", raw),
 		}
-		r.outgoing <- cmd
-		commands[i] = cmd
 	}
-	for i := range commands {
-		<-commands[i].Ready
-		results[i] = commands[i].Result
+	for i, cmd := range commands {
+		if err := r.enqueue(ctx, cmd, cmd.Command); err != nil {
+			r.abandon(commands[:i])
+			return nil, err
+		}
+	}
+	results := make([]*SubmitResult, len(commands))
+	for i, cmd := range commands {
+		if err := r.wait(ctx, cmd.Command); err != nil {
+			if _, ok := err.(*ContextError); ok {
+				r.abandon(commands[i+1:])
+				return nil, err
+			}
+		}
+		results[i] = cmd.Result
 	}
 	return results, nil
 }
 
+// abandon withdraws enqueued submissions from the run loop without waiting
+// for their responses.
+func (r *Remote) abandon(commands []*SubmitCommand) {
+	for _, cmd := range commands {
+		select {
+		case r.cancelled <- cmd.Id:
+		case <-r.closed:
+			return
+		}
+	}
+}
+
 // Synchronously gets ledger entries
-func (r *Remote) LedgerData(ledger interface{}, marker *data.Hash256) (*LedgerDataResult, error) {
+func (r *commands) LedgerData(ledger interface{}, marker *data.Hash256) (*LedgerDataResult, error) {
+	return r.LedgerDataContext(context.Background(), ledger, marker)
+}
+
+// LedgerDataContext is like LedgerData, but gives up when ctx is done.
+func (r *commands) LedgerDataContext(ctx context.Context, ledger interface{}, marker *data.Hash256) (*LedgerDataResult, error) {
 	cmd := &LedgerDataCommand{
 		Command: newCommand("ledger_data"),
 		Ledger:  ledger,
 		Marker:  marker,
 	}
-	r.outgoing <- cmd
-	<-cmd.Ready
-	if cmd.CommandError != nil {
-		return nil, cmd.CommandError
+	if err := r.send(ctx, cmd, cmd.Command); err != nil {
+		return nil, err
 	}
 	return cmd.Result, nil
 }
 
-func (r *Remote) streamLedgerData(ledger interface{}, start, end string, c chan data.LedgerEntrySlice, wg *sync.WaitGroup) {
+func (r *commands) streamLedgerData(ctx context.Context, ledger interface{}, start, end string, c chan data.LedgerEntrySlice, wg *sync.WaitGroup) {
 	defer wg.Done()
 	first, err := data.NewHash256(start)
 	if err != nil {
@@ -279,10 +641,8 @@ func (r *Remote) streamLedgerData(ledger interface{}, start, end string, c chan
 	cmd := newBinaryLedgerDataCommand(ledger, first)
 	var br bytes.Reader
 	for ; ; cmd = newBinaryLedgerDataCommand(ledger, cmd.Result.Marker) {
-		r.outgoing <- cmd
-		<-cmd.Ready
-		if cmd.CommandError != nil {
-			glog.Errorln(cmd.Error())
+		if err := r.send(ctx, cmd, cmd.Command); err != nil {
+			glog.Errorln(err.Error())
 			return
 		}
 		les := make(data.LedgerEntrySlice, 0, len(cmd.Result.State))
@@ -306,7 +666,11 @@ func (r *Remote) streamLedgerData(ledger interface{}, start, end string, c chan
 			}
 			les = append(les, le)
 		}
-		c <- les
+		select {
+		case c <- les:
+		case <-ctx.Done():
+			return
+		}
 		if cmd.Result.Marker == nil || done {
 			return
 		}
@@ -314,14 +678,20 @@ func (r *Remote) streamLedgerData(ledger interface{}, start, end string, c chan
 }
 
 // Asynchronously retrieve all data for a ledger using the binary form
-func (r *Remote) StreamLedgerData(ledger interface{}) chan data.LedgerEntrySlice {
+func (r *commands) StreamLedgerData(ledger interface{}) chan data.LedgerEntrySlice {
+	return r.StreamLedgerDataContext(context.Background(), ledger)
+}
+
+// StreamLedgerDataContext is like StreamLedgerData, but stops all the
+// workers and closes the returned channel when ctx is done.
+func (r *commands) StreamLedgerDataContext(ctx context.Context, ledger interface{}) chan data.LedgerEntrySlice {
 	c := make(chan data.LedgerEntrySlice, 100)
 	wg := &sync.WaitGroup{}
 	for i := 0; i < 16; i++ {
 		wg.Add(1)
 		start := fmt.Sprintf("%X%s", i, strings.Repeat("0", 63))
 		end := fmt.Sprintf("%X%s", i, strings.Repeat("F", 63))
-		go r.streamLedgerData(ledger, start, end, c, wg)
+		go r.streamLedgerData(ctx, ledger, start, end, c, wg)
 	}
 	go func() {
 		wg.Wait()
@@ -331,37 +701,48 @@ func (r *Remote) StreamLedgerData(ledger interface{}) chan data.LedgerEntrySlice
 }
 
 // Synchronously gets a single ledger
-func (r *Remote) Ledger(ledger interface{}, transactions bool) (*LedgerResult, error) {
+func (r *commands) Ledger(ledger interface{}, transactions bool) (*LedgerResult, error) {
+	return r.LedgerContext(context.Background(), ledger, transactions)
+}
+
+// LedgerContext is like Ledger, but gives up when ctx is done.
+func (r *commands) LedgerContext(ctx context.Context, ledger interface{}, transactions bool) (*LedgerResult, error) {
 	cmd := &LedgerCommand{
 		Command:      newCommand("ledger"),
 		LedgerIndex:  ledger,
 		Transactions: transactions,
 		Expand:       true,
 	}
-	r.outgoing <- cmd
-	<-cmd.Ready
-	if cmd.CommandError != nil {
-		return nil, cmd.CommandError
+	if err := r.send(ctx, cmd, cmd.Command); err != nil {
+		return nil, err
 	}
 	cmd.Result.Ledger.Transactions.Sort()
 	return cmd.Result, nil
 }
 
-func (r *Remote) LedgerHeader(ledger interface{}) (*LedgerHeaderResult, error) {
+func (r *commands) LedgerHeader(ledger interface{}) (*LedgerHeaderResult, error) {
+	return r.LedgerHeaderContext(context.Background(), ledger)
+}
+
+// LedgerHeaderContext is like LedgerHeader, but gives up when ctx is done.
+func (r *commands) LedgerHeaderContext(ctx context.Context, ledger interface{}) (*LedgerHeaderResult, error) {
 	cmd := &LedgerHeaderCommand{
 		Command: newCommand("ledger_header"),
 		Ledger:  ledger,
 	}
-	r.outgoing <- cmd
-	<-cmd.Ready
-	if cmd.CommandError != nil {
-		return nil, cmd.CommandError
+	if err := r.send(ctx, cmd, cmd.Command); err != nil {
+		return nil, err
 	}
 	return cmd.Result, nil
 }
 
 // Synchronously requests paths
-func (r *Remote) RipplePathFind(src, dest data.Account, amount data.Amount, srcCurr *[]data.Currency) (*RipplePathFindResult, error) {
+func (r *commands) RipplePathFind(src, dest data.Account, amount data.Amount, srcCurr *[]data.Currency) (*RipplePathFindResult, error) {
+	return r.RipplePathFindContext(context.Background(), src, dest, amount, srcCurr)
+}
+
+// RipplePathFindContext is like RipplePathFind, but gives up when ctx is done.
+func (r *commands) RipplePathFindContext(ctx context.Context, src, dest data.Account, amount data.Amount, srcCurr *[]data.Currency) (*RipplePathFindResult, error) {
 	cmd := &RipplePathFindCommand{
 		Command:       newCommand("ripple_path_find"),
 		SrcAccount:    src,
@@ -369,60 +750,72 @@ func (r *Remote) RipplePathFind(src, dest data.Account, amount data.Amount, srcC
 		DestAccount:   dest,
 		DestAmount:    amount,
 	}
-	r.outgoing <- cmd
-	<-cmd.Ready
-	if cmd.CommandError != nil {
-		return nil, cmd.CommandError
+	if err := r.send(ctx, cmd, cmd.Command); err != nil {
+		return nil, err
 	}
 	return cmd.Result, nil
 }
 
 // Synchronously requests account info
-func (r *Remote) AccountInfo(a data.Account, ledger interface{}) (*AccountInfoResult, error) {
+func (r *commands) AccountInfo(a data.Account, ledger interface{}) (*AccountInfoResult, error) {
+	return r.AccountInfoContext(context.Background(), a, ledger)
+}
+
+// AccountInfoContext is like AccountInfo, but gives up when ctx is done.
+func (r *commands) AccountInfoContext(ctx context.Context, a data.Account, ledger interface{}) (*AccountInfoResult, error) {
 	cmd := &AccountInfoCommand{
 		Command:     newCommand("account_info"),
 		Account:     a,
 		LedgerIndex: ledger,
 	}
-	r.outgoing <- cmd
-	<-cmd.Ready
-	if cmd.CommandError != nil {
-		return nil, cmd.CommandError
+	if err := r.send(ctx, cmd, cmd.Command); err != nil {
+		return nil, err
 	}
 	return cmd.Result, nil
 }
 
 // Synchronously requests server info
-func (r *Remote) ServerInfo() (*ServerInfoResult, error) {
+func (r *commands) ServerInfo() (*ServerInfoResult, error) {
+	return r.ServerInfoContext(context.Background())
+}
+
+// ServerInfoContext is like ServerInfo, but gives up when ctx is done.
+func (r *commands) ServerInfoContext(ctx context.Context) (*ServerInfoResult, error) {
 	cmd := &ServerInfoCommand{
 		Command: newCommand("server_info"),
 	}
-	r.outgoing <- cmd
-	<-cmd.Ready
-	if cmd.CommandError != nil {
-		return nil, cmd.CommandError
+	if err := r.send(ctx, cmd, cmd.Command); err != nil {
+		return nil, err
 	}
 	return cmd.Result, nil
 }
 
 // Synchronously requests DepositAuthorized
-func (r *Remote) DepositAuthorized(source, dest data.Account, ledger interface{}) (*DepositAuthorizedResult, error) {
+func (r *commands) DepositAuthorized(source, dest data.Account, ledger interface{}) (*DepositAuthorizedResult, error) {
+	return r.DepositAuthorizedContext(context.Background(), source, dest, ledger)
+}
+
+// DepositAuthorizedContext is like DepositAuthorized, but gives up when ctx is done.
+func (r *commands) DepositAuthorizedContext(ctx context.Context, source, dest data.Account, ledger interface{}) (*DepositAuthorizedResult, error) {
 	cmd := &DepositAuthorizedCommand{
 		Command:     newCommand("deposit_authorized"),
 		Source:      source,
 		Dest:        dest,
 		LedgerIndex: ledger,
 	}
-	r.outgoing <- cmd
-	<-cmd.Ready
-	if cmd.CommandError != nil {
-		return nil, cmd.CommandError
+	if err := r.send(ctx, cmd, cmd.Command); err != nil {
+		return nil, err
 	}
 	return cmd.Result, nil
 }
 
 // Synchronously requests account line info
-func (r *Remote) AccountLines(account data.Account, ledgerIndex interface{}, peer string) (*AccountLinesResult, error) {
+func (r *commands) AccountLines(account data.Account, ledgerIndex interface{}, peer string) (*AccountLinesResult, error) {
+	return r.AccountLinesContext(context.Background(), account, ledgerIndex, peer)
+}
+
+// AccountLinesContext is like AccountLines, but gives up when ctx is done.
+func (r *commands) AccountLinesContext(ctx context.Context, account data.Account, ledgerIndex interface{}, peer string) (*AccountLinesResult, error) {
 	var (
 		lines  data.AccountLineSlice
 		marker *data.Hash256
@@ -444,11 +837,10 @@ func (r *Remote) AccountLines(account data.Account, ledgerIndex interface{}, pee
 			cmd.Peer = p
 		}
 
-		r.outgoing <- cmd
-		<-cmd.Ready
+		err := r.send(ctx, cmd, cmd.Command)
 		switch {
-		case cmd.CommandError != nil:
-			return nil, cmd.CommandError
+		case err != nil:
+			return nil, err
 		case cmd.Result.Marker != nil:
 			lines = append(lines, cmd.Result.Lines...)
 			marker = cmd.Result.Marker
@@ -464,7 +856,12 @@ func (r *Remote) AccountLines(account data.Account, ledgerIndex interface{}, pee
 }
 
 // Synchronously requests account offers
-func (r *Remote) AccountOffers(account data.Account, ledgerIndex interface{}) (*AccountOffersResult, error) {
+func (r *commands) AccountOffers(account data.Account, ledgerIndex interface{}) (*AccountOffersResult, error) {
+	return r.AccountOffersContext(context.Background(), account, ledgerIndex)
+}
+
+// AccountOffersContext is like AccountOffers, but gives up when ctx is done.
+func (r *commands) AccountOffersContext(ctx context.Context, account data.Account, ledgerIndex interface{}) (*AccountOffersResult, error) {
 	var (
 		offers data.AccountOfferSlice
 		marker *data.Hash256
@@ -477,11 +874,10 @@ func (r *Remote) AccountOffers(account data.Account, ledgerIndex interface{}) (*
 			Marker:      marker,
 			LedgerIndex: ledgerIndex,
 		}
-		r.outgoing <- cmd
-		<-cmd.Ready
+		err := r.send(ctx, cmd, cmd.Command)
 		switch {
-		case cmd.CommandError != nil:
-			return nil, cmd.CommandError
+		case err != nil:
+			return nil, err
 		case cmd.Result.Marker != nil:
 			offers = append(offers, cmd.Result.Offers...)
 			marker = cmd.Result.Marker
@@ -496,7 +892,12 @@ func (r *Remote) AccountOffers(account data.Account, ledgerIndex interface{}) (*
 	}
 }
 
-func (r *Remote) BookOffers(taker data.Account, ledgerIndex interface{}, pays, gets data.Asset) (*BookOffersResult, error) {
+func (r *commands) BookOffers(taker data.Account, ledgerIndex interface{}, pays, gets data.Asset) (*BookOffersResult, error) {
+	return r.BookOffersContext(context.Background(), taker, ledgerIndex, pays, gets)
+}
+
+// BookOffersContext is like BookOffers, but gives up when ctx is done.
+func (r *commands) BookOffersContext(ctx context.Context, taker data.Account, ledgerIndex interface{}, pays, gets data.Asset) (*BookOffersResult, error) {
 	cmd := &BookOffersCommand{
 		Command:     newCommand("book_offers"),
 		LedgerIndex: ledgerIndex,
@@ -505,10 +906,8 @@ func (r *Remote) BookOffers(taker data.Account, ledgerIndex interface{}, pays, g
 		TakerGets:   gets,
 		Limit:       5000, // Marker not implemented....
 	}
-	r.outgoing <- cmd
-	<-cmd.Ready
-	if cmd.CommandError != nil {
-		return nil, cmd.CommandError
+	if err := r.send(ctx, cmd, cmd.Command); err != nil {
+		return nil, err
 	}
 	return cmd.Result, nil
 }
@@ -516,6 +915,12 @@ func (r *Remote) BookOffers(taker data.Account, ledgerIndex interface{}, pays, g
 // Synchronously subscribe to streams and receive a confirmation message
 // Streams are recived asynchronously over the Incoming channel
 func (r *Remote) Subscribe(ledger, transactions, transactionsProposed, server bool) (*SubscribeResult, error) {
+	return r.SubscribeContext(context.Background(), ledger, transactions, transactionsProposed, server)
+}
+
+// SubscribeContext is like Subscribe, but gives up waiting for the
+// confirmation when ctx is done. The subscription may still be made.
+func (r *Remote) SubscribeContext(ctx context.Context, ledger, transactions, transactionsProposed, server bool) (*SubscribeResult, error) {
 	streams := []string{}
 	if ledger {
 		streams = append(streams, "ledger")
@@ -530,13 +935,11 @@ func (r *Remote) Subscribe(ledger, transactions, transactionsProposed, server bo
 		streams = append(streams, "server")
 	}
 	cmd := &SubscribeCommand{
-		Command: newCommand("subscribe"),
-		Streams: streams,
+		Command:      newCommand("subscribe"),
+		Subscription: Subscription{Streams: streams},
 	}
-	r.outgoing <- cmd
-	<-cmd.Ready
-	if cmd.CommandError != nil {
-		return nil, cmd.CommandError
+	if err := r.send(ctx, cmd, cmd.Command); err != nil {
+		return nil, err
 	}
 
 	if ledger && cmd.Result.LedgerStreamMsg == nil {
@@ -548,6 +951,26 @@ func (r *Remote) Subscribe(ledger, transactions, transactionsProposed, server bo
 	return cmd.Result, nil
 }
 
+// SubscribeValidations subscribes to the validations and manifests
+// streams, whose messages are received over the Incoming channel as
+// ValidationStreamMsg and ManifestStreamMsg.
+func (r *Remote) SubscribeValidations() (*SubscribeResult, error) {
+	return r.SubscribeValidationsContext(context.Background())
+}
+
+// SubscribeValidationsContext is like SubscribeValidations, but gives up
+// waiting for the confirmation when ctx is done.
+func (r *Remote) SubscribeValidationsContext(ctx context.Context) (*SubscribeResult, error) {
+	cmd := &SubscribeCommand{
+		Command:      newCommand("subscribe"),
+		Subscription: Subscription{Streams: []string{StreamValidations, StreamManifests}},
+	}
+	if err := r.send(ctx, cmd, cmd.Command); err != nil {
+		return nil, err
+	}
+	return cmd.Result, nil
+}
+
 type OrderBookSubscription struct {
 	TakerGets data.Asset `json:"taker_gets"`
 	TakerPays data.Asset `json:"taker_pays"`
@@ -555,39 +978,116 @@ type OrderBookSubscription struct {
 	Both      bool       `json:"both"`
 }
 
+func (b OrderBookSubscription) sameBook(other OrderBookSubscription) bool {
+	return b.TakerGets == other.TakerGets && b.TakerPays == other.TakerPays && b.Both == other.Both
+}
+
 func (r *Remote) SubscribeOrderBooks(books []OrderBookSubscription) (*SubscribeResult, error) {
+	return r.SubscribeOrderBooksContext(context.Background(), books)
+}
+
+// SubscribeOrderBooksContext is like SubscribeOrderBooks, but gives up
+// waiting for the confirmation when ctx is done.
+func (r *Remote) SubscribeOrderBooksContext(ctx context.Context, books []OrderBookSubscription) (*SubscribeResult, error) {
 	cmd := &SubscribeCommand{
 		Command: newCommand("subscribe"),
-		Streams: []string{"ledger", "server"},
-		Books:   books,
+		Subscription: Subscription{
+			Streams: []string{StreamLedger, StreamServer},
+			Books:   books,
+		},
 	}
-	r.outgoing <- cmd
-	<-cmd.Ready
-	if cmd.CommandError != nil {
-		return nil, cmd.CommandError
+	if err := r.send(ctx, cmd, cmd.Command); err != nil {
+		return nil, err
+	}
+	return cmd.Result, nil
+}
+
+// SubscribeTo makes any combination of subscriptions. Like the others, it
+// is replayed after reconnection until unsubscribed.
+func (r *Remote) SubscribeTo(s Subscription) (*SubscribeResult, error) {
+	return r.SubscribeToContext(context.Background(), s)
+}
+
+// SubscribeToContext is like SubscribeTo, but gives up waiting for the
+// confirmation when ctx is done.
+func (r *Remote) SubscribeToContext(ctx context.Context, s Subscription) (*SubscribeResult, error) {
+	if s.IsEmpty() {
+		return nil, fmt.Errorf("Nothing to subscribe to")
+	}
+	cmd := &SubscribeCommand{
+		Command:      newCommand("subscribe"),
+		Subscription: s,
+	}
+	if err := r.send(ctx, cmd, cmd.Command); err != nil {
+		return nil, err
 	}
 	return cmd.Result, nil
 }
 
-func (r *Remote) Fee() (*FeeResult, error) {
+// SubscribeStreams subscribes to streams such as StreamConsensus and
+// StreamBookChanges by name.
+func (r *Remote) SubscribeStreams(streams ...string) (*SubscribeResult, error) {
+	return r.SubscribeToContext(context.Background(), Subscription{Streams: streams})
+}
+
+// SubscribeAccounts subscribes to the transactions affecting accounts,
+// which are received over the Incoming channel as TransactionStreamMsg.
+// With proposed, transactions are also received before validation.
+func (r *Remote) SubscribeAccounts(accounts []data.Account, proposed bool) (*SubscribeResult, error) {
+	return r.SubscribeAccountsContext(context.Background(), accounts, proposed)
+}
+
+// SubscribeAccountsContext is like SubscribeAccounts, but gives up
+// waiting for the confirmation when ctx is done.
+func (r *Remote) SubscribeAccountsContext(ctx context.Context, accounts []data.Account, proposed bool) (*SubscribeResult, error) {
+	if proposed {
+		return r.SubscribeToContext(ctx, Subscription{AccountsProposed: accounts})
+	}
+	return r.SubscribeToContext(ctx, Subscription{Accounts: accounts})
+}
+
+// Unsubscribe stops the streams, accounts and books of s, which are then
+// no longer replayed after reconnection. Books are matched on their assets
+// and Both.
+func (r *Remote) Unsubscribe(s Subscription) error {
+	return r.UnsubscribeContext(context.Background(), s)
+}
+
+// UnsubscribeContext is like Unsubscribe, but gives up waiting for the
+// confirmation when ctx is done.
+func (r *Remote) UnsubscribeContext(ctx context.Context, s Subscription) error {
+	if s.IsEmpty() {
+		return fmt.Errorf("Nothing to unsubscribe from")
+	}
+	cmd := &UnsubscribeCommand{
+		Command:      newCommand("unsubscribe"),
+		Subscription: s,
+	}
+	return r.send(ctx, cmd, cmd.Command)
+}
+
+func (r *commands) Fee() (*FeeResult, error) {
+	return r.FeeContext(context.Background())
+}
+
+// FeeContext is like Fee, but gives up when ctx is done.
+func (r *commands) FeeContext(ctx context.Context) (*FeeResult, error) {
 	cmd := &FeeCommand{
 		Command: newCommand("fee"),
 	}
-	r.outgoing <- cmd
-	<-cmd.Ready
-	if cmd.CommandError != nil {
-		return nil, cmd.CommandError
+	if err := r.send(ctx, cmd, cmd.Command); err != nil {
+		return nil, err
 	}
 	return cmd.Result, nil
 }
 
 // readPump reads from the websocket and sends to inbound channel.
 // Expects to receive PONGs at specified interval, or logs an error and returns.
-func (r *Remote) readPump(inbound chan<- []byte) {
-	r.ws.SetReadDeadline(time.Now().Add(pongWait))
-	r.ws.SetPongHandler(func(string) error { r.ws.SetReadDeadline(time.Now().Add(pongWait)); return nil })
+func (r *Remote) readPump(ws *websocket.Conn, inbound chan<- []byte) {
+	ws.SetReadDeadline(time.Now().Add(pongWait))
+	ws.SetPongHandler(func(string) error { ws.SetReadDeadline(time.Now().Add(pongWait)); return nil })
 	for {
-		_, message, err := r.ws.ReadMessage()
+		_, message, err := ws.ReadMessage()
 		if err != nil {
 			glog.Errorln(err)
 			return
@@ -595,7 +1095,7 @@ func (r *Remote) readPump(inbound chan<- []byte) {
 		if glog.V(2) {
 			glog.Infoln(dump(message))
 		}
-		r.ws.SetReadDeadline(time.Now().Add(pongWait))
+		ws.SetReadDeadline(time.Now().Add(pongWait))
 		inbound <- message
 	}
 }
@@ -603,7 +1103,7 @@ func (r *Remote) readPump(inbound chan<- []byte) {
 // Consumes from the outbound channel and sends them over the websocket.
 // Also sends PING messages at the specified interval.
 // Returns when outbound channel is closed, or an error is encountered.
-func (r *Remote) writePump(outbound <-chan interface{}) {
+func (r *Remote) writePump(ws *websocket.Conn, outbound <-chan interface{}) {
 	ticker := time.NewTicker(pingPeriod)
 	defer ticker.Stop()
 
@@ -613,7 +1113,7 @@ func (r *Remote) writePump(outbound <-chan interface{}) {
 		// An outbound message is available to send
 		case message, ok := <-outbound:
 			if !ok {
-				r.ws.WriteMessage(websocket.CloseMessage, []byte{})
+				ws.WriteMessage(websocket.CloseMessage, []byte{})
 				return
 			}
 
@@ -626,14 +1126,14 @@ func (r *Remote) writePump(outbound <-chan interface{}) {
 			if glog.V(2) {
 				glog.Infoln(dump(b))
 			}
-			if err := r.ws.WriteMessage(websocket.TextMessage, b); err != nil {
+			if err := ws.WriteMessage(websocket.TextMessage, b); err != nil {
 				glog.Errorln(err)
 				return
 			}
 
 		// Time to send a ping
 		case <-ticker.C:
-			if err := r.ws.WriteMessage(websocket.PingMessage, []byte{}); err != nil {
+			if err := ws.WriteMessage(websocket.PingMessage, []byte{}); err != nil {
 				glog.Errorln(err)
 				return
 			}
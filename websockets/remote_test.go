@@ -0,0 +1,484 @@
+package websockets
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/parihaaraka/ripple/data"
+	. "gopkg.in/check.v1"
+)
+
+type RemoteSuite struct{}
+
+var _ = Suite(&RemoteSuite{})
+
+// fakeServer is an in-process stand-in for rippled. Each request is passed
+// to handler, and a non-nil return value is sent back to the client. A slice
+// return value is sent as separate messages.
+type fakeServer struct {
+	*httptest.Server
+	sync.Mutex
+	conns []*websocket.Conn
+}
+
+func newFakeServer(handler func(request map[string]interface{}) interface{}) *fakeServer {
+	var upgrader websocket.Upgrader
+	s := &fakeServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		s.Lock()
+		s.conns = append(s.conns, ws)
+		s.Unlock()
+		for {
+			var request map[string]interface{}
+			if err := ws.ReadJSON(&request); err != nil {
+				return
+			}
+			reply := handler(request)
+			responses, ok := reply.([]interface{})
+			if !ok {
+				responses = []interface{}{reply}
+			}
+			for _, response := range responses {
+				if response == nil {
+					continue
+				}
+				if err := ws.WriteJSON(response); err != nil {
+					return
+				}
+			}
+		}
+	}))
+	return s
+}
+
+// Drop abruptly closes all the open connections.
+func (s *fakeServer) Drop() {
+	s.Lock()
+	defer s.Unlock()
+	for _, ws := range s.conns {
+		ws.Close()
+	}
+	s.conns = nil
+}
+
+func (s *fakeServer) Endpoint() string {
+	return "ws" + strings.TrimPrefix(s.URL, "http")
+}
+
+func response(request map[string]interface{}, result interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"id":     request["id"],
+		"status": "success",
+		"type":   "response",
+		"result": result,
+	}
+}
+
+// Answers fee commands and ignores everything else
+func feeOnly(request map[string]interface{}) interface{} {
+	if request["command"] != "fee" {
+		return nil
+	}
+	return response(request, map[string]interface{}{"current_ledger_size": "5"})
+}
+
+func (s *RemoteSuite) TestContextTimeout(c *C) {
+	server := newFakeServer(feeOnly)
+	defer server.Close()
+	r, err := NewRemote(server.Endpoint())
+	c.Assert(err, IsNil)
+	defer r.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = r.ServerInfoContext(ctx)
+	c.Assert(err, NotNil)
+	ctxErr, ok := err.(*ContextError)
+	c.Assert(ok, Equals, true)
+	c.Check(ctxErr.Name, Equals, "server_info")
+	c.Check(ctxErr.Timeout(), Equals, true)
+	c.Check(errors.Is(err, context.DeadlineExceeded), Equals, true)
+
+	// The run loop must still be serving other commands
+	result, err := r.FeeContext(context.Background())
+	c.Assert(err, IsNil)
+	c.Check(result.CurrentLedgerSize, Equals, uint32(5))
+}
+
+func (s *RemoteSuite) TestContextCancel(c *C) {
+	var fees int32
+	server := newFakeServer(func(request map[string]interface{}) interface{} {
+		if request["command"] == "fee" {
+			atomic.AddInt32(&fees, 1)
+		}
+		return feeOnly(request)
+	})
+	defer server.Close()
+	r, err := NewRemote(server.Endpoint())
+	c.Assert(err, IsNil)
+	defer r.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	_, err = r.ServerInfoContext(ctx)
+	ctxErr, ok := err.(*ContextError)
+	c.Assert(ok, Equals, true)
+	c.Check(ctxErr.Timeout(), Equals, false)
+	c.Check(errors.Is(err, context.Canceled), Equals, true)
+
+	// Already cancelled contexts never reach the server, even when the
+	// run loop is waiting for a command
+	for i := 0; i < 20; i++ {
+		time.Sleep(time.Millisecond)
+		_, err = r.FeeContext(ctx)
+		c.Check(errors.Is(err, context.Canceled), Equals, true)
+	}
+	_, err = r.FeeContext(context.Background())
+	c.Assert(err, IsNil)
+	c.Check(atomic.LoadInt32(&fees), Equals, int32(1))
+}
+
+func (s *RemoteSuite) TestCommandsDuringClose(c *C) {
+	server := newFakeServer(feeOnly)
+	defer server.Close()
+	r, err := NewRemote(server.Endpoint())
+	c.Assert(err, IsNil)
+
+	// Commands racing Close fail rather than panic
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				r.FeeContext(context.Background())
+			}
+		}()
+	}
+	r.Close()
+	wg.Wait()
+	_, err = r.FeeContext(context.Background())
+	c.Check(err, ErrorMatches, ".*Connection Closed.*")
+}
+
+func (s *RemoteSuite) TestSubmitBatchEncodingError(c *C) {
+	var submitted int32
+	server := newFakeServer(func(request map[string]interface{}) interface{} {
+		if request["command"] == "submit" {
+			atomic.AddInt32(&submitted, 1)
+			return response(request, map[string]interface{}{"engine_result": "tesSUCCESS"})
+		}
+		return feeOnly(request)
+	})
+	defer server.Close()
+	r, err := NewRemote(server.Endpoint())
+	c.Assert(err, IsNil)
+	defer r.Close()
+
+	// Nothing is submitted if a later transaction cannot be encoded
+	good, bad := &data.AccountSet{}, &data.AccountSet{}
+	good.TransactionType, bad.TransactionType = data.ACCOUNT_SET, data.ACCOUNT_SET
+	domain := make(data.VariableLength, 1<<20)
+	bad.Domain = &domain
+	_, err = r.SubmitBatch([]data.Transaction{good, bad})
+	c.Check(err, ErrorMatches, "Unsupported Variable Length encoding: .*")
+	_, err = r.Fee()
+	c.Assert(err, IsNil)
+	c.Check(atomic.LoadInt32(&submitted), Equals, int32(0))
+
+	results, err := r.SubmitBatch([]data.Transaction{good, good})
+	c.Assert(err, IsNil)
+	c.Check(results, HasLen, 2)
+	c.Check(atomic.LoadInt32(&submitted), Equals, int32(2))
+}
+
+func (s *RemoteSuite) TestLateResponseDiscarded(c *C) {
+	late := make(chan map[string]interface{}, 1)
+	server := newFakeServer(func(request map[string]interface{}) interface{} {
+		switch request["command"] {
+		case "server_info":
+			late <- request
+			return nil
+		case "fee":
+			// Answer the abandoned command first
+			return []interface{}{response(<-late, map[string]interface{}{}), feeOnly(request)}
+		}
+		return nil
+	})
+	defer server.Close()
+	r, err := NewRemote(server.Endpoint())
+	c.Assert(err, IsNil)
+	defer r.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = r.ServerInfoContext(ctx)
+	c.Assert(err, FitsTypeOf, &ContextError{})
+
+	_, err = r.FeeContext(context.Background())
+	c.Assert(err, IsNil)
+}
+
+func (s *RemoteSuite) TestReconnect(c *C) {
+	var (
+		requests = make(chan map[string]interface{}, 10)
+		dropped  int32
+	)
+	server := newFakeServer(func(request map[string]interface{}) interface{} {
+		requests <- request
+		switch request["command"] {
+		case "subscribe":
+			return response(request, map[string]interface{}{"ledger_index": 7})
+		case "server_info", "submit":
+			// Only answered after reconnection
+			if atomic.LoadInt32(&dropped) == 0 {
+				return nil
+			}
+			return response(request, map[string]interface{}{})
+		}
+		return nil
+	})
+	defer server.Close()
+	r, err := NewReconnectingRemote(server.Endpoint())
+	c.Assert(err, IsNil)
+	defer r.Close()
+
+	result, err := r.Subscribe(true, false, false, false)
+	c.Assert(err, IsNil)
+	c.Check(result.LedgerSequence, Equals, uint32(7))
+	c.Check((<-requests)["command"], Equals, "subscribe")
+
+	infoErr := make(chan error)
+	go func() {
+		_, err := r.ServerInfo()
+		infoErr <- err
+	}()
+	c.Check((<-requests)["command"], Equals, "server_info")
+	submitErr := make(chan error)
+	go func() {
+		_, err := r.Submit(&data.AccountSet{TxBase: data.TxBase{TransactionType: data.ACCOUNT_SET}})
+		submitErr <- err
+	}()
+	c.Check((<-requests)["command"], Equals, "submit")
+
+	atomic.StoreInt32(&dropped, 1)
+	server.Drop()
+	msg := (<-r.Incoming).(*ConnectionStateMsg)
+	c.Check(msg.Connected, Equals, false)
+	c.Check(<-submitErr, ErrorMatches, ".*Connection Closed.*")
+	msg = (<-r.Incoming).(*ConnectionStateMsg)
+	c.Check(msg.Connected, Equals, true)
+
+	// The subscription is replayed and server_info is re-sent, in either order
+	replayed := map[interface{}]bool{}
+	for i := 0; i < 2; i++ {
+		replayed[(<-requests)["command"]] = true
+	}
+	c.Check(replayed, DeepEquals, map[interface{}]bool{"subscribe": true, "server_info": true})
+	c.Check(<-infoErr, IsNil)
+}
+
+func (s *RemoteSuite) TestUnsubscribe(c *C) {
+	requests := make(chan map[string]interface{}, 10)
+	server := newFakeServer(func(request map[string]interface{}) interface{} {
+		requests <- request
+		return response(request, map[string]interface{}{})
+	})
+	defer server.Close()
+	r, err := NewReconnectingRemote(server.Endpoint())
+	c.Assert(err, IsNil)
+	defer r.Close()
+
+	var alice, bob data.Account
+	alice[19], bob[19] = 1, 2
+	_, err = r.SubscribeAccounts([]data.Account{alice, bob}, false)
+	c.Assert(err, IsNil)
+	request := <-requests
+	c.Check(request["command"], Equals, "subscribe")
+	c.Check(request["accounts"], DeepEquals, []interface{}{alice.String(), bob.String()})
+	c.Check(request["streams"], IsNil)
+
+	_, err = r.SubscribeStreams(StreamConsensus)
+	c.Assert(err, IsNil)
+	c.Check((<-requests)["streams"], DeepEquals, []interface{}{"consensus"})
+
+	c.Check(r.Unsubscribe(Subscription{}), ErrorMatches, "Nothing to unsubscribe from")
+	c.Assert(r.Unsubscribe(Subscription{Streams: []string{StreamConsensus}, Accounts: []data.Account{bob}}), IsNil)
+	request = <-requests
+	c.Check(request["command"], Equals, "unsubscribe")
+	c.Check(request["accounts"], DeepEquals, []interface{}{bob.String()})
+
+	// Only what remains subscribed is replayed
+	server.Drop()
+	c.Check((<-r.Incoming).(*ConnectionStateMsg).Connected, Equals, false)
+	c.Check((<-r.Incoming).(*ConnectionStateMsg).Connected, Equals, true)
+	request = <-requests
+	c.Check(request["command"], Equals, "subscribe")
+	c.Check(request["accounts"], DeepEquals, []interface{}{alice.String()})
+	c.Check(request["streams"], IsNil)
+	select {
+	case request := <-requests:
+		c.Errorf("Unexpected request: %v", request)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// Answers subscribe commands with a burst of ledger stream messages
+func ledgerBurst(count int) func(request map[string]interface{}) interface{} {
+	return func(request map[string]interface{}) interface{} {
+		switch request["command"] {
+		case "subscribe":
+			replies := []interface{}{response(request, map[string]interface{}{"ledger_index": 1})}
+			for i := 1; i <= count; i++ {
+				replies = append(replies, map[string]interface{}{"type": "ledgerClosed", "ledger_index": i})
+			}
+			return replies
+		case "fee":
+			return feeOnly(request)
+		}
+		return nil
+	}
+}
+
+func (s *RemoteSuite) TestTypedStreamsDropOldest(c *C) {
+	server := newFakeServer(ledgerBurst(5))
+	defer server.Close()
+	r, err := NewRemoteWithOptions(server.Endpoint(), RemoteOptions{
+		TypedStreams: true,
+		Buffer:       2,
+		Overflow:     OverflowDropOldest,
+	})
+	c.Assert(err, IsNil)
+	defer r.Close()
+
+	_, err = r.Subscribe(true, false, false, false)
+	c.Assert(err, IsNil)
+
+	// Nobody is reading the ledgers, which must not stall commands
+	_, err = r.Fee()
+	c.Assert(err, IsNil)
+	c.Check(r.Dropped(), DeepEquals, map[string]uint64{"ledgerClosed": 3})
+	c.Check((<-r.Streams.Ledger).LedgerSequence, Equals, uint32(4))
+	c.Check((<-r.Streams.Ledger).LedgerSequence, Equals, uint32(5))
+	c.Check(r.Incoming, HasLen, 0)
+}
+
+func (s *RemoteSuite) TestTypedStreamsDisconnect(c *C) {
+	server := newFakeServer(ledgerBurst(2))
+	defer server.Close()
+	r, err := NewRemoteWithOptions(server.Endpoint(), RemoteOptions{
+		TypedStreams: true,
+		Buffer:       1,
+		Overflow:     OverflowDisconnect,
+	})
+	c.Assert(err, IsNil)
+	defer r.Close()
+
+	_, err = r.Subscribe(true, false, false, false)
+	c.Assert(err, IsNil)
+	_, ok := <-r.Incoming
+	c.Check(ok, Equals, false)
+	c.Check(r.Dropped(), DeepEquals, map[string]uint64{"ledgerClosed": 1})
+	c.Check((<-r.Streams.Ledger).LedgerSequence, Equals, uint32(1))
+	_, ok = <-r.Streams.Ledger
+	c.Check(ok, Equals, false)
+}
+
+func (s *RemoteSuite) TestReconnectDropOldest(c *C) {
+	server := newFakeServer(feeOnly)
+	defer server.Close()
+	r, err := NewRemoteWithOptions(server.Endpoint(), RemoteOptions{
+		Reconnect:    true,
+		TypedStreams: true,
+		Buffer:       1,
+		Overflow:     OverflowDropOldest,
+	})
+	c.Assert(err, IsNil)
+	defer r.Close()
+
+	// Nobody is reading Incoming, which must not stop the reconnections
+	for i := 0; i < 3; i++ {
+		server.Drop()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		_, err = r.FeeContext(ctx)
+		cancel()
+		c.Assert(err, IsNil)
+	}
+	c.Assert(r.Incoming, HasLen, 1)
+	c.Check((<-r.Incoming).(*ConnectionStateMsg).Connected, Equals, true)
+}
+
+func (s *RemoteSuite) TestPathFindSession(c *C) {
+	update := func(id interface{}, amount string, full bool) map[string]interface{} {
+		return map[string]interface{}{
+			"type":               "path_find",
+			"id":                 id,
+			"full_reply":         full,
+			"destination_amount": "1000",
+			"alternatives":       []interface{}{map[string]interface{}{"source_amount": amount}},
+		}
+	}
+	server := newFakeServer(func(request map[string]interface{}) interface{} {
+		switch request["subcommand"] {
+		case "create":
+			first := update(request["id"], "1200", false)
+			delete(first, "type")
+			return []interface{}{
+				response(request, first),
+				update(request["id"], "1100", true),
+				update(float64(0), "900", true), // Some other request
+			}
+		case "status":
+			return response(request, update(nil, "1100", true))
+		case "close":
+			return response(request, map[string]interface{}{"closed": true})
+		}
+		return nil
+	})
+	defer server.Close()
+	r, err := NewRemote(server.Endpoint())
+	c.Assert(err, IsNil)
+	defer r.Close()
+
+	var account data.Account
+	amount, err := data.NewAmount("1000")
+	c.Assert(err, IsNil)
+	session, err := r.PathFind(account, account, *amount, nil, nil)
+	c.Assert(err, IsNil)
+	first := <-session.Updates
+	c.Check(first.FullReply, Equals, false)
+	c.Check(first.Alternatives[0].SourceAmount.String(), Equals, "0.0012/XRP")
+	next := <-session.Updates
+	c.Check(next.FullReply, Equals, true)
+	c.Check(next.Id, Equals, first.Id)
+	c.Check(next.Alternatives[0].SourceAmount.String(), Equals, "0.0011/XRP")
+	other := (<-r.Incoming).(*PathFindCreateResult)
+	c.Check(other.Alternatives[0].SourceAmount.String(), Equals, "0.0009/XRP")
+
+	status, err := session.Status()
+	c.Assert(err, IsNil)
+	c.Check(status.FullReply, Equals, true)
+
+	// Replacing discards the first request's updates
+	c.Assert(session.Replace(account, account, *amount, nil, nil), IsNil)
+	replaced := <-session.Updates
+	c.Check(replaced.Id, Not(Equals), first.Id)
+	<-session.Updates
+	<-r.Incoming
+
+	c.Assert(session.Close(), IsNil)
+	_, ok := <-session.Updates
+	c.Check(ok, Equals, false)
+}
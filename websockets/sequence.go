@@ -0,0 +1,398 @@
+package websockets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/parihaaraka/ripple/crypto"
+	"github.com/parihaaraka/ripple/data"
+)
+
+// ErrNoTickets is returned by SequenceManager.ReserveTicket when the pool is empty.
+var ErrNoTickets = errors.New("No tickets available")
+
+// Reservation is a sequence, or a ticket, reserved for a single transaction.
+type Reservation struct {
+	Sequence uint32
+	Ticket   bool
+}
+
+// Apply sets the Sequence, or the TicketSequence, of tx.
+func (r Reservation) Apply(tx data.Transaction) error {
+	base := tx.GetBase()
+	if !r.Ticket {
+		base.Sequence = r.Sequence
+		return nil
+	}
+	f := reflect.Indirect(reflect.ValueOf(tx)).FieldByName("TicketSequence")
+	if !f.IsValid() {
+		return fmt.Errorf("%s cannot use a ticket", base.TransactionType)
+	}
+	ticket := r.Sequence
+	f.Set(reflect.ValueOf(&ticket))
+	base.Sequence = 0
+	return nil
+}
+
+// SequenceManager hands out the sequences of a single account to many
+// goroutines, so that transactions can be submitted without first asking
+// the network for the next sequence, or waiting for the previous one.
+//
+// Each reservation must be settled by Done once the outcome of its
+// transaction is known. Sequences of transactions which were never applied
+// leave gaps, which hold up all later transactions until they are filled
+// by FillGaps. Tickets, created by CreateTickets, can be used instead of
+// sequences by transactions which must not be held up.
+type SequenceManager struct {
+	client      Client
+	account     data.Account
+	key         crypto.Key
+	keySequence *uint32
+
+	mu          sync.Mutex
+	next        uint32            // Zero until loaded from the network
+	outstanding map[uint32]uint32 // By LastLedgerSequence once the outcome is unknown
+	gaps        map[uint32]bool
+	tickets     []uint32
+	reserved    map[uint32]uint32 // Tickets in use, by LastLedgerSequence once the outcome is unknown
+}
+
+// accountObjectsClient is implemented by the clients which can list the
+// tickets an account owns, so that Reconcile can rebuild the pool.
+type accountObjectsClient interface {
+	AccountObjectsContext(ctx context.Context, account data.Account, ledgerIndex interface{}, entryType data.LedgerEntryType) *AccountObjectsIterator
+}
+
+// NewSequenceManager returns a manager for the account, which signs the
+// gap fills and ticket creations it submits with key.
+func NewSequenceManager(client Client, account data.Account, key crypto.Key, keySequence *uint32) *SequenceManager {
+	return &SequenceManager{
+		client:      client,
+		account:     account,
+		key:         key,
+		keySequence: keySequence,
+		outstanding: make(map[uint32]uint32),
+		gaps:        make(map[uint32]bool),
+		reserved:    make(map[uint32]uint32),
+	}
+}
+
+// Reconcile updates the next sequence from the current ledger. Outstanding
+// sequences and gaps below the account's sequence have been consumed, by
+// this or another client, and are forgotten. Without any outstanding, the
+// next sequence is reset to the account's, discarding any gaps above it.
+// Outstanding sequences whose outcome was unknown become gaps once their
+// LastLedgerSequence has been validated. If the client can list account objects, the pool of tickets is rebuilt
+// from those owned in the validated ledger too.
+func (m *SequenceManager) Reconcile(ctx context.Context) error {
+	info, err := m.client.AccountInfoContext(ctx, m.account, "current")
+	if err != nil {
+		return err
+	}
+	if info.AccountData.Sequence == nil {
+		return fmt.Errorf("No sequence for %s", m.account)
+	}
+	current := *info.AccountData.Sequence
+	var validated uint32
+	if m.expiring() {
+		server, err := m.client.ServerInfoContext(ctx)
+		if err != nil {
+			return err
+		}
+		validated = uint32(server.Info.ValidatedLedger.Seq)
+	}
+	m.reconcileSequences(current, validated)
+	if client, ok := m.client.(accountObjectsClient); ok {
+		return m.reconcileTickets(ctx, client)
+	}
+	return nil
+}
+
+// expiring reports whether any outstanding sequence awaits the validation
+// of its LastLedgerSequence.
+func (m *SequenceManager) expiring() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, last := range m.outstanding {
+		if last != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *SequenceManager) reconcileSequences(current, validated uint32) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for sequence, last := range m.outstanding {
+		switch {
+		case sequence < current:
+			delete(m.outstanding, sequence)
+		case last != 0 && validated > last:
+			delete(m.outstanding, sequence)
+			m.gaps[sequence] = true
+		}
+	}
+	for sequence := range m.gaps {
+		if sequence < current {
+			delete(m.gaps, sequence)
+		}
+	}
+	if current > m.next || len(m.outstanding) == 0 {
+		m.next = current
+		for sequence := range m.gaps {
+			if sequence >= current {
+				delete(m.gaps, sequence)
+			}
+		}
+	}
+}
+
+// reconcileTickets replaces the pool with the tickets owned in the
+// validated ledger which are not in use. Tickets in use which are no
+// longer owned have been consumed and are forgotten. Those whose outcome
+// was unknown are returned to the pool once their LastLedgerSequence has
+// been validated without consuming them.
+func (m *SequenceManager) reconcileTickets(ctx context.Context, client accountObjectsClient) error {
+	it := client.AccountObjectsContext(ctx, m.account, "validated", data.TICKET)
+	owned := make(map[uint32]bool)
+	for it.Next() {
+		if ticket, ok := it.Object().(*data.Ticket); ok && ticket.TicketSequence != nil {
+			owned[*ticket.TicketSequence] = true
+		}
+	}
+	switch e, ok := it.Err().(*CommandError); {
+	case ok && e.Name == "actNotFound":
+		// Not yet in the validated ledger, so without tickets
+	case it.Err() != nil:
+		return it.Err()
+	}
+	validated := it.Ledger()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tickets = nil
+	for ticket := range owned {
+		last, used := m.reserved[ticket]
+		switch {
+		case !used:
+			m.tickets = append(m.tickets, ticket)
+		case last != 0 && validated > last:
+			delete(m.reserved, ticket)
+			m.tickets = append(m.tickets, ticket)
+		}
+	}
+	for ticket := range m.reserved {
+		if !owned[ticket] {
+			delete(m.reserved, ticket)
+		}
+	}
+	sort.Slice(m.tickets, func(i, j int) bool { return m.tickets[i] < m.tickets[j] })
+	return nil
+}
+
+// reserve returns the first of n consecutive sequences.
+func (m *SequenceManager) reserve(ctx context.Context, n uint32) (uint32, error) {
+	m.mu.Lock()
+	loaded := m.next != 0
+	m.mu.Unlock()
+	if !loaded {
+		if err := m.Reconcile(ctx); err != nil {
+			return 0, err
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	first := m.next
+	for i := uint32(0); i < n; i++ {
+		m.outstanding[first+i] = 0
+	}
+	m.next += n
+	return first, nil
+}
+
+// Reserve returns the next sequence, loading it from the network
+// the first time.
+func (m *SequenceManager) Reserve(ctx context.Context) (Reservation, error) {
+	sequence, err := m.reserve(ctx, 1)
+	return Reservation{Sequence: sequence}, err
+}
+
+// ReserveTicket takes a ticket from the pool.
+func (m *SequenceManager) ReserveTicket() (Reservation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.tickets) == 0 {
+		return Reservation{}, ErrNoTickets
+	}
+	ticket := m.tickets[0]
+	m.tickets = m.tickets[1:]
+	m.reserved[ticket] = 0
+	return Reservation{Sequence: ticket, Ticket: true}, nil
+}
+
+// Tickets returns the number of tickets in the pool.
+func (m *SequenceManager) Tickets() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.tickets)
+}
+
+// AddTickets puts tickets which the account already owns into the pool.
+func (m *SequenceManager) AddTickets(tickets ...uint32) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tickets = append(m.tickets, tickets...)
+	sort.Slice(m.tickets, func(i, j int) bool { return m.tickets[i] < m.tickets[j] })
+}
+
+// Done settles a reservation. applied reports whether its transaction was
+// included in a validated ledger, whatever its result. A sequence which
+// was not applied becomes a gap, and a ticket returns to the pool.
+func (m *SequenceManager) Done(r Reservation, applied bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if r.Ticket {
+		delete(m.reserved, r.Sequence)
+		if !applied {
+			m.tickets = append(m.tickets, r.Sequence)
+		}
+		return
+	}
+	delete(m.outstanding, r.Sequence)
+	if !applied {
+		m.gaps[r.Sequence] = true
+	}
+}
+
+// Gaps returns the sequences to be filled, in order.
+func (m *SequenceManager) Gaps() []uint32 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	gaps := make([]uint32, 0, len(m.gaps))
+	for sequence := range m.gaps {
+		gaps = append(gaps, sequence)
+	}
+	sort.Slice(gaps, func(i, j int) bool { return gaps[i] < gaps[j] })
+	return gaps
+}
+
+// settle calls Done with the outcome of SubmitAndWait for tx. When the
+// outcome is unknown, the reservation is left outstanding for Reconcile
+// along with the LastLedgerSequence of tx.
+func (m *SequenceManager) settle(r Reservation, tx data.Transaction, err error) {
+	switch err.(type) {
+	case nil:
+		m.Done(r, true)
+	case *SubmitError:
+		m.Done(r, false)
+	default:
+		last := tx.GetBase().LastLedgerSequence
+		switch {
+		case err == ErrExpired:
+			m.Done(r, false)
+		case last == nil:
+			// Never submitted
+			m.Done(r, false)
+		case r.Ticket:
+			m.mu.Lock()
+			m.reserved[r.Sequence] = *last
+			m.mu.Unlock()
+		default:
+			m.mu.Lock()
+			m.outstanding[r.Sequence] = *last
+			m.mu.Unlock()
+		}
+	}
+}
+
+// SubmitAndWait is like the SubmitAndWait function, but with the next
+// sequence, or a ticket from the pool if useTicket is true.
+func (m *SequenceManager) SubmitAndWait(ctx context.Context, tx data.Transaction, useTicket bool) (*data.TransactionWithMetaData, error) {
+	var (
+		r   Reservation
+		err error
+	)
+	if useTicket {
+		r, err = m.ReserveTicket()
+	} else {
+		r, err = m.Reserve(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Apply(tx); err != nil {
+		m.Done(r, false)
+		return nil, err
+	}
+	tx.GetBase().Account = m.account
+	txm, err := SubmitAndWait(ctx, m.client, tx, m.key, m.keySequence)
+	m.settle(r, tx, err)
+	return txm, err
+}
+
+// FillGaps submits a no-op AccountSet for each gap, so that the
+// transactions after it can be applied.
+func (m *SequenceManager) FillGaps(ctx context.Context) error {
+	for _, sequence := range m.Gaps() {
+		tx := &data.AccountSet{TxBase: data.TxBase{
+			TransactionType: data.ACCOUNT_SET,
+			Account:         m.account,
+			Sequence:        sequence,
+		}}
+		_, err := SubmitAndWait(ctx, m.client, tx, m.key, m.keySequence)
+		if e, ok := err.(*SubmitError); ok && e.EngineResult.Past() {
+			// Filled by another transaction
+			err = nil
+		}
+		if err != nil {
+			return err
+		}
+		m.mu.Lock()
+		delete(m.gaps, sequence)
+		m.mu.Unlock()
+	}
+	return nil
+}
+
+// CreateTickets submits a TicketCreate for count tickets and adds
+// them to the pool once it is validated.
+func (m *SequenceManager) CreateTickets(ctx context.Context, count uint32) error {
+	// The tickets take the sequences following the TicketCreate
+	first, err := m.reserve(ctx, count+1)
+	if err != nil {
+		return err
+	}
+	tx := &data.TicketCreate{
+		TxBase: data.TxBase{
+			TransactionType: data.TICKET_CREATE,
+			Account:         m.account,
+			Sequence:        first,
+		},
+		TicketCount: &count,
+	}
+	txm, err := SubmitAndWait(ctx, m.client, tx, m.key, m.keySequence)
+	m.settle(Reservation{Sequence: first}, tx, err)
+	created := err == nil && txm.MetaData.TransactionResult.Success()
+	tickets := make([]uint32, count)
+	for i := range tickets {
+		tickets[i] = first + 1 + uint32(i)
+		// Unused ticket sequences are gaps like any other
+		if err != nil {
+			m.settle(Reservation{Sequence: tickets[i]}, tx, err)
+		} else {
+			m.Done(Reservation{Sequence: tickets[i]}, created)
+		}
+	}
+	switch {
+	case err != nil:
+		return err
+	case !created:
+		return fmt.Errorf("TicketCreate failed: %s", txm.MetaData.TransactionResult)
+	}
+	m.AddTickets(tickets...)
+	return nil
+}
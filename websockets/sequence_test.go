@@ -0,0 +1,227 @@
+package websockets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/parihaaraka/ripple/data"
+	. "gopkg.in/check.v1"
+)
+
+type SequenceSuite struct{}
+
+var _ = Suite(&SequenceSuite{})
+
+func newTestSequenceManager(c *C, server *fakeServer) (*SequenceManager, *Remote) {
+	r, err := NewRemote(server.Endpoint())
+	c.Assert(err, IsNil)
+	seed, err := data.NewSeedFromAddress("snoPBrXtMeMyMHUVTgbuqAfg1SUTb")
+	c.Assert(err, IsNil)
+	var sequence uint32
+	account := seed.AccountId(data.ECDSA, &sequence)
+	return NewSequenceManager(r, account, seed.Key(data.ECDSA), &sequence), r
+}
+
+func (s *SequenceSuite) TestReserve(c *C) {
+	server, _ := submitServer(c, 99)
+	defer server.Close()
+	m, r := newTestSequenceManager(c, server)
+	defer r.Close()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		reserved = make(map[uint32]bool)
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := m.Reserve(context.Background())
+			c.Check(err, IsNil)
+			mu.Lock()
+			reserved[res.Sequence] = true
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	for sequence := uint32(5); sequence < 15; sequence++ {
+		c.Check(reserved[sequence], Equals, true)
+	}
+
+	// Unapplied sequences are gaps until reconciled without any outstanding
+	m.Done(Reservation{Sequence: 6}, false)
+	m.Done(Reservation{Sequence: 5}, true)
+	c.Check(m.Gaps(), DeepEquals, []uint32{6})
+	c.Assert(m.Reconcile(context.Background()), IsNil)
+	c.Check(m.Gaps(), DeepEquals, []uint32{6})
+	for sequence := uint32(7); sequence < 15; sequence++ {
+		m.Done(Reservation{Sequence: sequence}, false)
+	}
+	c.Assert(m.Reconcile(context.Background()), IsNil)
+	c.Check(m.Gaps(), HasLen, 0)
+	res, err := m.Reserve(context.Background())
+	c.Assert(err, IsNil)
+	c.Check(res.Sequence, Equals, uint32(5))
+}
+
+func (s *SequenceSuite) TestApply(c *C) {
+	payment := &data.Payment{}
+	payment.Sequence = 3
+	c.Assert(Reservation{Sequence: 9, Ticket: true}.Apply(payment), IsNil)
+	c.Check(payment.Sequence, Equals, uint32(0))
+	c.Check(*payment.TicketSequence, Equals, uint32(9))
+
+	fee := &data.SetFee{TxBase: data.TxBase{TransactionType: data.SET_FEE}}
+	c.Check(Reservation{Sequence: 9, Ticket: true}.Apply(fee), ErrorMatches, "SetFee cannot use a ticket")
+}
+
+func (s *SequenceSuite) TestCreateTickets(c *C) {
+	server, _ := submitServer(c, 99)
+	defer server.Close()
+	m, r := newTestSequenceManager(c, server)
+	defer r.Close()
+
+	c.Assert(m.CreateTickets(context.Background(), 3), IsNil)
+	c.Check(m.Tickets(), Equals, 3)
+	ticket, err := m.ReserveTicket()
+	c.Assert(err, IsNil)
+	c.Check(ticket, Equals, Reservation{Sequence: 6, Ticket: true})
+	m.Done(ticket, false)
+	c.Check(m.Tickets(), Equals, 3)
+
+	// The ticket sequences are skipped
+	res, err := m.Reserve(context.Background())
+	c.Assert(err, IsNil)
+	c.Check(res.Sequence, Equals, uint32(9))
+}
+
+// ledgerServer reports the validated ledger and the account's tickets in
+// it, which are changed by the test. Other commands are not answered.
+func ledgerServer(validated *uint32, tickets *atomic.Value) *fakeServer {
+	return newFakeServer(func(request map[string]interface{}) interface{} {
+		switch request["command"] {
+		case "account_info":
+			return response(request, map[string]interface{}{
+				"ledger_current_index": atomic.LoadUint32(validated) + 1,
+				"account_data":         map[string]interface{}{"LedgerEntryType": "AccountRoot", "Sequence": 5},
+			})
+		case "server_info":
+			return response(request, map[string]interface{}{
+				"info": map[string]interface{}{"validated_ledger": map[string]interface{}{"seq": atomic.LoadUint32(validated)}},
+			})
+		case "account_objects":
+			var objects []interface{}
+			for _, ticket := range tickets.Load().([]uint32) {
+				objects = append(objects, map[string]interface{}{
+					"LedgerEntryType": "Ticket",
+					"TicketSequence":  ticket,
+					"index":           fmt.Sprintf("%064X", ticket),
+				})
+			}
+			return response(request, map[string]interface{}{
+				"ledger_index":    atomic.LoadUint32(validated),
+				"account_objects": objects,
+			})
+		}
+		return nil
+	})
+}
+
+func (s *SequenceSuite) TestReconcileTickets(c *C) {
+	validated := uint32(100)
+	var tickets atomic.Value
+	tickets.Store([]uint32{8, 6, 7})
+	server := ledgerServer(&validated, &tickets)
+	defer server.Close()
+	m, r := newTestSequenceManager(c, server)
+	defer r.Close()
+	ctx := context.Background()
+
+	// The pool is loaded from the ledger
+	c.Assert(m.Reconcile(ctx), IsNil)
+	c.Check(m.Tickets(), Equals, 3)
+
+	// A ticket whose outcome is unknown stays out of the pool until its
+	// LastLedgerSequence is validated
+	ticket, err := m.ReserveTicket()
+	c.Assert(err, IsNil)
+	c.Check(ticket.Sequence, Equals, uint32(6))
+	last := uint32(120)
+	tx := &data.AccountSet{TxBase: data.TxBase{LastLedgerSequence: &last}}
+	m.settle(ticket, tx, context.Canceled)
+	c.Assert(m.Reconcile(ctx), IsNil)
+	c.Check(m.Tickets(), Equals, 2)
+	atomic.StoreUint32(&validated, 121)
+	c.Assert(m.Reconcile(ctx), IsNil)
+	c.Check(m.Tickets(), Equals, 3)
+
+	// Unless its transaction consumed it after all
+	ticket, err = m.ReserveTicket()
+	c.Assert(err, IsNil)
+	last = 130
+	m.settle(ticket, tx, context.Canceled)
+	tickets.Store([]uint32{7, 8})
+	c.Assert(m.Reconcile(ctx), IsNil)
+	c.Check(m.Tickets(), Equals, 2)
+	atomic.StoreUint32(&validated, 131)
+	c.Assert(m.Reconcile(ctx), IsNil)
+	c.Check(m.Tickets(), Equals, 2)
+	ticket, err = m.ReserveTicket()
+	c.Assert(err, IsNil)
+	c.Check(ticket.Sequence, Equals, uint32(7))
+
+	// A transaction which was never submitted returns its ticket at once
+	m.settle(ticket, &data.AccountSet{}, context.Canceled)
+	c.Check(m.Tickets(), Equals, 2)
+}
+
+func (s *SequenceSuite) TestReconcileUnknownSequence(c *C) {
+	validated := uint32(100)
+	var tickets atomic.Value
+	tickets.Store([]uint32{})
+	server := ledgerServer(&validated, &tickets)
+	defer server.Close()
+	m, r := newTestSequenceManager(c, server)
+	defer r.Close()
+	ctx := context.Background()
+	c.Assert(m.Reconcile(ctx), IsNil)
+
+	// The fee is never answered, so the outcome is unknown
+	timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	tx := &data.AccountSet{TxBase: data.TxBase{TransactionType: data.ACCOUNT_SET}}
+	_, err := m.SubmitAndWait(timeout, tx, false)
+	_, ok := err.(*ContextError)
+	c.Assert(ok, Equals, true, Commentf("%v", err))
+	c.Check(tx.Sequence, Equals, uint32(5))
+	c.Check(*tx.LastLedgerSequence, Equals, uint32(121))
+	held, err := m.Reserve(ctx)
+	c.Assert(err, IsNil)
+	c.Check(held.Sequence, Equals, uint32(6))
+
+	// It becomes a gap once its LastLedgerSequence has been validated
+	c.Assert(m.Reconcile(ctx), IsNil)
+	c.Check(m.Gaps(), HasLen, 0)
+	atomic.StoreUint32(&validated, 122)
+	c.Assert(m.Reconcile(ctx), IsNil)
+	c.Check(m.Gaps(), DeepEquals, []uint32{5})
+
+	// A transaction which was never submitted leaves a gap at once
+	next, err := m.Reserve(ctx)
+	c.Assert(err, IsNil)
+	m.settle(next, &data.AccountSet{}, errors.New("Not signed"))
+	c.Check(m.Gaps(), DeepEquals, []uint32{5, 7})
+
+	// Without any outstanding, the next sequence is the account's again
+	m.Done(held, false)
+	c.Assert(m.Reconcile(ctx), IsNil)
+	c.Check(m.Gaps(), HasLen, 0)
+	next, err = m.Reserve(ctx)
+	c.Assert(err, IsNil)
+	c.Check(next.Sequence, Equals, uint32(5))
+}
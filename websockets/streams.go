@@ -0,0 +1,139 @@
+package websockets
+
+import (
+	"reflect"
+	"sync/atomic"
+)
+
+// OverflowPolicy decides what a Remote does with a stream message when the
+// channel it is to be sent on is full.
+type OverflowPolicy int
+
+const (
+	// Wait for the consumer, which stalls command responses meanwhile
+	OverflowBlock OverflowPolicy = iota
+	// Discard the oldest message waiting in the channel to make room
+	OverflowDropOldest
+	// Drop the connection, which a reconnecting Remote then redials
+	OverflowDisconnect
+)
+
+// The capacity of each channel of a Remote unless otherwise configured
+const defaultBuffer = 1000
+
+// RemoteOptions configure a Remote made with NewRemoteWithOptions.
+type RemoteOptions struct {
+	Reconnect    bool           // As for NewReconnectingRemote
+	TypedStreams bool           // Send stream messages on Streams rather than Incoming
+	Buffer       int            // The capacity of each channel, 1000 if zero
+	Overflow     OverflowPolicy // Applied to every channel of stream messages
+}
+
+// Streams has a channel for each type of stream message. Transactions in
+// subscribed order books arrive on Transaction like any other.
+type Streams struct {
+	Ledger      chan *LedgerStreamMsg
+	Transaction chan *TransactionStreamMsg
+	Server      chan *ServerStreamMsg
+	PathFind    chan *PathFindCreateResult
+	Validation  chan *ValidationStreamMsg
+	Manifest    chan *ManifestStreamMsg
+	PeerStatus  chan *PeerStatusStreamMsg
+	Consensus   chan *ConsensusStreamMsg
+	BookChanges chan *BookChangesStreamMsg
+}
+
+// The stream message type of each type of message struct
+var streamMessageTypes = func() map[reflect.Type]string {
+	types := make(map[reflect.Type]string)
+	for name, factory := range streamMessageFactory {
+		types[reflect.TypeOf(factory())] = name
+	}
+	return types
+}()
+
+// newStreams makes the channels of a Streams and returns them by the
+// stream message type they carry.
+func newStreams(buffer int) (*Streams, map[string]reflect.Value) {
+	streams := &Streams{}
+	routes := make(map[string]reflect.Value)
+	v := reflect.ValueOf(streams).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		ch := reflect.MakeChan(v.Field(i).Type(), buffer)
+		v.Field(i).Set(ch)
+		routes[streamMessageTypes[ch.Type().Elem()]] = ch
+	}
+	return streams, routes
+}
+
+// deliver sends a stream message on its channel, applying the overflow
+// policy if it is full. Returns false if the connection is to be dropped.
+func (r *Remote) deliver(msgType string, msg interface{}) bool {
+	if update, ok := msg.(*PathFindCreateResult); ok && r.pathFind != nil && update.Id == r.pathFind.id {
+		r.pathFind.update(update)
+		return true
+	}
+	ch, ok := r.routes[msgType]
+	if !ok {
+		ch = reflect.ValueOf(r.Incoming)
+	}
+	v := reflect.ValueOf(msg)
+	switch r.overflow {
+	case OverflowDropOldest:
+		r.dropOldest(ch, v)
+	case OverflowDisconnect:
+		if !ch.TrySend(v) {
+			r.countDropped(msg)
+			return false
+		}
+	default:
+		ch.Send(v)
+	}
+	return true
+}
+
+// notify sends a ConnectionStateMsg on Incoming. Unless the overflow policy
+// is to block, the oldest message waiting is discarded if Incoming is full,
+// as there is no connection left to drop. Returns false if Close() is
+// called first.
+func (r *Remote) notify(msg *ConnectionStateMsg) bool {
+	if r.overflow != OverflowBlock {
+		r.dropOldest(reflect.ValueOf(r.Incoming), reflect.ValueOf(msg))
+		return true
+	}
+	select {
+	case r.Incoming <- msg:
+		return true
+	case <-r.closing:
+		return false
+	}
+}
+
+// dropOldest sends v on ch, first discarding the oldest message waiting
+// in ch if it is full. The run loop is the only sender, so once a message
+// has been taken there is room.
+func (r *Remote) dropOldest(ch, v reflect.Value) {
+	for !ch.TrySend(v) {
+		if old, ok := ch.TryRecv(); ok {
+			r.countDropped(old.Interface())
+		}
+	}
+}
+
+func (r *Remote) countDropped(msg interface{}) {
+	if counter, ok := r.dropped[streamMessageTypes[reflect.TypeOf(msg)]]; ok {
+		atomic.AddUint64(counter, 1)
+	}
+}
+
+// Dropped returns the number of stream messages of each type which have
+// been discarded by the overflow policy, keyed as in streamMessageFactory.
+func (r *Remote) Dropped() map[string]uint64 {
+	dropped := make(map[string]uint64)
+	for msgType, counter := range r.dropped {
+		if n := atomic.LoadUint64(counter); n > 0 {
+			dropped[msgType] = n
+		}
+	}
+	return dropped
+}
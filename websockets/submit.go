@@ -0,0 +1,96 @@
+package websockets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/parihaaraka/ripple/crypto"
+	"github.com/parihaaraka/ripple/data"
+)
+
+// How often SubmitAndWait checks for validation.
+const pollInterval = time.Second
+
+// ErrExpired is returned by SubmitAndWait when a ledger after the
+// transaction's LastLedgerSequence is validated without it, so it
+// never can be.
+var ErrExpired = errors.New("LastLedgerSequence passed without validation")
+
+// SubmitError is returned by SubmitAndWait when the server rejects the
+// transaction outright, so it never can be validated.
+type SubmitError struct {
+	*SubmitResult
+}
+
+func (e *SubmitError) Error() string {
+	return fmt.Sprintf("%s: %s", e.EngineResult, e.EngineResultMessage)
+}
+
+// SubmitAndWait autofills tx using DefaultAutofillOptions, signs it with
+// key and submits it. It then waits until the transaction is found in a
+// validated ledger, resubmitting it while the result is a ter or tel code.
+// The validated transaction is returned whether or not it succeeded, so
+// check its TransactionResult. ErrExpired is returned when the
+// LastLedgerSequence passes without it being validated, and a ContextError
+// if ctx is done first, though the transaction may still be validated.
+func SubmitAndWait(ctx context.Context, client Client, tx data.Transaction, key crypto.Key, keySequence *uint32) (*data.TransactionWithMetaData, error) {
+	if err := AutofillContext(ctx, client, tx, nil); err != nil {
+		return nil, err
+	}
+	if err := data.Sign(tx, key, keySequence); err != nil {
+		return nil, err
+	}
+	result, err := client.SubmitContext(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	if result.EngineResult.Malformed() || result.EngineResult.Failed() {
+		return nil, &SubmitError{result}
+	}
+	hash := *tx.GetHash()
+	last := *tx.GetBase().LastLedgerSequence
+	retry := result.EngineResult.Retry()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, &ContextError{Name: "submit", Err: ctx.Err()}
+		case <-ticker.C:
+		}
+		// Check the validated ledger before the transaction, as it
+		// might be validated in between.
+		info, err := client.ServerInfoContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		txr, err := client.TxContext(ctx, hash)
+		switch e, ok := err.(*CommandError); {
+		case err == nil && txr.Validated:
+			return &txr.TransactionWithMetaData, nil
+		case err != nil && !(ok && e.Name == "txnNotFound"):
+			return nil, err
+		}
+		if uint32(info.Info.ValidatedLedger.Seq) > last {
+			return nil, ErrExpired
+		}
+		if !retry {
+			continue
+		}
+		if result, err = client.SubmitContext(ctx, tx); err != nil {
+			return nil, err
+		}
+		switch {
+		case result.EngineResult.Past():
+			// Applied by an earlier submission, or superseded.
+			// Either way it will be found or expire.
+			retry = false
+		case result.EngineResult.Malformed() || result.EngineResult.Failed():
+			return nil, &SubmitError{result}
+		default:
+			retry = result.EngineResult.Retry()
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package websockets
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/parihaaraka/ripple/data"
+	. "gopkg.in/check.v1"
+)
+
+type SubmitSuite struct{}
+
+var _ = Suite(&SubmitSuite{})
+
+func errorResponse(request map[string]interface{}, name string, code int) map[string]interface{} {
+	return map[string]interface{}{
+		"id":            request["id"],
+		"status":        "error",
+		"type":          "response",
+		"error":         name,
+		"error_code":    code,
+		"error_message": name,
+	}
+}
+
+// submitServer queues the first submission, then finds the
+// transaction in a validated ledger after it is resubmitted.
+func submitServer(c *C, validatedLedger int) (*fakeServer, chan string) {
+	var tx map[string]interface{}
+	b, err := ioutil.ReadFile("testdata/tx.json")
+	c.Assert(err, IsNil)
+	c.Assert(json.Unmarshal(b, &tx), IsNil)
+	var (
+		commands    = make(chan string, 100)
+		submissions int
+	)
+	return newFakeServer(func(request map[string]interface{}) interface{} {
+		command := request["command"].(string)
+		commands <- command
+		switch command {
+		case "account_info":
+			return response(request, map[string]interface{}{
+				"ledger_current_index": 100,
+				"account_data":         map[string]interface{}{"LedgerEntryType": "AccountRoot", "Sequence": 5},
+			})
+		case "account_objects":
+			return response(request, map[string]interface{}{"ledger_index": validatedLedger, "account_objects": []interface{}{}})
+		case "fee":
+			return response(request, map[string]interface{}{"drops": map[string]interface{}{"open_ledger_fee": "12"}})
+		case "submit":
+			submissions++
+			result := "terQUEUED"
+			if submissions > 1 {
+				result = "tesSUCCESS"
+			}
+			return response(request, map[string]interface{}{"engine_result": result})
+		case "server_info":
+			return response(request, map[string]interface{}{"info": map[string]interface{}{"validated_ledger": map[string]interface{}{"seq": validatedLedger}}})
+		case "tx":
+			if submissions < 2 {
+				return errorResponse(request, "txnNotFound", 29)
+			}
+			return response(request, tx["result"])
+		}
+		return nil
+	}), commands
+}
+
+func (s *SubmitSuite) TestSubmitAndWait(c *C) {
+	server, commands := submitServer(c, 99)
+	defer server.Close()
+	r, err := NewRemote(server.Endpoint())
+	c.Assert(err, IsNil)
+	defer r.Close()
+
+	seed, err := data.NewSeedFromAddress("snoPBrXtMeMyMHUVTgbuqAfg1SUTb")
+	c.Assert(err, IsNil)
+	var sequence uint32
+	tx := &data.AccountSet{TxBase: data.TxBase{
+		TransactionType: data.ACCOUNT_SET,
+		Account:         seed.AccountId(data.ECDSA, &sequence),
+	}}
+	txm, err := SubmitAndWait(context.Background(), r, tx, seed.Key(data.ECDSA), &sequence)
+	c.Assert(err, IsNil)
+	c.Check(txm.MetaData.TransactionResult.Success(), Equals, true)
+	c.Check(tx.Sequence, Equals, uint32(5))
+	c.Check(tx.Fee.String(), Equals, "0.000012")
+	c.Check(*tx.LastLedgerSequence, Equals, uint32(120))
+
+	var sent []string
+	for len(commands) > 0 {
+		sent = append(sent, <-commands)
+	}
+	c.Check(sent, DeepEquals, []string{"account_info", "server_info", "fee", "submit", "server_info", "tx", "submit", "server_info", "tx"})
+}
+
+func (s *SubmitSuite) TestSubmitAndWaitExpired(c *C) {
+	server, _ := submitServer(c, 121)
+	defer server.Close()
+	r, err := NewRemote(server.Endpoint())
+	c.Assert(err, IsNil)
+	defer r.Close()
+
+	seed, err := data.NewSeedFromAddress("snoPBrXtMeMyMHUVTgbuqAfg1SUTb")
+	c.Assert(err, IsNil)
+	var sequence uint32
+	tx := &data.AccountSet{TxBase: data.TxBase{
+		TransactionType: data.ACCOUNT_SET,
+		Account:         seed.AccountId(data.ECDSA, &sequence),
+	}}
+	_, err = SubmitAndWait(context.Background(), r, tx, seed.Key(data.ECDSA), &sequence)
+	c.Check(err, Equals, ErrExpired)
+}
+
+func (s *SubmitSuite) TestSubmitAndWaitCancelled(c *C) {
+	server, _ := submitServer(c, 99)
+	defer server.Close()
+	r, err := NewRemote(server.Endpoint())
+	c.Assert(err, IsNil)
+	defer r.Close()
+
+	seed, err := data.NewSeedFromAddress("snoPBrXtMeMyMHUVTgbuqAfg1SUTb")
+	c.Assert(err, IsNil)
+	var sequence uint32
+	tx := &data.AccountSet{TxBase: data.TxBase{
+		TransactionType: data.ACCOUNT_SET,
+		Account:         seed.AccountId(data.ECDSA, &sequence),
+	}}
+	ctx, cancel := context.WithTimeout(context.Background(), pollInterval/10)
+	defer cancel()
+	_, err = SubmitAndWait(ctx, r, tx, seed.Key(data.ECDSA), &sequence)
+	ctxErr, ok := err.(*ContextError)
+	c.Assert(ok, Equals, true, Commentf("%v", err))
+	c.Check(ctxErr.Name, Equals, "submit")
+	c.Check(ctxErr.Timeout(), Equals, true)
+}
@@ -1,7 +1,9 @@
 package websockets
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 
 	"github.com/parihaaraka/ripple/data"
 )
@@ -48,19 +50,168 @@ func (s *ServerStreamMsg) TransactionCost() uint64 {
 	return (s.BaseFee * s.LoadFactor) / s.LoadBase
 }
 
+// Fields from subscribed validations stream messages. The Validation is
+// decoded from the serialized data, so that its signature can be checked.
+type ValidationStreamMsg struct {
+	Validation          data.Validation     `json:"-"`
+	Data                data.VariableLength `json:"data"`
+	LedgerHash          data.Hash256        `json:"ledger_hash"`
+	LedgerSequence      uint32              `json:"ledger_index,string"`
+	Full                bool                `json:"full"`
+	ValidationPublicKey string              `json:"validation_public_key"`
+	MasterKey           string              `json:"master_key,omitempty"` // Not verified by the client
+}
+
+// Fields from subscribed manifests stream messages
+type ManifestStreamMsg struct {
+	Manifest   string `json:"manifest"` // Base64
+	MasterKey  string `json:"master_key"`
+	SigningKey string `json:"signing_key"`
+	Sequence   uint32 `json:"seq"`
+	Domain     string `json:"domain,omitempty"`
+}
+
+// Fields from subscribed peer_status stream messages, which only admin
+// connections may subscribe to
+type PeerStatusStreamMsg struct {
+	Action         string          `json:"action"` // CLOSING_LEDGER, ACCEPTED_LEDGER, SWITCHED_LEDGER or LOST_SYNC
+	Date           data.RippleTime `json:"date"`
+	LedgerHash     *data.Hash256   `json:"ledger_hash,omitempty"`
+	LedgerSequence uint32          `json:"ledger_index,omitempty"`
+	LedgerIndexMin uint32          `json:"ledger_index_min,omitempty"`
+	LedgerIndexMax uint32          `json:"ledger_index_max,omitempty"`
+}
+
+// Fields from subscribed consensus stream messages
+type ConsensusStreamMsg struct {
+	Consensus string `json:"consensus"` // open, establish or accepted
+}
+
+// A change of one order book in a book_changes stream message. The
+// currencies are "XRP_drops" or "issuer/currency".
+type BookChange struct {
+	CurrencyA string              `json:"currency_a"`
+	CurrencyB string              `json:"currency_b"`
+	VolumeA   data.NonNativeValue `json:"volume_a"`
+	VolumeB   data.NonNativeValue `json:"volume_b"`
+	High      data.NonNativeValue `json:"high"`
+	Low       data.NonNativeValue `json:"low"`
+	Open      data.NonNativeValue `json:"open"`
+	Close     data.NonNativeValue `json:"close"`
+}
+
+// Fields from subscribed book_changes stream messages
+type BookChangesStreamMsg struct {
+	LedgerSequence uint32          `json:"ledger_index"`
+	LedgerHash     data.Hash256    `json:"ledger_hash"`
+	LedgerTime     data.RippleTime `json:"ledger_time"`
+	Validated      bool            `json:"validated"`
+	Changes        []BookChange    `json:"changes"`
+}
+
 // Map message types to the appropriate data structure
 var streamMessageFactory = map[string]func() interface{}{
 	"ledgerClosed": func() interface{} { return &LedgerStreamMsg{} },
 	"transaction":  func() interface{} { return &TransactionStreamMsg{} },
 	"serverStatus": func() interface{} { return &ServerStreamMsg{} },
 	"path_find":    func() interface{} { return &PathFindCreateResult{} },
+
+	"validationReceived": func() interface{} { return &ValidationStreamMsg{} },
+	"manifestReceived":   func() interface{} { return &ManifestStreamMsg{} },
+	"peerStatusChange":   func() interface{} { return &PeerStatusStreamMsg{} },
+	"consensusPhase":     func() interface{} { return &ConsensusStreamMsg{} },
+	"bookChanges":        func() interface{} { return &BookChangesStreamMsg{} },
+}
+
+// The streams which may be subscribed to
+const (
+	StreamLedger               = "ledger"
+	StreamTransactions         = "transactions"
+	StreamTransactionsProposed = "transactions_proposed"
+	StreamServer               = "server"
+	StreamValidations          = "validations"
+	StreamManifests            = "manifests"
+	StreamPeerStatus           = "peer_status"
+	StreamConsensus            = "consensus"
+	StreamBookChanges          = "book_changes"
+)
+
+// Subscription is what a subscribe or unsubscribe command is for. The
+// transactions affecting Accounts are streamed once validated, and those
+// affecting AccountsProposed also as they are proposed.
+type Subscription struct {
+	Streams          []string                `json:"streams,omitempty"`
+	Accounts         []data.Account          `json:"accounts,omitempty"`
+	AccountsProposed []data.Account          `json:"accounts_proposed,omitempty"`
+	Books            []OrderBookSubscription `json:"books,omitempty"`
+}
+
+func (s *Subscription) IsEmpty() bool {
+	return len(s.Streams)+len(s.Accounts)+len(s.AccountsProposed)+len(s.Books) == 0
+}
+
+// Without returns what remains subscribed of s after unsubscribing from u.
+// A book is matched on its assets and whether both sides were asked for.
+func (s Subscription) Without(u Subscription) Subscription {
+	var remains Subscription
+	for _, stream := range s.Streams {
+		if !containsString(u.Streams, stream) {
+			remains.Streams = append(remains.Streams, stream)
+		}
+	}
+	for _, account := range s.Accounts {
+		if !containsAccount(u.Accounts, account) {
+			remains.Accounts = append(remains.Accounts, account)
+		}
+	}
+	for _, account := range s.AccountsProposed {
+		if !containsAccount(u.AccountsProposed, account) {
+			remains.AccountsProposed = append(remains.AccountsProposed, account)
+		}
+	}
+	for _, book := range s.Books {
+		found := false
+		for _, other := range u.Books {
+			if book.sameBook(other) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			remains.Books = append(remains.Books, book)
+		}
+	}
+	return remains
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAccount(list []data.Account, a data.Account) bool {
+	for _, item := range list {
+		if item == a {
+			return true
+		}
+	}
+	return false
 }
 
 type SubscribeCommand struct {
 	*Command
-	Streams []string                `json:"streams"`
-	Books   []OrderBookSubscription `json:"books,omitempty"`
-	Result  *SubscribeResult        `json:"result,omitempty"`
+	Subscription
+	Result *SubscribeResult `json:"result,omitempty"`
+}
+
+type UnsubscribeCommand struct {
+	*Command
+	Subscription
+	Result *struct{} `json:"result,omitempty"`
 }
 
 type SubscribeResult struct {
@@ -86,3 +237,24 @@ func (msg *TransactionStreamMsg) UnmarshalJSON(b []byte) error {
 	extract.MetaData = &msg.Transaction.MetaData
 	return json.Unmarshal(b, &extract)
 }
+
+// Wrapper to stop recursive unmarshalling
+type validationStreamJSON ValidationStreamMsg
+
+func (msg *ValidationStreamMsg) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, (*validationStreamJSON)(msg)); err != nil {
+		return err
+	}
+	if len(msg.Data) == 0 {
+		return fmt.Errorf("Validation of %s without data", msg.LedgerHash)
+	}
+	v, err := data.ReadValidation(bytes.NewReader(msg.Data))
+	if err != nil {
+		return err
+	}
+	msg.Validation = *v
+	if msg.Validation.Hash, err = data.NodeId(v); err != nil {
+		return err
+	}
+	return nil
+}
@@ -168,3 +168,59 @@ func BenchmarkTransactionStreamJSON(b *testing.B) {
 		}
 	}
 }
+
+func (s *MessagesSuite) TestValidationStreamMsg(c *C) {
+	msg := streamMessageFactory["validationReceived"]().(*ValidationStreamMsg)
+	readResponseFile(c, msg, "testdata/validation_stream.json")
+
+	c.Assert(msg.LedgerSequence, Equals, uint32(6951500))
+	c.Assert(msg.Full, Equals, false)
+	c.Assert(msg.ValidationPublicKey, Equals, "n9L81uNCaPgtUJfaHh89gmdvXKAmSt5Gdsw2g1iPWaPkAHW5Nm4C")
+	v := &msg.Validation
+	c.Assert(v.LedgerHash, Equals, msg.LedgerHash)
+	c.Assert(v.LedgerSequence, Equals, msg.LedgerSequence)
+	c.Assert(v.SigningPubKey.NodePublicKey(), Equals, msg.ValidationPublicKey)
+	c.Assert(v.SigningTime.Uint32(), Equals, uint32(454934438))
+	c.Assert(v.Full(), Equals, false)
+	ok, err := data.CheckSignature(v)
+	c.Assert(err, IsNil)
+	c.Assert(ok, Equals, true)
+
+	var missing ValidationStreamMsg
+	c.Check(json.Unmarshal([]byte(`{"ledger_hash":"1A8194A501C8C9AC779A96495365D596371C09636E63F62BB0B4B81CF1239BAF"}`), &missing), ErrorMatches, "Validation of 1A8194A5.* without data")
+}
+
+func (s *MessagesSuite) TestPeerStatusStreamMsg(c *C) {
+	msg := streamMessageFactory["peerStatusChange"]().(*PeerStatusStreamMsg)
+	readResponseFile(c, msg, "testdata/peer_status_stream.json")
+
+	c.Assert(msg.Action, Equals, "CLOSING_LEDGER")
+	c.Assert(msg.Date.Uint32(), Equals, uint32(508546525))
+	c.Assert(msg.LedgerHash.String(), Equals, "4D4CD9CD543F0C1EF023CC457F5BEFEA59EEF73E4552542D40E7C4FA08D3C320")
+	c.Assert(msg.LedgerSequence, Equals, uint32(18853106))
+	c.Assert(msg.LedgerIndexMin, Equals, uint32(18852082))
+	c.Assert(msg.LedgerIndexMax, Equals, uint32(18853106))
+}
+
+func (s *MessagesSuite) TestConsensusStreamMsg(c *C) {
+	msg := streamMessageFactory["consensusPhase"]().(*ConsensusStreamMsg)
+	readResponseFile(c, msg, "testdata/consensus_stream.json")
+
+	c.Assert(msg.Consensus, Equals, "accepted")
+}
+
+func (s *MessagesSuite) TestBookChangesStreamMsg(c *C) {
+	msg := streamMessageFactory["bookChanges"]().(*BookChangesStreamMsg)
+	readResponseFile(c, msg, "testdata/book_changes_stream.json")
+
+	c.Assert(msg.LedgerSequence, Equals, uint32(88530953))
+	c.Assert(msg.LedgerTime.Uint32(), Equals, uint32(756236031))
+	c.Assert(msg.Validated, Equals, true)
+	c.Assert(msg.Changes, HasLen, 1)
+	change := msg.Changes[0]
+	c.Assert(change.CurrencyA, Equals, "XRP_drops")
+	c.Assert(change.CurrencyB, Equals, "rhub8VRN55s94qWKDv6jmDy1pUykJzF3wq/USD")
+	c.Assert(change.VolumeA.String(), Equals, "23020993")
+	c.Assert(change.VolumeB.String(), Equals, "11.46939713412848")
+	c.Assert(change.Close.String(), Equals, "2007159.2")
+}